@@ -0,0 +1,108 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alexstov/sling/emul"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// CtlCmd command.
+var CtlCmd = &cobra.Command{
+	Use:   "ctl <state|rate|workers|pause|resume|snapshot|stop> [value] [flags]",
+	Short: "Control the running send",
+	Long: `
+	Change the running request send through its control endpoint, --control of the send.
+	state reports the run state and the interim results, rate sets the rate limit, e.g. 50/s, 3000/m or 0
+	for unlimited, workers sets the number of workers sending the requests, pause and resume pause and
+	resume sending, snapshot outputs the interim results in the send log and console, and stop stops the
+	send gracefully completing the requests in flight.`,
+	Example: `
+	# Start the soak test with the control endpoint
+	sling request send -d /home/alexstov/sling/test_set -r 1000000 -n 20 --control localhost:8637
+
+	# Raise the load
+	sling ctl rate 200/s --control localhost:8637
+	sling ctl workers 50 --control localhost:8637
+
+	# Pause, resume, report the interim results and stop
+	sling ctl pause --control unix:/tmp/sling.sock
+	sling ctl resume --control unix:/tmp/sling.sock
+	sling ctl snapshot --control unix:/tmp/sling.sock
+	sling ctl stop --control unix:/tmp/sling.sock`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  ctlRun,
+}
+
+// Ctl command flags
+var ctlFlags Flagmapper
+
+func init() {
+	var err error
+
+	// Ctl flags default to the config.
+	initRoot()
+	if ctlFlags, err = NewCmdFlags(CtlCmd, CmdCtl, sconf); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create ctl flags.")
+	}
+
+	RootCmd.AddCommand(CtlCmd)
+}
+
+func ctlRun(cmd *cobra.Command, args []string) {
+	var err error
+	var ctlArgs emul.CtlArgs
+
+	// Set explicit command flags to override config defaults.
+	RootFlags.SetExplicit()
+	ctlFlags.SetExplicit()
+
+	// The errors are output to the console, the fatal level only reaches the log.
+	if err = ctlFlags.ResolveCtlArgs(&ctlArgs); err != nil {
+		Con.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"error": err}, "Cannot resolve ctl arguments.")
+		os.Exit(1)
+	}
+
+	action, value := args[0], ""
+	if len(args) > 1 {
+		value = args[1]
+	}
+	state, err := emul.SendControl(ctlArgs, action, value)
+	if err != nil {
+		Con.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"error": err.Error(), "Control": ctlArgs.Control}, "Control action failed.")
+		os.Exit(1)
+	}
+
+	outState("Send state.", state)
+}
+
+// outState outputs the running send state and the interim results.
+func outState(msg string, state emul.ControlState) {
+	fields := logrus.Fields{"Elapsed": state.Elapsed, "Paused": state.Paused, "Workers": state.Workers, "Rate": state.Rate,
+		"Count": state.Count, "Errors": state.Errors, "Failures": state.Failures, "MeanMs": fmt.Sprintf("%.1f", state.MeanMs),
+		"P50Ms": fmt.Sprintf("%.1f", state.P50Ms), "P95Ms": fmt.Sprintf("%.1f", state.P95Ms), "P99Ms": fmt.Sprintf("%.1f", state.P99Ms),
+		"MaxMs": state.MaxMs}
+	if state.Concurrency > 0 {
+		fields["Concurrency"] = fmt.Sprintf("%.1f", state.Concurrency)
+	}
+	if state.Stopping {
+		fields["Stopping"] = true
+	}
+	Con.OutLogAndConsole(logrus.InfoLevel, fields, msg)
+}
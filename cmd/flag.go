@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/agoalofalife/event"
 	"github.com/pkg/errors" //"errors"
@@ -82,6 +83,170 @@ const (
 	ConLvl
 	// LogLvl log output level, --, logLvl
 	LogLvl
+	// Replay timeline file to replay, CSV or JSONL with offsets and file paths, --replay
+	Replay
+	// Speed replay speed multiplier, --speed
+	Speed
+	// DriftMs replay schedule drift to report, --driftMs
+	DriftMs
+	// Manifest request manifest file, YAML or JSONL, --manifest
+	Manifest
+	// Mix request selection mode, seq or weighted, --mix
+	Mix
+	// Weights weights sidecar file mapping file name patterns to weights, --weights
+	Weights
+	// Seed random seed, zero seeds from the clock, --seed
+	Seed
+	// Exclude file name exclude globs, --exclude
+	Exclude
+	// Order send order, name, mtime, size or shuffle, --order
+	Order
+	// Watch watch the directories and send new files as they arrive, --watch
+	Watch
+	// SettleMs spooled file settle delay, milliseconds, --settleMs
+	SettleMs
+	// Input read requests from stdin (-) or a named pipe, --input
+	Input
+	// Split input split mode, delim, nul, len or jsonl, --split
+	Split
+	// Delim input record delimiter, --delim
+	Delim
+	// CacheMb request body cache memory budget, megabytes, --cacheMb
+	CacheMb
+	// Think think time distribution between requests, --think
+	Think
+	// Warmup warm-up duration or request count, --warmup
+	Warmup
+	// Search capacity search mode, step or bisect, --search
+	Search
+	// SearchMin capacity search lowest rate, --searchMin
+	SearchMin
+	// SearchMax capacity search highest rate, --searchMax
+	SearchMax
+	// SearchStep capacity search rate step, --searchStep
+	SearchStep
+	// HoldSec capacity search step hold time, --holdSec
+	HoldSec
+	// SLO capacity search SLO, --slo
+	SLO
+	// Shadow shadow endpoint, --shadow
+	Shadow
+	// Compare shadow response comparison mode, --compare
+	Compare
+	// Ignore shadow comparison ignored JSON paths, --ignore
+	Ignore
+	// Verify golden response verification, --verify
+	Verify
+	// GoldenDir golden responses directory, --goldenDir
+	GoldenDir
+	// Mask golden comparison masks, --mask
+	Mask
+	// Status expected response status codes, --status
+	Status
+	// Match required response body regexes, --match
+	Match
+	// NotMatch forbidden response body regexes, --notMatch
+	NotMatch
+	// JSONEquals expected response JSON values, --jsonEquals
+	JSONEquals
+	// JSONExists required response JSON paths, --jsonExists
+	JSONExists
+	// MinSize minimum response size, --minSize
+	MinSize
+	// MaxSize maximum response size, --maxSize
+	MaxSize
+	// MaxLatencyMs maximum response latency, --maxLatencyMs
+	MaxLatencyMs
+	// Listen record listen address, --listen
+	Listen
+	// Upstream record upstream endpoint, --upstream
+	Upstream
+	// RecordDir recorded sessions directory, --recordDir
+	RecordDir
+	// TCPListen mock server TCP listen address, --tcp
+	TCPListen
+	// HTTPListen mock server HTTP listen address, --http
+	HTTPListen
+	// Respond mock server responses, --respond
+	Respond
+	// Latency mock server latency distribution, --latency
+	Latency
+	// ErrorRate mock server error rate, --errorRate
+	ErrorRate
+	// DropRate mock server connection drop rate, --dropRate
+	DropRate
+	// ErrorStatus mock server error status, --errorStatus
+	ErrorStatus
+	// Rules chaos rules file, --rules
+	Rules
+	// Adaptive adaptive rate, --adaptive
+	Adaptive
+	// Decrease adaptive rate decrease factor, --decrease
+	Decrease
+	// RecoverStep adaptive rate recovery step, --recoverStep
+	RecoverStep
+	// MinRate adaptive lowest rate, --minRate
+	MinRate
+	// ThrottleStatus throttling status codes, --throttleStatus
+	ThrottleStatus
+	// RateIntervalSec effective rate report interval, --rateIntervalSec
+	RateIntervalSec
+	// Attempts request attempts, --attempts
+	Attempts
+	// BackoffMs retry backoff base, --backoffMs
+	BackoffMs
+	// BackoffCapMs retry backoff cap, --backoffCapMs
+	BackoffCapMs
+	// Jitter retry backoff jitter, --jitter
+	Jitter
+	// RetryOn retryable errors and statuses, --retryOn
+	RetryOn
+	// FailFast fail-fast, --failFast
+	FailFast
+	// MaxErrRate circuit breaker error rate, --maxErrRate
+	MaxErrRate
+	// ErrWindowSec circuit breaker window, --errWindowSec
+	ErrWindowSec
+	// ErrMinCount circuit breaker minimum requests, --errMinCount
+	ErrMinCount
+	// MaxConsecutive circuit breaker consecutive failures, --maxConsecutive
+	MaxConsecutive
+	// OnBreak circuit breaker action, --onBreak
+	OnBreak
+	// BreakPauseSec circuit breaker pause, --breakPauseSec
+	BreakPauseSec
+	// Failover endpoint failover, --failover
+	Failover
+	// FailoverErrors failover connection errors, --failoverErrors
+	FailoverErrors
+	// ProbeSec failover probe interval, --probeSec
+	ProbeSec
+	// Rate rate expression, --rate
+	Rate
+	// Burst rate limit burst, --burst
+	Burst
+	// Schedule rate schedule, --schedule
+	Schedule
+	// ScheduleSpeed rate schedule speed, --scheduleSpeed
+	ScheduleSpeed
+	// Concurrency adaptive concurrency, --concurrency
+	Concurrency
+	// ConcurrencyInit adaptive concurrency initial limit, --concurrencyInit
+	ConcurrencyInit
+	// ConcurrencyMin adaptive concurrency lowest limit, --concurrencyMin
+	ConcurrencyMin
+	// ConcurrencyMax adaptive concurrency highest limit, --concurrencyMax
+	ConcurrencyMax
+	// ConcurrencyBackoff adaptive concurrency backoff, --concurrencyBackoff
+	ConcurrencyBackoff
+	// Tolerance gradient concurrency tolerance, --tolerance
+	Tolerance
+	// DropLatencyMs adaptive concurrency drop latency, --dropLatencyMs
+	DropLatencyMs
+	// Control control endpoint, --control
+	Control
+	// ScheduleIntervalSec rate schedule report interval, --scheduleIntervalSec
+	ScheduleIntervalSec
 )
 
 const (
@@ -95,6 +260,8 @@ const (
 	UintType
 	// UintSliceType flag
 	UintSliceType
+	// StrSliceType flag
+	StrSliceType
 )
 
 var flagUsage = [...]string{
@@ -104,7 +271,7 @@ var flagUsage = [...]string{
 	"write histogram to console",
 	"limit the number of concurrent connections",
 	"number of concurrent connections",
-	"directory to send files from",
+	"directories to send files from",
 	"active endpoint index in SLINGCONFIG, zero-based",
 	"file path or file name to send",
 	"write histogram to log file",
@@ -121,10 +288,92 @@ var flagUsage = [...]string{
 	"read network client timeout",
 	"network client timeout",
 	"write network client timeout",
-	"file name matching wildcard",
+	"file name matching wildcards, ** matches any number of directories",
 	"log output level",
 	"console output level",
 	"set console flat output without timestamp and fields",
+	"timeline file to replay, CSV or JSONL with offsets and file paths",
+	"replay speed multiplier, e.g. 0.5x, 2x, 10x",
+	"replay schedule drift to report, milliseconds",
+	"request manifest file, YAML or JSONL",
+	"request selection mode, seq or weighted",
+	"weights sidecar file mapping file name patterns to weights",
+	"random seed, zero seeds from the clock",
+	"file name exclude globs",
+	"send order, name, mtime, size or shuffle",
+	"watch the directories and send new files as they arrive",
+	"spooled file settle delay, milliseconds",
+	"read requests from stdin (-) or a named pipe",
+	"input split mode, delim, nul, len or jsonl",
+	"input record delimiter, escape sequences allowed",
+	"request body cache memory budget, megabytes, zero disables the cache",
+	"think time between requests, e.g. uniform(10,50), normal(100,20), exp(100), lognormal(100,0.5), milliseconds",
+	"warm-up duration, e.g. 30s, or request count, reported separately",
+	"capacity search mode, step or bisect",
+	"capacity search lowest rate per second",
+	"capacity search highest rate per second",
+	"capacity search rate step or bisect precision per second",
+	"capacity search step hold time, seconds",
+	"capacity search SLO, e.g. p99<200ms,err<1%",
+	"shadow endpoint name or index in SLINGCONFIG, every request is also sent there and the responses compared",
+	"shadow response comparison, bytes, json or ignore",
+	"JSON paths of the fields ignored by the shadow comparison, e.g. $.meta.ts",
+	"verify the responses against the golden responses of the request files",
+	"golden responses directory",
+	"golden comparison masks, JSON paths, e.g. $.meta.ts, or regexes, e.g. re:[0-9a-f-]{36}",
+	"expected response status codes",
+	"required response body regexes",
+	"forbidden response body regexes",
+	"expected response JSON values, e.g. $.status=ok, $.count=3",
+	"required response JSON paths, e.g. $.items[0].id",
+	"minimum response size, bytes",
+	"maximum response size, bytes, zero is unlimited",
+	"maximum response latency, milliseconds, zero is unlimited",
+	"record listen address",
+	"record upstream endpoint name, index or address",
+	"recorded sessions directory",
+	"mock server TCP listen address, empty disables",
+	"mock server HTTP listen address, empty disables",
+	"mock server responses, echo, file:PATH or map:PATH",
+	"mock server latency, e.g. uniform(10,50), normal(100,20), exp(100), lognormal(100,0.5), milliseconds",
+	"mock server error response rate, e.g. 0.01 or 1%",
+	"mock server connection drop rate, e.g. 0.01 or 1%",
+	"mock server error response HTTP status",
+	"chaos rules file, reloaded when it changes",
+	"decrease the rate when the endpoint throttles and honor Retry-After",
+	"adaptive rate decrease factor, e.g. 0.5",
+	"adaptive rate recovery per second, requests per second",
+	"adaptive lowest rate per second",
+	"throttling response status codes",
+	"effective rate report interval, seconds",
+	"request attempts including retries, one disables retries",
+	"retry backoff base, milliseconds, doubled every attempt",
+	"retry backoff cap, milliseconds",
+	"retry backoff jitter fraction, e.g. 0.2 or 20%",
+	"retryable error classes timeout, refused, reset, eof, and status codes",
+	"abort the run on the first failure",
+	"circuit breaker error rate over the window, e.g. 0.5 or 50%, zero disables",
+	"circuit breaker error rate window, seconds",
+	"circuit breaker minimum requests in the window to evaluate the error rate",
+	"circuit breaker consecutive failures, zero disables",
+	"circuit breaker action, abort or pause",
+	"circuit breaker pause, seconds",
+	"switch to the next endpoint when the active one is unreachable",
+	"consecutive connection errors to fail over",
+	"failed endpoint recovery probe interval, seconds",
+	"rate expression, e.g. 0.5/s, 100/s,5000/m,200000/h or 1/200ms, zero is not limited",
+	"requests allowed at once by the rate limits, zero is connection number",
+	"rate schedule CSV file of timestamps and target rates",
+	"rate schedule time compression factor, e.g. 60 plays an hour per minute",
+	"adaptive concurrency algorithm, aimd or gradient",
+	"adaptive concurrency initial limit, zero starts at the lowest",
+	"adaptive concurrency lowest limit",
+	"adaptive concurrency highest limit, zero is the connection number",
+	"adaptive concurrency decrease factor on drops, e.g. 0.9",
+	"gradient concurrency latency tolerance, e.g. 1.5",
+	"adaptive concurrency latency counted as a drop, milliseconds, zero disables",
+	"control endpoint of the running send, host:port or unix:/path/to/socket",
+	"rate schedule report interval, seconds",
 }
 
 // EventID enum
@@ -185,6 +434,12 @@ type StrVal struct {
 	Default string
 }
 
+// StrSliceVal flag value
+type StrSliceVal struct {
+	Value   []string
+	Default []string
+}
+
 // NewFlag returns a new, empty flag set with the specified it, name, etc.
 func NewFlag(id FlagID, flagType FlagType) *Flag {
 	f := &Flag{
@@ -230,6 +485,16 @@ func NewFlagStr(id FlagID, defaultValue string) *Flag {
 	return flag
 }
 
+// NewFlagStrSlice returns a new string slice flag.
+func NewFlagStrSlice(id FlagID, defaultValue []string) *Flag {
+	flag := NewFlag(id, StrSliceType)
+	flag.Value = &StrSliceVal{
+		Default: defaultValue,
+	}
+
+	return flag
+}
+
 // Changed returns true if default value was changed.
 func (f Flag) Changed() (bool, error) {
 	if f.Flagset == nil {
@@ -261,6 +526,8 @@ func (f *Flag) EqualVal(val interface{}) bool {
 		return f.Value.(*UintVal).Value == val.(uint)
 	case BoolType:
 		return f.Value.(*BoolVal).Value == val.(bool)
+	case StrSliceType:
+		return reflect.DeepEqual(f.Value.(*StrSliceVal).Value, val)
 	}
 
 	return false
@@ -275,6 +542,8 @@ func (f Flag) SetValue(val interface{}) {
 		f.Value.(*UintVal).Value = val.(uint)
 	case BoolType:
 		f.Value.(*BoolVal).Value = val.(bool)
+	case StrSliceType:
+		f.Value.(*StrSliceVal).Value = val.([]string)
 	}
 
 	return
@@ -289,6 +558,8 @@ func (f *Flag) EqualDefault(val interface{}) bool {
 		return f.Value.(*UintVal).Default == val.(uint)
 	case BoolType:
 		return f.Value.(*BoolVal).Default == val.(bool)
+	case StrSliceType:
+		return reflect.DeepEqual(f.Value.(*StrSliceVal).Default, val)
 	}
 
 	return false
@@ -4,9 +4,9 @@ package cmd
 
 import "strconv"
 
-const _FlagID_name = "UnknownFlagaddresscltTypeconHiscxnLimcxnNumdirendpointfilelogHisportrateMinrateSecrepeatsaveReqsaveReqDirsaveRessaveResDirsleepMstmoCxntmoRdStmoSectmoWrSwildcardlogLvlconLvlconFlat"
+const _FlagID_name = "UnknownFlagaddresscltTypeconHiscxnLimcxnNumdirendpointfilelogHisportrateMinrateSecrepeatsaveReqsaveReqDirsaveRessaveResDirsleepMstmoCxntmoRdStmoSectmoWrSwildcardlogLvlconLvlconFlatreplayspeeddriftMsmanifestmixweightsseedexcludeorderwatchsettleMsinputsplitdelimcacheMbthinkwarmupsearchsearchMinsearchMaxsearchStepholdSecsloshadowcompareignoreverifygoldenDirmaskstatusmatchnotMatchjsonEqualsjsonExistsminSizemaxSizemaxLatencyMslistenupstreamrecordDirtcphttprespondlatencyerrorRatedropRateerrorStatusrulesadaptivedecreaserecoverStepminRatethrottleStatusrateIntervalSecattemptsbackoffMsbackoffCapMsjitterretryOnfailFastmaxErrRateerrWindowSecerrMinCountmaxConsecutiveonBreakbreakPauseSecfailoverfailoverErrorsprobeSecrateburstschedulescheduleSpeedconcurrencyconcurrencyInitconcurrencyMinconcurrencyMaxconcurrencyBackofftolerancedropLatencyMscontrolscheduleIntervalSec"
 
-var _FlagID_index = [...]uint8{0, 11,18,25,31,37,43,46,54,58,64,68,75,82,88,95,105,112,122,129,135,141,147,153,161,167,173,180}
+var _FlagID_index = [...]uint16{0, 11, 18, 25, 31, 37, 43, 46, 54, 58, 64, 68, 75, 82, 88, 95, 105, 112, 122, 129, 135, 141, 147, 153, 161, 167, 173, 180, 186, 191, 198, 206, 209, 216, 220, 227, 232, 237, 245, 250, 255, 260, 267, 272, 278, 284, 293, 302, 312, 319, 322, 328, 335, 341, 347, 356, 360, 366, 371, 379, 389, 399, 406, 413, 425, 431, 439, 448, 451, 455, 462, 469, 478, 486, 497, 502, 510, 518, 529, 536, 550, 565, 573, 582, 594, 600, 607, 615, 625, 637, 648, 662, 669, 682, 690, 704, 712, 716, 721, 729, 742, 753, 768, 782, 796, 814, 823, 836, 843, 862}
 
 func (i FlagID) String() string {
 	if i < 0 || i >= FlagID(len(_FlagID_index)-1) {
@@ -16,11 +16,18 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alexstov/sling/conf"
 	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/net"
 	"github.com/alexstov/sling/sio"
+	"github.com/alexstov/sling/throt"
+	"github.com/alexstov/sling/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -39,6 +46,14 @@ const (
 	CmdSend
 	// CmdView view command
 	CmdView
+	// CmdRecord record command
+	CmdRecord
+	// CmdServe serve command
+	CmdServe
+	// CmdProxy proxy command
+	CmdProxy
+	// CmdCtl ctl command
+	CmdCtl
 )
 
 var cmdUse = [...]string{
@@ -46,6 +61,10 @@ var cmdUse = [...]string{
 	"root",
 	"send",
 	"view",
+	"record",
+	"serve",
+	"proxy",
+	"ctl",
 }
 
 // Flags has all command flags.
@@ -74,14 +93,18 @@ func NewCmdFlags(cmd *cobra.Command, id ID, slconf *conf.SlingConfig) (flagmappe
 	case CmdSend:
 		flagmapper.Add(NewFlagStr(File, sconf.File), false)
 		flagmapper.Add(NewFlagUint(Repeat, sconf.Repeat), false)
-		flagmapper.Add(NewFlagStr(Dir, sconf.Dir), false)
-		flagmapper.Add(NewFlagStr(Wildcard, sconf.Wildcard), false)
+		flagmapper.Add(NewFlagStrSlice(Dir, splitList(sconf.Dir)), false)
+		flagmapper.Add(NewFlagStrSlice(Wildcard, splitList(sconf.Wildcard)), false)
+		flagmapper.Add(NewFlagStrSlice(Exclude, splitList(sconf.Exclude)), false)
+		flagmapper.Add(NewFlagStr(Order, sconf.Order), false)
 		flagmapper.Add(NewFlagUint(CxnNum, sconf.Throttle.CxnNum), false)
 		flagmapper.Add(NewFlagUint(SleepMs, sconf.Throttle.SleepMs), false)
 		flagmapper.Add(NewFlagStr(SaveReqDir, sconf.SaveReqDir), false)
 		flagmapper.Add(NewFlagStr(SaveResDir, sconf.SaveResDir), false)
 		flagmapper.Add(NewFlagUint(RateSec, sconf.Throttle.RateSec), false)
 		flagmapper.Add(NewFlagUint(RateMin, sconf.Throttle.RateMin), false)
+		flagmapper.Add(NewFlagStr(Rate, sconf.Throttle.Rate), false)
+		flagmapper.Add(NewFlagUint(Burst, sconf.Throttle.Burst), false)
 		flagmapper.Add(NewFlagBool(LogHis, sconf.Log.Histogram), false)
 		flagmapper.Add(NewFlagBool(ConHis, sconf.Console.Histogram), false)
 		flagmapper.Add(NewFlagUint(TmoSec, sconf.Throttle.TmoSec), false)
@@ -95,6 +118,97 @@ func NewCmdFlags(cmd *cobra.Command, id ID, slconf *conf.SlingConfig) (flagmappe
 		flagmapper.Add(NewFlagStr(Address, sconf.Endpoints[sconf.EndpointIndex].Address), false)
 		flagmapper.Add(NewFlagUint(Port, sconf.Endpoints[sconf.EndpointIndex].Port), false)
 		flagmapper.Add(NewFlagStr(CltType, fmt.Sprintf("%s", sconf.Endpoints[sconf.EndpointIndex].Type)), false)
+		flagmapper.Add(NewFlagStr(Replay, sconf.Replay.File), false)
+		flagmapper.Add(NewFlagStr(Speed, sconf.Replay.Speed), false)
+		flagmapper.Add(NewFlagUint(DriftMs, sconf.Replay.DriftMs), false)
+		flagmapper.Add(NewFlagStr(Manifest, sconf.Manifest), false)
+		flagmapper.Add(NewFlagStr(Mix, sconf.Mix.Mode), false)
+		flagmapper.Add(NewFlagStr(Weights, sconf.Mix.Weights), false)
+		flagmapper.Add(NewFlagUint(Seed, sconf.Seed), false)
+		flagmapper.Add(NewFlagBool(Watch, sconf.Watch.Enabled), false)
+		flagmapper.Add(NewFlagUint(SettleMs, sconf.Watch.SettleMs), false)
+		flagmapper.Add(NewFlagStr(Input, sconf.Input.File), false)
+		flagmapper.Add(NewFlagStr(Split, sconf.Input.Split), false)
+		flagmapper.Add(NewFlagStr(Delim, sconf.Input.Delim), false)
+		flagmapper.Add(NewFlagUint(CacheMb, sconf.CacheMb), false)
+		flagmapper.Add(NewFlagStr(Think, sconf.Throttle.Think), false)
+		flagmapper.Add(NewFlagStr(Warmup, sconf.Warmup), false)
+		flagmapper.Add(NewFlagStr(Search, sconf.Search.Mode), false)
+		flagmapper.Add(NewFlagUint(SearchMin, sconf.Search.Min), false)
+		flagmapper.Add(NewFlagUint(SearchMax, sconf.Search.Max), false)
+		flagmapper.Add(NewFlagUint(SearchStep, sconf.Search.Step), false)
+		flagmapper.Add(NewFlagUint(HoldSec, sconf.Search.HoldSec), false)
+		flagmapper.Add(NewFlagStr(SLO, sconf.Search.SLO), false)
+		flagmapper.Add(NewFlagBool(Adaptive, sconf.Adaptive.Enabled), false)
+		flagmapper.Add(NewFlagStr(Decrease, sconf.Adaptive.Decrease), false)
+		flagmapper.Add(NewFlagUint(RecoverStep, sconf.Adaptive.Step), false)
+		flagmapper.Add(NewFlagUint(MinRate, sconf.Adaptive.MinRate), false)
+		flagmapper.Add(NewFlagStrSlice(ThrottleStatus, intList(sconf.Adaptive.Statuses)), false)
+		flagmapper.Add(NewFlagUint(RateIntervalSec, sconf.Adaptive.IntervalSec), false)
+		flagmapper.Add(NewFlagStr(Schedule, sconf.Schedule.File), false)
+		flagmapper.Add(NewFlagStr(ScheduleSpeed, sconf.Schedule.Speed), false)
+		flagmapper.Add(NewFlagUint(ScheduleIntervalSec, sconf.Schedule.IntervalSec), false)
+		flagmapper.Add(NewFlagStr(Concurrency, sconf.Concurrency.Algo), false)
+		flagmapper.Add(NewFlagUint(ConcurrencyInit, sconf.Concurrency.Initial), false)
+		flagmapper.Add(NewFlagUint(ConcurrencyMin, sconf.Concurrency.Min), false)
+		flagmapper.Add(NewFlagUint(ConcurrencyMax, sconf.Concurrency.Max), false)
+		flagmapper.Add(NewFlagStr(ConcurrencyBackoff, sconf.Concurrency.Backoff), false)
+		flagmapper.Add(NewFlagStr(Tolerance, sconf.Concurrency.Tolerance), false)
+		flagmapper.Add(NewFlagUint(DropLatencyMs, sconf.Concurrency.DropLatencyMs), false)
+		flagmapper.Add(NewFlagStr(Control, sconf.Control.Listen), false)
+		flagmapper.Add(NewFlagUint(Attempts, sconf.Retry.Attempts), false)
+		flagmapper.Add(NewFlagUint(BackoffMs, sconf.Retry.BaseMs), false)
+		flagmapper.Add(NewFlagUint(BackoffCapMs, sconf.Retry.CapMs), false)
+		flagmapper.Add(NewFlagStr(Jitter, sconf.Retry.Jitter), false)
+		flagmapper.Add(NewFlagStrSlice(RetryOn, sconf.Retry.On), false)
+		flagmapper.Add(NewFlagBool(FailFast, sconf.Breaker.FailFast), false)
+		flagmapper.Add(NewFlagStr(MaxErrRate, sconf.Breaker.ErrRate), false)
+		flagmapper.Add(NewFlagUint(ErrWindowSec, sconf.Breaker.WindowSec), false)
+		flagmapper.Add(NewFlagUint(ErrMinCount, sconf.Breaker.MinRequests), false)
+		flagmapper.Add(NewFlagUint(MaxConsecutive, sconf.Breaker.Consecutive), false)
+		flagmapper.Add(NewFlagStr(OnBreak, sconf.Breaker.Action), false)
+		flagmapper.Add(NewFlagUint(BreakPauseSec, sconf.Breaker.PauseSec), false)
+		flagmapper.Add(NewFlagBool(Failover, sconf.Failover.Enabled), false)
+		flagmapper.Add(NewFlagUint(FailoverErrors, sconf.Failover.Errors), false)
+		flagmapper.Add(NewFlagUint(ProbeSec, sconf.Failover.ProbeSec), false)
+		flagmapper.Add(NewFlagStr(Shadow, sconf.Shadow.Endpoint), false)
+		flagmapper.Add(NewFlagStr(Compare, sconf.Shadow.Compare), false)
+		flagmapper.Add(NewFlagStrSlice(Ignore, splitList(sconf.Shadow.Ignore)), false)
+		flagmapper.Add(NewFlagBool(Verify, sconf.Golden.Verify), false)
+		flagmapper.Add(NewFlagStr(GoldenDir, sconf.Golden.Dir), false)
+		flagmapper.Add(NewFlagStrSlice(Mask, sconf.Golden.Masks), false)
+		flagmapper.Add(NewFlagStrSlice(Status, intList(sconf.Assert.Status)), false)
+		flagmapper.Add(NewFlagStrSlice(Match, sconf.Assert.Match), false)
+		flagmapper.Add(NewFlagStrSlice(NotMatch, sconf.Assert.NotMatch), false)
+		flagmapper.Add(NewFlagStrSlice(JSONEquals, sconf.Assert.JSONEquals), false)
+		flagmapper.Add(NewFlagStrSlice(JSONExists, sconf.Assert.JSONExists), false)
+		flagmapper.Add(NewFlagUint(MinSize, sconf.Assert.MinSize), false)
+		flagmapper.Add(NewFlagUint(MaxSize, sconf.Assert.MaxSize), false)
+		flagmapper.Add(NewFlagUint(MaxLatencyMs, sconf.Assert.MaxLatencyMs), false)
+	case CmdRecord:
+		flagmapper.Add(NewFlagStr(CltType, fmt.Sprintf("%s", sconf.Record.Type)), false)
+		flagmapper.Add(NewFlagStr(Listen, sconf.Record.Listen), false)
+		flagmapper.Add(NewFlagStr(Upstream, sconf.Record.Upstream), false)
+		flagmapper.Add(NewFlagStr(RecordDir, sconf.Record.Dir), false)
+		flagmapper.Add(NewFlagUint(TmoSec, sconf.Throttle.TmoSec), false)
+	case CmdServe:
+		flagmapper.Add(NewFlagStr(TCPListen, sconf.Serve.TCP), false)
+		flagmapper.Add(NewFlagStr(HTTPListen, sconf.Serve.HTTP), false)
+		flagmapper.Add(NewFlagStr(Respond, sconf.Serve.Respond), false)
+		flagmapper.Add(NewFlagStr(Latency, sconf.Serve.Latency), false)
+		flagmapper.Add(NewFlagStr(ErrorRate, sconf.Serve.ErrorRate), false)
+		flagmapper.Add(NewFlagStr(DropRate, sconf.Serve.DropRate), false)
+		flagmapper.Add(NewFlagUint(ErrorStatus, sconf.Serve.ErrorStatus), false)
+		flagmapper.Add(NewFlagUint(Seed, sconf.Seed), false)
+	case CmdProxy:
+		flagmapper.Add(NewFlagStr(Listen, sconf.Proxy.Listen), false)
+		flagmapper.Add(NewFlagStr(Upstream, sconf.Proxy.Upstream), false)
+		flagmapper.Add(NewFlagStr(Rules, sconf.Proxy.Rules), false)
+		flagmapper.Add(NewFlagUint(TmoCxn, sconf.Throttle.TmoCxn), false)
+		flagmapper.Add(NewFlagUint(Seed, sconf.Seed), false)
+	case CmdCtl:
+		flagmapper.Add(NewFlagStr(Control, sconf.Control.Listen), false)
+		flagmapper.Add(NewFlagUint(TmoSec, sconf.Throttle.TmoSec), false)
 	}
 
 	flagmapper.SetExplicit()
@@ -162,16 +276,20 @@ func (fs Flags) ResolveSendArgs(args *emul.SendArgs) (err error) {
 		// Get the file attributes for file or directory.
 		if fi, err = os.Stat(flag.Value.(*StrVal).Value); os.IsNotExist(err) {
 			// Only file name is passed. Build the full path.
-			// NOTE: SrcDir may have been passed explicitly too.
+			// NOTE: SrcDir may have been passed explicitly too, the first directory is used.
 			// TODO: validate file atrributes here?
-			if fi, err = os.Stat(fs.Map[Dir].Value.(*StrVal).Value); err != nil || fi == nil || !fi.IsDir() {
+			var dir string
+			if dirs := fs.Map[Dir].Value.(*StrSliceVal).Value; len(dirs) > 0 {
+				dir = dirs[0]
+			}
+			if fi, err = os.Stat(dir); err != nil || fi == nil || !fi.IsDir() {
 				logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": fs.Map[Dir]}, "Invalid directory.")
 				err = errors.Wrap(err, "os.Stat")
 				return
 			}
 
 			var filePath string
-			if filePath, err = fs.Filer.BuildFilePath(fs.Map[Dir].Value.(*StrVal).Value, flag.Value.(*StrVal).Value); err != nil {
+			if filePath, err = fs.Filer.BuildFilePath(dir, flag.Value.(*StrVal).Value); err != nil {
 				logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": fs.Map[Dir]}, "Invalid filepath.")
 				err = errors.Wrap(err, "os.Stat")
 				return
@@ -190,12 +308,112 @@ func (fs Flags) ResolveSendArgs(args *emul.SendArgs) (err error) {
 		// SrcDir is set explicitly.
 		args.SendType = emul.MultiReq
 
-		if fi, err = os.Stat(flag.Value.(*StrVal).Value); err != nil || fi == nil || !fi.IsDir() {
-			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": fs.Map[Dir]}, "Invalid directory.")
-			err = errors.Wrap(err, "os.Stat")
+		for _, dir := range flag.Value.(*StrSliceVal).Value {
+			if fi, err = os.Stat(dir); err != nil || fi == nil || !fi.IsDir() {
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "dir": dir}, "Invalid directory.")
+				err = errors.Wrap(err, "os.Stat")
+				return
+			}
+		}
+	}
+
+	// Resolve request source, the spool, input, replay and manifest cannot be combined.
+	var sources []string
+	if flag, ok := fs.Map[Watch]; ok && flag.Value.(*BoolVal).Value {
+		if _, ok := fs.Explicit[File]; !ok {
+			sources = append(sources, flag.Name)
+		}
+	}
+	for _, id := range []FlagID{Input, Replay, Manifest} {
+		if flag, ok := fs.Explicit[id]; ok {
+			sources = append(sources, flag.Name)
+		}
+	}
+	if len(sources) > 1 {
+		err = fmt.Errorf("request sources %s cannot be combined", strings.Join(sources, ", "))
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"sources": sources, "error": err}, "Invalid request source.")
+		return
+	}
+
+	// Resolve watch flag, the spooled files in the directories are the requests to send.
+	if flag, ok := fs.Map[Watch]; ok && flag.Value.(*BoolVal).Value {
+		if _, ok := fs.Explicit[File]; !ok {
+			args.SendType = emul.WatchReq
+			args.SettleMs = fs.Map[SettleMs].Value.(*UintVal).Value
+
+			for _, dir := range fs.Map[Dir].Value.(*StrSliceVal).Value {
+				if fi, err = os.Stat(dir); err != nil || fi == nil || !fi.IsDir() {
+					logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "dir": dir}, "Invalid spool directory.")
+					err = errors.Wrap(err, "os.Stat")
+					return
+				}
+			}
+		}
+	}
+
+	// Resolve input flag, the requests are read from stdin or a named pipe.
+	if flag, ok := fs.Explicit[Input]; ok {
+		args.SendType = emul.InputReq
+		args.Input = flag.Value.(*StrVal).Value
+
+		if args.Split, err = sio.ParseSplitMode(fs.Map[Split].Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Split, "flag": fs.Map[Split]}, "Invalid input split mode.")
+			err = errors.Wrap(err, "sio.ParseSplitMode")
+			return
+		}
+		args.Delim = sio.ParseDelim(fs.Map[Delim].Value.(*StrVal).Value)
+	}
+
+	// Resolve replay flag, the timeline sets the requests to send.
+	if flag, ok := fs.Explicit[Replay]; ok {
+		args.SendType = emul.ReplayReq
+
+		if args.Timeline, err = emul.ReadTimeline(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid replay timeline.")
+			err = errors.Wrap(err, "emul.ReadTimeline")
+			return
+		}
+		if args.Speed, err = emul.ParseSpeed(fs.Map[Speed].Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Speed, "flag": fs.Map[Speed]}, "Invalid replay speed.")
+			err = errors.Wrap(err, "emul.ParseSpeed")
+			return
+		}
+		args.DriftMs = fs.Map[DriftMs].Value.(*UintVal).Value
+	}
+
+	// Resolve manifest flag, an alternative to the file and directory.
+	if flag, ok := fs.Explicit[Manifest]; ok {
+		args.SendType = emul.ManifestReq
+
+		if args.Manifest, err = emul.ReadManifest(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid request manifest.")
+			err = errors.Wrap(err, "emul.ReadManifest")
+			return
+		}
+	}
+
+	// Resolve request mix and session seed.
+	if flag, ok := fs.Map[Mix]; ok {
+		if args.MixMode, err = emul.ParseMixMode(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid request mix.")
+			err = errors.Wrap(err, "emul.ParseMixMode")
 			return
 		}
 	}
+	if flag, ok := fs.Map[Weights]; ok && flag.Value.(*StrVal).Value != "" {
+		if args.Weights, err = emul.ReadWeights(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid weights file.")
+			err = errors.Wrap(err, "emul.ReadWeights")
+			return
+		}
+	}
+	if flag, ok := fs.Map[Seed]; ok {
+		args.Seed = int64(flag.Value.(*UintVal).Value)
+	}
+	if args.Seed == 0 {
+		args.Seed = time.Now().UnixNano()
+	}
+	logger.Out(logrus.InfoLevel, logrus.Fields{"SessionID": SessionID, "Seed": args.Seed}, "Session seed.")
 
 	// Resolve repeat flag.
 	if flag, ok := fs.Map[Repeat]; ok {
@@ -216,34 +434,156 @@ func (fs Flags) ResolveSendArgs(args *emul.SendArgs) (err error) {
 				args.SendType = emul.RepeatReq
 			}
 		}
+
+		if args.SendType == emul.ReplayReq {
+			// Replay all timeline requests.
+			args.Repeat = uint(len(args.Timeline))
+		}
+
+		if _, ok := fs.Explicit[Repeat]; !ok && args.SendType == emul.ManifestReq {
+			// Send all manifest requests, each one weight times.
+			args.Repeat = 0
+			for _, req := range args.Manifest {
+				args.Repeat += req.Weight
+			}
+		}
 	}
 
 	// Apply Wildcard
 	if flag, ok := fs.Map[Wildcard]; ok {
 		// Apply wildcard.
-		args.Wildcard = flag.Value.(*StrVal).Value
+		args.Wildcards = flag.Value.(*StrSliceVal).Value
 	}
 
 	// Apply SrcDir
 	if flag, ok := fs.Map[Dir]; ok {
 		// Apply wildcard.
-		args.SrcDir = flag.Value.(*StrVal).Value
+		args.SrcDirs = flag.Value.(*StrSliceVal).Value
+	}
+
+	if flag, ok := fs.Map[Exclude]; ok {
+		// Apply exclude globs.
+		args.Excludes = flag.Value.(*StrSliceVal).Value
+	}
+
+	if flag, ok := fs.Map[Order]; ok {
+		// Apply send order.
+		if args.Order, err = sio.ParseOrder(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid send order.")
+			err = errors.Wrap(err, "sio.ParseOrder")
+			return
+		}
+	}
+
+	if flag, ok := fs.Map[CacheMb]; ok {
+		// Apply request body cache budget.
+		args.CacheMb = flag.Value.(*UintVal).Value
+	}
+
+	if flag, ok := fs.Map[Warmup]; ok {
+		// Apply warm-up phase.
+		if args.WarmupDur, args.WarmupCount, err = emul.ParseWarmup(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid warm-up.")
+			err = errors.Wrap(err, "emul.ParseWarmup")
+			return
+		}
 	}
 
 	if flag, ok := fs.Map[SleepMs]; ok {
 		// Apply send delay.
 		args.SleepMs = flag.Value.(*UintVal).Value
 	}
-	// Apply connectoin number and delay for RepeatReq and MultiReq
-	if args.SendType == emul.RepeatReq || args.SendType == emul.MultiReq {
+	if flag, ok := fs.Map[Think]; ok {
+		// Apply think time, it replaces the send delay.
+		if args.Think, err = throt.ParseThink(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid think time.")
+			err = errors.Wrap(err, "throt.ParseThink")
+			return
+		}
+	}
+	// Apply connection number for the send types with multiple requests.
+	if args.SendType == emul.RepeatReq || args.SendType == emul.MultiReq || args.SendType == emul.ReplayReq || args.SendType == emul.ManifestReq || args.SendType == emul.WatchReq || args.SendType == emul.InputReq {
 		if flag, ok := fs.Map[CxnNum]; ok {
 			// Apply connection number.
 			args.CxnNum = flag.Value.(*UintVal).Value
 		}
+		if args.SendType == emul.WatchReq || args.SendType == emul.InputReq {
+			// Spool and input are not bounded, send with connection number workers.
+			args.Repeat = args.CxnNum
+		}
 	} else {
 		args.CxnNum = 1
 	}
 
+	// Resolve adaptive rate.
+	if flag, ok := fs.Map[Adaptive]; ok && flag.Value.(*BoolVal).Value {
+		if args.Adaptive, err = fs.resolveAdaptive(); err != nil {
+			return
+		}
+	}
+
+	// Resolve failed request retry policy.
+	if args.Retry, err = fs.resolveRetry(args.Seed); err != nil {
+		return
+	}
+
+	// Resolve run circuit breaker.
+	if args.Breaker, err = fs.resolveBreaker(); err != nil {
+		return
+	}
+
+	// Resolve rate schedule, the requests are sent until the schedule ends.
+	if flag, ok := fs.Map[Schedule]; ok && flag.Value.(*StrVal).Value != "" {
+		if args.Schedule, err = fs.resolveSchedule(flag.Value.(*StrVal).Value); err != nil {
+			return
+		}
+		if args.Adaptive != nil {
+			err = fmt.Errorf("rate schedule cannot be combined with adaptive rate")
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "error": err}, "Invalid rate schedule.")
+			return
+		}
+		if err = fs.resolveEndless(args, "rate schedule"); err != nil {
+			return
+		}
+	}
+	if flag, ok := fs.Map[ScheduleIntervalSec]; ok {
+		args.RateInterval = time.Duration(flag.Value.(*UintVal).Value) * time.Second
+	}
+
+	// Resolve capacity search, the requests are sent until the search completes.
+	if flag, ok := fs.Map[Search]; ok {
+		var mode emul.SearchMode
+		if mode, err = emul.ParseSearchMode(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid search mode.")
+			err = errors.Wrap(err, "emul.ParseSearchMode")
+			return
+		}
+		if mode != emul.NoSearch {
+			if args.Search, err = fs.resolveSearch(mode); err != nil {
+				return
+			}
+			if args.Schedule != nil {
+				err = fmt.Errorf("capacity search cannot be combined with rate schedule")
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "error": err}, "Invalid search.")
+				return
+			}
+			if err = fs.resolveEndless(args, "capacity search"); err != nil {
+				return
+			}
+		}
+	}
+
+	// Resolve adaptive concurrency, the limit does not exceed the connection number.
+	if flag, ok := fs.Map[Concurrency]; ok {
+		if args.Concurrency, err = fs.resolveConcurrency(flag.Value.(*StrVal).Value, args.CxnNum); err != nil {
+			return
+		}
+	}
+
+	if flag, ok := fs.Map[Control]; ok {
+		args.Control = flag.Value.(*StrVal).Value
+	}
+
 	if flag, ok := fs.Map[Address]; ok {
 		// Apply IP address.
 		args.Address = flag.Value.(*StrVal).Value
@@ -266,6 +606,18 @@ func (fs Flags) ResolveSendArgs(args *emul.SendArgs) (err error) {
 		args.RateMin = flag.Value.(*UintVal).Value
 	}
 
+	if flag, ok := fs.Map[Rate]; ok {
+		// Apply rate expression windows, enforced with the rates per second and minute.
+		if args.Rate, err = throt.ParseRate(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": flag.ID, "flag": flag}, "Invalid rate expression.")
+			err = errors.Wrap(err, "throt.ParseRate")
+			return
+		}
+	}
+	if flag, ok := fs.Map[Burst]; ok {
+		args.Burst = flag.Value.(*UintVal).Value
+	}
+
 	if flag, ok := fs.Map[TmoSec]; ok {
 		// Apply timeouts.
 		args.TmoSec = flag.Value.(*UintVal).Value
@@ -286,6 +638,44 @@ func (fs Flags) ResolveSendArgs(args *emul.SendArgs) (err error) {
 	if flag, ok := fs.Map[CltType]; ok {
 		args.CltType = conf.ParseClinetType(flag.Value.(*StrVal).Value)
 	}
+	if sconf != nil {
+		args.Endpoints = sconf.Endpoints
+	}
+
+	// Resolve endpoint failover starting at the active endpoint.
+	if flag, ok := fs.Map[Failover]; ok && flag.Value.(*BoolVal).Value {
+		if args.Failover, err = fs.resolveFailover(args.Endpoints); err != nil {
+			return
+		}
+	}
+
+	// Resolve global response assertions.
+	if _, ok := fs.Map[Status]; ok {
+		if args.Expect, err = fs.resolveExpect(); err != nil {
+			return
+		}
+		if args.Expect != nil && len(args.Expect.Status) > 0 && args.CltType == conf.TCP {
+			err = fmt.Errorf("TCP responses have no status")
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Status, "error": err}, "Invalid expected status.")
+			return
+		}
+	}
+
+	// Resolve golden response verification.
+	if flag, ok := fs.Map[Verify]; ok && flag.Value.(*BoolVal).Value {
+		if args.Golden, err = emul.NewGolden(fs.Map[GoldenDir].Value.(*StrVal).Value, fs.Map[Mask].Value.(*StrSliceVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Mask, "flag": fs.Map[Mask]}, "Invalid golden mask.")
+			err = errors.Wrap(err, "emul.NewGolden")
+			return
+		}
+	}
+
+	// Resolve shadow endpoint to send every request to and compare the responses.
+	if flag, ok := fs.Map[Shadow]; ok && flag.Value.(*StrVal).Value != "" {
+		if args.Shadow, err = fs.resolveShadow(flag.Value.(*StrVal).Value, args.Endpoints); err != nil {
+			return
+		}
+	}
 	if flag, ok := fs.Map[SaveReq]; ok {
 		args.SaveReq = flag.Value.(*BoolVal).Value
 	}
@@ -305,6 +695,123 @@ func (fs Flags) ResolveSendArgs(args *emul.SendArgs) (err error) {
 	return
 }
 
+// ResolveRecordArgs resolves record arguments from command flags.
+func (fs Flags) ResolveRecordArgs(args *net.RecordArgs) (err error) {
+	if flag, ok := fs.Map[CltType]; ok {
+		if args.CltType = conf.ParseClinetType(flag.Value.(*StrVal).Value); args.CltType == conf.UnknownClient {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": CltType, "flag": flag}, "Invalid record type.")
+			return fmt.Errorf("unknown record type %q", flag.Value.(*StrVal).Value)
+		}
+	}
+	if flag, ok := fs.Map[Listen]; ok {
+		args.Listen = flag.Value.(*StrVal).Value
+	}
+	if flag, ok := fs.Map[RecordDir]; ok {
+		args.Dir = flag.Value.(*StrVal).Value + "/" + SessionID
+	}
+	if flag, ok := fs.Map[TmoSec]; ok {
+		args.TmoSec = flag.Value.(*UintVal).Value
+	}
+
+	if flag, ok := fs.Map[Upstream]; ok {
+		args.Upstream = resolveUpstream(flag.Value.(*StrVal).Value, false)
+	}
+
+	return
+}
+
+// ResolveCtlArgs resolves ctl arguments from command flags.
+func (fs Flags) ResolveCtlArgs(args *emul.CtlArgs) (err error) {
+	if flag, ok := fs.Map[Control]; ok {
+		args.Control = flag.Value.(*StrVal).Value
+	}
+	if args.Control == "" {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Control}, "Missing control endpoint.")
+		return fmt.Errorf("no control endpoint, set --control or control listen in SLINGCONFIG")
+	}
+	if flag, ok := fs.Map[TmoSec]; ok {
+		args.Timeout = time.Duration(flag.Value.(*UintVal).Value) * time.Second
+	}
+
+	return
+}
+
+// ResolveProxyArgs resolves proxy arguments from command flags.
+func (fs Flags) ResolveProxyArgs(args *net.ProxyArgs) (err error) {
+	if flag, ok := fs.Map[Listen]; ok {
+		args.Listen = flag.Value.(*StrVal).Value
+	}
+	if flag, ok := fs.Map[Upstream]; ok {
+		args.Upstream = resolveUpstream(flag.Value.(*StrVal).Value, true)
+	}
+	if flag, ok := fs.Map[Rules]; ok {
+		args.Rules = flag.Value.(*StrVal).Value
+	}
+	if flag, ok := fs.Map[TmoCxn]; ok {
+		args.TmoCxn = flag.Value.(*UintVal).Value
+	}
+
+	// Zero seeds from the clock.
+	if flag, ok := fs.Map[Seed]; ok {
+		args.Seed = int64(flag.Value.(*UintVal).Value)
+	}
+	if args.Seed == 0 {
+		args.Seed = time.Now().UnixNano()
+	}
+
+	return
+}
+
+// ResolveServeArgs resolves serve arguments from command flags.
+func (fs Flags) ResolveServeArgs(args *net.ServeArgs) (err error) {
+	if flag, ok := fs.Map[TCPListen]; ok {
+		args.TCPListen = flag.Value.(*StrVal).Value
+	}
+	if flag, ok := fs.Map[HTTPListen]; ok {
+		args.HTTPListen = flag.Value.(*StrVal).Value
+	}
+	if flag, ok := fs.Map[ErrorStatus]; ok {
+		args.ErrorStatus = int(flag.Value.(*UintVal).Value)
+	}
+	if flag, ok := fs.Map[Respond]; ok {
+		if args.Responder, err = net.ParseRespond(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Respond, "flag": flag}, "Invalid mock server responses.")
+			return errors.Wrap(err, "net.ParseRespond")
+		}
+	}
+	if flag, ok := fs.Map[Latency]; ok {
+		if args.Latency, err = throt.ParseThink(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Latency, "flag": flag}, "Invalid mock server latency.")
+			return errors.Wrap(err, "throt.ParseThink")
+		}
+	}
+	if flag, ok := fs.Map[ErrorRate]; ok {
+		if args.ErrorRate, err = util.ParseFraction(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": ErrorRate, "flag": flag}, "Invalid mock server error rate.")
+			return errors.Wrap(err, "util.ParseFraction")
+		}
+	}
+	if flag, ok := fs.Map[DropRate]; ok {
+		if args.DropRate, err = util.ParseFraction(flag.Value.(*StrVal).Value); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": DropRate, "flag": flag}, "Invalid mock server drop rate.")
+			return errors.Wrap(err, "util.ParseFraction")
+		}
+	}
+	if args.ErrorRate+args.DropRate > 1 {
+		return fmt.Errorf("error rate %v and drop rate %v exceed 1", args.ErrorRate, args.DropRate)
+	}
+
+	// Zero seeds from the clock.
+	if flag, ok := fs.Map[Seed]; ok {
+		args.Seed = int64(flag.Value.(*UintVal).Value)
+	}
+	if args.Seed == 0 {
+		args.Seed = time.Now().UnixNano()
+	}
+
+	return
+}
+
 // Add a flag to the command flag set.
 func (fs Flags) Add(flag *Flag, persist bool) {
 	fs.Map[flag.ID] = flag
@@ -343,6 +850,12 @@ func (fs Flags) Add(flag *Flag, persist bool) {
 		} else {
 			logger.Out(logrus.DebugLevel, logrus.Fields{"flag": flag, "fs": fs}, "Invalid flag value TODO.")
 		}
+	case StrSliceType:
+		if _, ok := flag.Value.(*StrSliceVal); ok {
+			cmdFlagSet.StringSliceVarP(&flag.Value.(*StrSliceVal).Value, flag.Name, flag.Shorthand, flag.Value.(*StrSliceVal).Default, flag.Usage)
+		} else {
+			logger.Out(logrus.DebugLevel, logrus.Fields{"flag": flag, "fs": fs}, "Invalid flag value TODO.")
+		}
 	}
 }
 
@@ -358,3 +871,316 @@ func (fs Flags) SetActiveEndpoint(eptIdx uint) {
 	fs.Map[Port].SetValue(sconf.Endpoints[eptIdx].Port)
 	fs.Map[CltType].SetValue(fmt.Sprintf("%s", sconf.Endpoints[eptIdx].Type))
 }
+
+// resolveUpstream resolves the upstream endpoint by name or index, otherwise it is the address.
+// TCP endpoints resolve to host:port and HTTP endpoints to the URL, or to its host:port if hostPort is set.
+func resolveUpstream(name string, hostPort bool) string {
+	if name == "" || sconf == nil {
+		return name
+	}
+
+	ept, _, err := conf.FindEndpoint(sconf.Endpoints, name)
+	if err != nil {
+		return name
+	}
+	if ept.Type == conf.TCP || hostPort {
+		return ept.HostPort()
+	}
+
+	return ept.Address
+}
+
+// splitList splits comma separated config list.
+func splitList(list string) (items []string) {
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// intList formats the numbers as the list items.
+func intList(nums []int) (items []string) {
+	for _, num := range nums {
+		items = append(items, strconv.Itoa(num))
+	}
+	return
+}
+
+// resolveSearch resolves capacity search arguments.
+func (fs Flags) resolveSearch(mode emul.SearchMode) (search *emul.Search, err error) {
+	search = &emul.Search{Mode: mode,
+		Min:  fs.Map[SearchMin].Value.(*UintVal).Value,
+		Max:  fs.Map[SearchMax].Value.(*UintVal).Value,
+		Step: fs.Map[SearchStep].Value.(*UintVal).Value,
+		Hold: time.Duration(fs.Map[HoldSec].Value.(*UintVal).Value) * time.Second}
+
+	if search.Min == 0 || search.Max < search.Min || search.Hold == 0 {
+		err = fmt.Errorf("invalid capacity search range %d..%d, hold %v", search.Min, search.Max, search.Hold)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Search, "error": err}, "Invalid search range.")
+		return nil, err
+	}
+	if search.SLO, err = emul.ParseSLO(fs.Map[SLO].Value.(*StrVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": SLO, "flag": fs.Map[SLO]}, "Invalid SLO.")
+		return nil, errors.Wrap(err, "emul.ParseSLO")
+	}
+
+	return search, nil
+}
+
+// resolveEndless resolves the send type and connection number of the run sending the requests
+// until the feature, search or schedule, completes.
+func (fs Flags) resolveEndless(args *emul.SendArgs, feature string) (err error) {
+	switch args.SendType {
+	case emul.SingleReq, emul.RepeatReq:
+		args.SendType = emul.RepeatReq
+	case emul.MultiReq, emul.ManifestReq:
+	default:
+		err = fmt.Errorf("%s does not support %s requests", feature, args.SendType)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"SendType": args.SendType}, "Invalid "+feature+" requests.")
+		return
+	}
+	if flag, ok := fs.Map[CxnNum]; ok {
+		args.CxnNum = flag.Value.(*UintVal).Value
+	}
+	args.Endless = true
+	args.Repeat = args.CxnNum
+
+	return
+}
+
+// resolveSchedule resolves the rate schedule file and the time compression.
+func (fs Flags) resolveSchedule(path string) (schedule *throt.RateSchedule, err error) {
+	var speed float64
+	if speed, err = emul.ParseSpeed(fs.Map[ScheduleSpeed].Value.(*StrVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": ScheduleSpeed, "flag": fs.Map[ScheduleSpeed]}, "Invalid rate schedule speed.")
+		return nil, errors.Wrap(err, "emul.ParseSpeed")
+	}
+	if schedule, err = throt.ReadRateSchedule(path, speed); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Schedule, "file": path, "error": err}, "Invalid rate schedule.")
+		return nil, errors.Wrap(err, "throt.ReadRateSchedule")
+	}
+
+	return schedule, nil
+}
+
+// resolveAdaptive resolves adaptive rate arguments.
+func (fs Flags) resolveAdaptive() (adaptive *throt.AdaptiveArgs, err error) {
+	adaptive = &throt.AdaptiveArgs{Step: float64(fs.Map[RecoverStep].Value.(*UintVal).Value),
+		MinRate:  float64(fs.Map[MinRate].Value.(*UintVal).Value),
+		Interval: time.Duration(fs.Map[RateIntervalSec].Value.(*UintVal).Value) * time.Second}
+
+	decrease := fs.Map[Decrease].Value.(*StrVal).Value
+	if decrease == "" {
+		decrease = "0.5"
+	}
+	if adaptive.Decrease, err = strconv.ParseFloat(decrease, 64); err != nil || adaptive.Decrease <= 0 || adaptive.Decrease >= 1 {
+		err = fmt.Errorf("invalid adaptive rate decrease %q", decrease)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Decrease, "flag": fs.Map[Decrease]}, "Invalid adaptive rate decrease.")
+		return nil, err
+	}
+	if adaptive.MinRate == 0 {
+		adaptive.MinRate = 1
+	}
+
+	for _, item := range fs.Map[ThrottleStatus].Value.(*StrSliceVal).Value {
+		var status int
+		if status, err = strconv.Atoi(item); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": ThrottleStatus, "status": item}, "Invalid throttling status.")
+			return nil, errors.Wrap(err, "strconv.Atoi")
+		}
+		adaptive.Statuses = append(adaptive.Statuses, status)
+	}
+	if len(adaptive.Statuses) == 0 {
+		adaptive.Statuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	}
+
+	return adaptive, nil
+}
+
+// resolveConcurrency resolves adaptive concurrency, nil if the algorithm is off.
+func (fs Flags) resolveConcurrency(algo string, cxnNum uint) (concurrency *throt.ConcurrencyArgs, err error) {
+	concurrency = &throt.ConcurrencyArgs{Initial: float64(fs.Map[ConcurrencyInit].Value.(*UintVal).Value),
+		Min:        float64(fs.Map[ConcurrencyMin].Value.(*UintVal).Value),
+		Max:        float64(fs.Map[ConcurrencyMax].Value.(*UintVal).Value),
+		MaxLatency: time.Duration(fs.Map[DropLatencyMs].Value.(*UintVal).Value) * time.Millisecond,
+		Interval:   time.Duration(fs.Map[RateIntervalSec].Value.(*UintVal).Value) * time.Second}
+
+	if concurrency.Algo, err = throt.ParseConcurrencyAlgo(algo); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Concurrency, "flag": fs.Map[Concurrency]}, "Invalid concurrency algorithm.")
+		return nil, errors.Wrap(err, "throt.ParseConcurrencyAlgo")
+	}
+	if concurrency.Algo == throt.NoConcurrency {
+		return nil, nil
+	}
+
+	backoff := fs.Map[ConcurrencyBackoff].Value.(*StrVal).Value
+	if backoff == "" {
+		backoff = "0.9"
+	}
+	if concurrency.Backoff, err = strconv.ParseFloat(backoff, 64); err != nil || concurrency.Backoff <= 0 || concurrency.Backoff >= 1 {
+		err = fmt.Errorf("invalid adaptive concurrency backoff %q", backoff)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": ConcurrencyBackoff, "flag": fs.Map[ConcurrencyBackoff]}, "Invalid concurrency backoff.")
+		return nil, err
+	}
+	tolerance := fs.Map[Tolerance].Value.(*StrVal).Value
+	if tolerance == "" {
+		tolerance = "1.5"
+	}
+	if concurrency.Tolerance, err = strconv.ParseFloat(tolerance, 64); err != nil || concurrency.Tolerance < 1 {
+		err = fmt.Errorf("invalid gradient concurrency tolerance %q", tolerance)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Tolerance, "flag": fs.Map[Tolerance]}, "Invalid concurrency tolerance.")
+		return nil, err
+	}
+
+	// The connections send the requests, the limit cannot exceed their number.
+	if concurrency.Max == 0 || concurrency.Max > float64(cxnNum) {
+		if concurrency.Max > float64(cxnNum) {
+			logger.Out(logrus.WarnLevel, logrus.Fields{"ConcurrencyMax": concurrency.Max, "CxnNum": cxnNum}, "Adaptive concurrency limited by the connection number.")
+		}
+		concurrency.Max = float64(cxnNum)
+	}
+
+	return concurrency, nil
+}
+
+// resolveRetry resolves failed request retry policy, nil if requests are not retried.
+func (fs Flags) resolveRetry(seed int64) (retry *emul.Retry, err error) {
+	flag, ok := fs.Map[Attempts]
+	if !ok {
+		return nil, nil
+	}
+
+	var jitter float64
+	if jitter, err = util.ParseFraction(fs.Map[Jitter].Value.(*StrVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Jitter, "flag": fs.Map[Jitter]}, "Invalid retry jitter.")
+		return nil, errors.Wrap(err, "util.ParseFraction")
+	}
+
+	if retry, err = emul.NewRetry(flag.Value.(*UintVal).Value,
+		time.Duration(fs.Map[BackoffMs].Value.(*UintVal).Value)*time.Millisecond,
+		time.Duration(fs.Map[BackoffCapMs].Value.(*UintVal).Value)*time.Millisecond,
+		jitter, fs.Map[RetryOn].Value.(*StrSliceVal).Value, seed); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": RetryOn, "error": err}, "Invalid retry policy.")
+		return nil, errors.Wrap(err, "emul.NewRetry")
+	}
+
+	return retry, nil
+}
+
+// resolveBreaker resolves run circuit breaker, nil if no trip condition is set.
+func (fs Flags) resolveBreaker() (breaker *emul.Breaker, err error) {
+	flag, ok := fs.Map[FailFast]
+	if !ok {
+		return nil, nil
+	}
+
+	breaker = &emul.Breaker{FailFast: flag.Value.(*BoolVal).Value,
+		Window:      time.Duration(fs.Map[ErrWindowSec].Value.(*UintVal).Value) * time.Second,
+		MinRequests: fs.Map[ErrMinCount].Value.(*UintVal).Value,
+		Consecutive: fs.Map[MaxConsecutive].Value.(*UintVal).Value,
+		Pause:       time.Duration(fs.Map[BreakPauseSec].Value.(*UintVal).Value) * time.Second}
+
+	if breaker.ErrRate, err = util.ParseFraction(fs.Map[MaxErrRate].Value.(*StrVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": MaxErrRate, "flag": fs.Map[MaxErrRate]}, "Invalid circuit breaker error rate.")
+		return nil, errors.Wrap(err, "util.ParseFraction")
+	}
+	if breaker.Action, err = emul.ParseBreakerAction(fs.Map[OnBreak].Value.(*StrVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": OnBreak, "flag": fs.Map[OnBreak]}, "Invalid circuit breaker action.")
+		return nil, errors.Wrap(err, "emul.ParseBreakerAction")
+	}
+	if !breaker.Enabled() {
+		return nil, nil
+	}
+	if breaker.Action == emul.PauseBreaker && breaker.Pause == 0 {
+		err = fmt.Errorf("circuit breaker pause is not set")
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": BreakPauseSec, "error": err}, "Invalid circuit breaker pause.")
+		return nil, err
+	}
+
+	return breaker, nil
+}
+
+// resolveFailover resolves endpoint failover over the endpoints of the active endpoint type.
+func (fs Flags) resolveFailover(endpoints []conf.Endpoint) (failover *emul.Failover, err error) {
+	index := int(fs.Map[Endpoint].Value.(*UintVal).Value)
+	if index >= len(endpoints) {
+		err = fmt.Errorf("invalid active endpoint index %d", index)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Endpoint, "error": err}, "Invalid failover endpoint.")
+		return nil, err
+	}
+
+	var candidates []conf.Endpoint
+	active := 0
+	for i, ept := range endpoints {
+		if ept.Type != endpoints[index].Type {
+			continue
+		}
+		if i == index {
+			active = len(candidates)
+		}
+		candidates = append(candidates, ept)
+	}
+
+	if failover, err = emul.NewFailover(candidates, active, fs.Map[FailoverErrors].Value.(*UintVal).Value,
+		time.Duration(fs.Map[ProbeSec].Value.(*UintVal).Value)*time.Second); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Failover, "error": err}, "Invalid failover endpoints.")
+		return nil, errors.Wrap(err, "emul.NewFailover")
+	}
+
+	return failover, nil
+}
+
+// resolveShadow resolves shadow endpoint and comparison arguments.
+func (fs Flags) resolveShadow(name string, endpoints []conf.Endpoint) (shadow *emul.Shadow, err error) {
+	var ept conf.Endpoint
+	if ept, _, err = conf.FindEndpoint(endpoints, name); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Shadow, "endpoint": name}, "Invalid shadow endpoint.")
+		return nil, errors.Wrap(err, "conf.FindEndpoint")
+	}
+
+	var compare emul.CompareMode
+	if compare, err = emul.ParseCompareMode(fs.Map[Compare].Value.(*StrVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Compare, "flag": fs.Map[Compare]}, "Invalid compare mode.")
+		return nil, errors.Wrap(err, "emul.ParseCompareMode")
+	}
+
+	if shadow, err = emul.NewShadow(ept, compare, fs.Map[Ignore].Value.(*StrSliceVal).Value); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Ignore, "flag": fs.Map[Ignore]}, "Invalid ignored JSON path.")
+		return nil, errors.Wrap(err, "emul.NewShadow")
+	}
+
+	return shadow, nil
+}
+
+// resolveExpect resolves global response assertions, nil if none.
+func (fs Flags) resolveExpect() (expect *emul.Expect, err error) {
+	expect = &emul.Expect{Match: fs.Map[Match].Value.(*StrSliceVal).Value,
+		NotMatch:     fs.Map[NotMatch].Value.(*StrSliceVal).Value,
+		JSONEquals:   fs.Map[JSONEquals].Value.(*StrSliceVal).Value,
+		JSONExists:   fs.Map[JSONExists].Value.(*StrSliceVal).Value,
+		MinSize:      fs.Map[MinSize].Value.(*UintVal).Value,
+		MaxSize:      fs.Map[MaxSize].Value.(*UintVal).Value,
+		MaxLatencyMs: fs.Map[MaxLatencyMs].Value.(*UintVal).Value}
+
+	for _, item := range fs.Map[Status].Value.(*StrSliceVal).Value {
+		var status int
+		if status, err = strconv.Atoi(item); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"id": Status, "status": item}, "Invalid expected status.")
+			return nil, errors.Wrap(err, "strconv.Atoi")
+		}
+		expect.Status = append(expect.Status, status)
+	}
+
+	if len(expect.Status) == 0 && len(expect.Match) == 0 && len(expect.NotMatch) == 0 && len(expect.JSONEquals) == 0 &&
+		len(expect.JSONExists) == 0 && expect.MinSize == 0 && expect.MaxSize == 0 && expect.MaxLatencyMs == 0 {
+		return nil, nil
+	}
+	if err = expect.Compile(); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "Invalid response assertions.")
+		return nil, errors.Wrap(err, "expect.Compile")
+	}
+
+	return expect, nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/spf13/cobra"
 
 	. "github.com/alexstov/sling/cmd"
+	"github.com/alexstov/sling/emul"
 	"github.com/alexstov/sling/unit"
 )
 
@@ -96,7 +97,7 @@ var _ = Describe("Flagmap", func() {
 				pflags := testCmd.Flags()
 				Expect(pflags).ShouldNot(BeNil())
 				flagMap := flags.GetFlagmap()
-				Expect(23).To(Equal(len(flagMap)))
+				Expect(94).To(Equal(len(flagMap)))
 				flag = flagMap[File]
 				Expect(flag).ShouldNot(BeNil())
 				flag = flagMap[Repeat]
@@ -139,6 +140,101 @@ var _ = Describe("Flagmap", func() {
 				Expect(flag).ShouldNot(BeNil())
 				flag = flagMap[SaveRes]
 				Expect(flag).ShouldNot(BeNil())
+				flag = flagMap[Replay]
+				Expect(flag).ShouldNot(BeNil())
+				flag = flagMap[Speed]
+				Expect(flag).ShouldNot(BeNil())
+				flag = flagMap[DriftMs]
+				Expect(flag).ShouldNot(BeNil())
+				flag = flagMap[Manifest]
+				Expect(flag).ShouldNot(BeNil())
+			})
+		})
+		Context("record command", func() {
+			It("flags created", func() {
+				defer GinkgoRecover()
+				defer mockCtrl.Finish()
+				sconf := unit.NewConfig()
+
+				flags, err = NewCmdFlags(testCmd, CmdRecord, &sconf)
+
+				Expect(err).Should(BeNil())
+				flagMap := flags.GetFlagmap()
+				Expect(len(flagMap)).To(Equal(5))
+				Expect(flagMap[CltType]).ShouldNot(BeNil())
+				Expect(flagMap[Listen]).ShouldNot(BeNil())
+				Expect(flagMap[Upstream]).ShouldNot(BeNil())
+				Expect(flagMap[RecordDir]).ShouldNot(BeNil())
+			})
+		})
+		Context("serve command", func() {
+			It("flags created", func() {
+				defer GinkgoRecover()
+				defer mockCtrl.Finish()
+				sconf := unit.NewConfig()
+
+				flags, err = NewCmdFlags(testCmd, CmdServe, &sconf)
+
+				Expect(err).Should(BeNil())
+				flagMap := flags.GetFlagmap()
+				Expect(len(flagMap)).To(Equal(8))
+				Expect(flagMap[TCPListen]).ShouldNot(BeNil())
+				Expect(flagMap[HTTPListen]).ShouldNot(BeNil())
+				Expect(flagMap[Respond]).ShouldNot(BeNil())
+				Expect(flagMap[Latency]).ShouldNot(BeNil())
+			})
+		})
+		Context("proxy command", func() {
+			It("flags created", func() {
+				defer GinkgoRecover()
+				defer mockCtrl.Finish()
+				sconf := unit.NewConfig()
+
+				flags, err = NewCmdFlags(testCmd, CmdProxy, &sconf)
+
+				Expect(err).Should(BeNil())
+				flagMap := flags.GetFlagmap()
+				Expect(len(flagMap)).To(Equal(5))
+				Expect(flagMap[Listen]).ShouldNot(BeNil())
+				Expect(flagMap[Upstream]).ShouldNot(BeNil())
+				Expect(flagMap[Rules]).ShouldNot(BeNil())
+			})
+		})
+		Context("ctl command", func() {
+			It("flags created", func() {
+				defer GinkgoRecover()
+				defer mockCtrl.Finish()
+				sconf := unit.NewConfig()
+
+				flags, err = NewCmdFlags(testCmd, CmdCtl, &sconf)
+
+				Expect(err).Should(BeNil())
+				flagMap := flags.GetFlagmap()
+				Expect(len(flagMap)).To(Equal(2))
+				Expect(flagMap[Control]).ShouldNot(BeNil())
+				Expect(flagMap[TmoSec]).ShouldNot(BeNil())
+			})
+		})
+	})
+
+	Describe("ResolveSendArgs", func() {
+		Context("replay and manifest", func() {
+			It("request sources rejected", func() {
+				defer GinkgoRecover()
+				defer mockCtrl.Finish()
+				sconf := unit.NewConfig()
+
+				flags, err = NewCmdFlags(testCmd, CmdSend, &sconf)
+				Expect(err).Should(BeNil())
+				Expect(testCmd.Flags().Set("replay", "timeline.csv")).Should(BeNil())
+				Expect(testCmd.Flags().Set("manifest", "manifest.yml")).Should(BeNil())
+				flags.SetExplicit()
+
+				var args emul.SendArgs
+				err = flags.ResolveSendArgs(&args)
+
+				Expect(err).ShouldNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("replay, manifest"))
 			})
 		})
 	})
@@ -14,7 +14,10 @@
 
 package cmd
 
-import "github.com/alexstov/sling/emul"
+import (
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/net"
+)
 
 // Flagmapper builds command flags.
 type Flagmapper interface {
@@ -23,6 +26,10 @@ type Flagmapper interface {
 	GetFlagmap() map[FlagID]*Flag
 	GetExplicit() map[FlagID]*Flag
 	ResolveSendArgs(args *emul.SendArgs) (err error)
+	ResolveRecordArgs(args *net.RecordArgs) (err error)
+	ResolveServeArgs(args *net.ServeArgs) (err error)
+	ResolveProxyArgs(args *net.ProxyArgs) (err error)
+	ResolveCtlArgs(args *emul.CtlArgs) (err error)
 	AddEvent(flagID FlagID, name interface{}, performing interface{}, parameters ...interface{}) (flag bool, err error)
 	SetActiveEndpoint(eptIdx uint)
 }
@@ -0,0 +1,35 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// GoldenCmd command.
+var GoldenCmd = &cobra.Command{
+	Use:                   "golden SUBCOMMAND",
+	DisableFlagsInUseLine: true,
+	Short:                 "Manage golden responses",
+	Long: `
+	Manage golden responses used to verify the responses with sling request send --verify`,
+	Example: `
+	# Approve the responses saved in the latest session as the new golden responses
+	sling golden approve`,
+}
+
+func init() {
+	RootCmd.AddCommand(GoldenCmd)
+}
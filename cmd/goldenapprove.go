@@ -0,0 +1,90 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/alexstov/sling/emul"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// GoldenapproveCmd command.
+var GoldenapproveCmd = &cobra.Command{
+	Use:   "approve [SESSION]",
+	Short: "Approve session responses as golden responses",
+	Long: `
+	Promote the responses saved in the session to the golden responses of the request files.
+	The session is the response directory name under saveResDir, the latest session by default.`,
+	Example: `
+	# Approve the responses saved in the latest session
+	sling golden approve
+
+	# Approve the responses saved in the session
+	sling golden approve 2019-07-20T10:39:05.123456789`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  goldenapproveRun,
+}
+
+func goldenapproveRun(cmd *cobra.Command, args []string) {
+	var err error
+	var session string
+	var count, skipped int
+
+	if len(args) > 0 {
+		session = args[0]
+	} else if session, err = latestSession(sconf.SaveResDir); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Approve command execution failed.")
+		return
+	}
+
+	if count, skipped, err = emul.ApproveGoldens(filepath.Join(sconf.SaveResDir, session), sconf.Golden.Dir); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"Session": session, "error": err}, "Approve command execution failed.")
+		return
+	}
+
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Session": session, "Count": count, "Skipped": skipped, "Dir": sconf.Golden.Dir}, "Golden responses approved.")
+}
+
+// latestSession returns the most recent session with the saved responses index.
+func latestSession(resDir string) (session string, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(resDir); err != nil {
+		return
+	}
+
+	var latest os.FileInfo
+	for _, info := range infos {
+		if _, errS := os.Stat(filepath.Join(resDir, info.Name(), emul.ResIndex)); !info.IsDir() || errS != nil {
+			continue
+		}
+		if latest == nil || info.ModTime().After(latest.ModTime()) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no sessions with saved responses in %s", resDir)
+	}
+
+	return latest.Name(), nil
+}
+
+func init() {
+	GoldenCmd.AddCommand(GoldenapproveCmd)
+}
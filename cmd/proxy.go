@@ -0,0 +1,135 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alexstov/sling/net"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ProxyCmd command.
+var ProxyCmd = &cobra.Command{
+	Use:   "proxy [flags]",
+	Short: "Start the chaos proxy",
+	Long: `
+	Forward TCP between the listen address and the upstream until interrupted, injecting latency, jitter,
+	bandwidth caps, connection resets, half-closes, truncated payloads and byte corruption by the rules file.
+	The rules file is reloaded when it changes or on SIGHUP and applies to the open connections too.`,
+	Example: `
+	# Proxy port 8636 to the "tcp" endpoint with the faults of the rules file
+	sling proxy --listen :8636 --upstream tcp --rules /home/alexstov/sling/chaos.yml
+
+	# Switch the rules at runtime
+	kill -HUP $(pgrep -f "sling proxy")`,
+	Run: proxyRun,
+}
+
+// Proxy command flags
+var proxyFlags Flagmapper
+
+// Chaos rules file check interval.
+const rulesPollInterval = time.Second
+
+func init() {
+	var err error
+
+	// Proxy flags default to the config.
+	initRoot()
+	if proxyFlags, err = NewCmdFlags(ProxyCmd, CmdProxy, sconf); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create proxy flags.")
+	}
+
+	RootCmd.AddCommand(ProxyCmd)
+}
+
+func proxyRun(cmd *cobra.Command, args []string) {
+	var err error
+	var proxyArgs net.ProxyArgs
+
+	// Set explicit command flags to override config defaults.
+	RootFlags.SetExplicit()
+	proxyFlags.SetExplicit()
+
+	if err = proxyFlags.ResolveProxyArgs(&proxyArgs); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Cannot resolve proxy arguments.")
+		return
+	}
+
+	proxy := net.NewProxy(logger, proxyArgs)
+	if _, err = proxy.Reload(true); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err, "Rules": proxyArgs.Rules}, "Cannot read the chaos rules.")
+		return
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	addr, err := proxy.Start()
+	if err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Proxy command execution failed.")
+		return
+	}
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Listen": addr, "Upstream": proxyArgs.Upstream, "Rules": proxyArgs.Rules,
+		"Seed": proxyArgs.Seed}, "Proxying, interrupt to stop.")
+	outRules(proxy)
+
+	// Reload the rules when the file changes or on SIGHUP, the invalid rules are ignored.
+	ticker := time.NewTicker(rulesPollInterval)
+	defer ticker.Stop()
+	for stop := false; !stop; {
+		var reloaded bool
+		select {
+		case s := <-sig:
+			if s != syscall.SIGHUP {
+				stop = true
+				continue
+			}
+			reloaded, err = proxy.Reload(true)
+		case <-ticker.C:
+			reloaded, err = proxy.Reload(false)
+		}
+
+		if err != nil {
+			Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"error": err, "Rules": proxyArgs.Rules}, "Cannot reload the chaos rules, keeping the current rules.")
+		} else if reloaded {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Rules": proxyArgs.Rules}, "Chaos rules reloaded.")
+			outRules(proxy)
+		}
+	}
+
+	if err = proxy.Stop(); err != nil {
+		logger.Out(logrus.WarnLevel, logrus.Fields{"err": err}, "Cannot stop the proxy cleanly.")
+	}
+
+	stat := proxy.Stats()
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Connections": stat.Connections, "BytesUp": stat.BytesUp, "BytesDown": stat.BytesDown,
+		"Resets": stat.Resets, "HalfCloses": stat.HalfCloses, "Truncations": stat.Truncations, "Corrupted": stat.Corrupted}, "Proxy results.")
+}
+
+// outRules outputs the chaos rules.
+func outRules(proxy *net.Proxy) {
+	for _, rule := range proxy.Rules().Rules {
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Enabled": rule.Active(), "Direction": rule.Direction, "LatencyMs": rule.LatencyMs,
+			"JitterMs": rule.JitterMs, "Bandwidth": rule.Bandwidth, "Reset": rule.Reset, "HalfClose": rule.HalfClose,
+			"Truncate": rule.Truncate, "Corrupt": rule.Corrupt}, "Chaos rule "+rule.Name+".")
+	}
+}
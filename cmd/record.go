@@ -0,0 +1,101 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alexstov/sling/net"
+	"github.com/alexstov/sling/sio"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// RecordCmd command.
+var RecordCmd = &cobra.Command{
+	Use:   "record [flags]",
+	Short: "Record incoming traffic as request files",
+	Long: `
+	Listen as a TCP or HTTP server and save each received request as a request file until interrupted.
+	With the upstream endpoint the requests are forwarded and the upstream responses are recorded too.
+	The session directory under recordDir has the timeline.jsonl of the request offsets to replay.`,
+	Example: `
+	# Record TCP requests received on port 8635
+	sling record --cltType TCP --listen :8635
+
+	# Record HTTP requests forwarded to the "http" endpoint with the responses
+	sling record --cltType HTTPPost --listen :8081 --upstream http
+
+	# Replay the recorded session
+	sling request send --replay /home/alexstov/sling/record/2019-07-20T10:39:05.123456789/timeline.jsonl`,
+	Run: recordRun,
+}
+
+// Record command flags
+var recordFlags Flagmapper
+
+func init() {
+	var err error
+
+	// Record flags default to the config.
+	initRoot()
+	if recordFlags, err = NewCmdFlags(RecordCmd, CmdRecord, sconf); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create record flags.")
+	}
+
+	RootCmd.AddCommand(RecordCmd)
+}
+
+func recordRun(cmd *cobra.Command, args []string) {
+	var err error
+	var recordArgs net.RecordArgs
+	var filer sio.Filer
+
+	// Set explicit command flags to override config defaults.
+	RootFlags.SetExplicit()
+	recordFlags.SetExplicit()
+
+	if err = recordFlags.ResolveRecordArgs(&recordArgs); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Cannot resolve record arguments.")
+		return
+	}
+	if filer, err = sio.NewFiler(logger); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Cannot create filer.")
+		return
+	}
+
+	recorder := net.NewRecorder(logger, filer, recordArgs)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	addr, err := recorder.Start()
+	if err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Record command execution failed.")
+		return
+	}
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Listen": addr, "Upstream": recordArgs.Upstream,
+		"Dir": recordArgs.Dir}, "Recording, interrupt to stop.")
+
+	<-sig
+	if err = recorder.Stop(); err != nil {
+		logger.Out(logrus.WarnLevel, logrus.Fields{"err": err}, "Cannot stop recording cleanly.")
+	}
+
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Count": recorder.Count, "Timeline": recordArgs.Dir + "/" + net.RecordTimeline},
+		"Recording complete.")
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/alexstov/sling/conf"
 	"github.com/alexstov/sling/cui"
@@ -54,20 +55,31 @@ var RootCmd = &cobra.Command{
 // RootFlags command flags
 var RootFlags Flagmapper
 
-func init() {
-	var err error
+// rootOnce initializes the logger and config once.
+var rootOnce sync.Once
 
-	// Create logger with default settings.
-	if logger, err = slog.NewLogger(); err != nil {
-		fmt.Println("Failed to create logger", err)
-		return
-	}
+func init() {
+	initRoot()
+}
 
-	logger.Out(logrus.TraceLevel, nil, "Calling root command initializer")
-	if err = initSlingFromConfig(); err != nil {
-		fmt.Println("Failed to initialize sling from config.", err)
-		return
-	}
+// initRoot creates the logger and initializes sling from config, the command
+// initializers in the files ordered before root.go call it before creating the flags.
+func initRoot() {
+	rootOnce.Do(func() {
+		var err error
+
+		// Create logger with default settings.
+		if logger, err = slog.NewLogger(); err != nil {
+			fmt.Println("Failed to create logger", err)
+			return
+		}
+
+		logger.Out(logrus.TraceLevel, nil, "Calling root command initializer")
+		if err = initSlingFromConfig(); err != nil {
+			fmt.Println("Failed to initialize sling from config.", err)
+			return
+		}
+	})
 }
 
 func initSlingFromConfig() (err error) {
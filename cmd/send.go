@@ -17,9 +17,11 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alexstov/sling/conf"
@@ -27,6 +29,7 @@ import (
 	"github.com/alexstov/sling/net"
 	"github.com/alexstov/sling/sio"
 	"github.com/alexstov/sling/throt"
+	"github.com/alexstov/sling/util"
 	"github.com/cloudflare/cfssl/log"
 	"github.com/rcrowley/go-metrics"
 	"github.com/sirupsen/logrus"
@@ -40,8 +43,52 @@ var cmdSend = &cobra.Command{
 
 Examples:
 # Send myfile.dat from /tmp/data directory. Use default send parameters from SLINGCONFIG.
-sling request send -f myfile.dat -d /tmp/data`,
-	Run: sendRun,
+sling request send -f myfile.dat -d /tmp/data
+
+# Replay recorded timeline twice as fast as it was recorded.
+sling request send --replay /tmp/data/timeline.csv --speed 2x
+
+# Send all .dat files under two directories recursively, except backups, in shuffled order.
+sling request send -d /tmp/data -d /tmp/more -w "**/*.dat" --exclude "*.bak.dat" --order shuffle --seed 42
+
+# Watch /tmp/spool and send new files as they arrive until interrupted.
+sling request send -d /tmp/spool --watch
+
+# Send each line generated by jq as a request.
+jq -c '.[]' requests.json | sling request send --input -
+
+# Send requests from a named pipe, each request prefixed by 4-byte big-endian length.
+sling request send --input /tmp/requests.fifo --split len
+
+# Find the highest rate keeping p99 under 200ms and errors under 1%, stepping from 10/s by 10/s every 30s.
+sling request send -f myfile.dat -d /tmp/data --search step --searchMin 10 --searchStep 10 --holdSec 30 --slo "p99<200ms,err<1%"
+
+# Follow the rate curve from day.csv, playing an hour of the schedule per minute.
+sling request send -d /tmp/data --schedule /tmp/data/day.csv --scheduleSpeed 60 -n 50
+
+# Discover the safe number of requests in flight up to 200 connections, requests slower than 300ms are drops.
+sling request send -d /tmp/data -r 10000 -n 200 --concurrency gradient --dropLatencyMs 300
+
+# Soak test with the control endpoint, change the rate and the workers with sling ctl while sending.
+sling request send -d /tmp/data -r 1000000 -n 20 --control localhost:8637
+
+# Send each request to the active and the "new" endpoints and compare the JSON responses except the timestamp.
+sling request send -d /tmp/data -r 100 --shadow new --compare ignore --ignore '$.meta.timestamp'
+
+# Verify the responses against the golden responses masking the timestamp, approve with sling golden approve.
+sling request send -d /tmp/data -o --verify --mask '$.meta.timestamp'
+
+# Fail the requests with a non-200 status, an error in the body or the latency over 500ms.
+sling request send -d /tmp/data -r 100 --status 200 --notMatch '"error"' --maxLatencyMs 500
+
+# Send requests listed in the manifest.
+sling request send --manifest /tmp/data/manifest.yml
+
+# Send 1000 requests from /tmp/data selected at random by weight, reproducible with the same seed.
+sling request send -d /tmp/data -r 1000 --mix weighted --weights /tmp/data/weights.yml --seed 42`,
+	RunE:          sendRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 // Send command flags
@@ -79,8 +126,13 @@ func init() {
 	logger.Out(logrus.TraceLevel, nil, "Send command initialized.")
 }
 
-func sendRun(cmd *cobra.Command, args []string) {
-	var err error
+func sendRun(cmd *cobra.Command, args []string) error {
+	return RunSend(flagmapper)
+}
+
+// RunSend resolves the send arguments from the flags and sends the requests, the run
+// fails when the arguments do not resolve.
+func RunSend(flagmapper Flagmapper) (err error) {
 	var sendArgs emul.SendArgs
 	var em *emul.Emul
 
@@ -93,12 +145,97 @@ func sendRun(cmd *cobra.Command, args []string) {
 
 	// Resolve send command arguments, filepath, etc. from the command flags.
 	if err = flagmapper.ResolveSendArgs(&sendArgs); err != nil {
-		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot resolve send arguments.")
+		Con.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"error": err.Error()}, "Cannot resolve send arguments.")
+		return
 	}
 
 	// Create new emulator.
 	if em, err = NewSendEmul(&sendArgs); err != nil {
-		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create emul.")
+		Con.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"error": err.Error()}, "Cannot create emul.")
+		return
+	}
+
+	// Stop watching the spool, searching or following the schedule on interrupt, the requests in flight are completed.
+	if sendArgs.SendType == emul.WatchReq || sendArgs.Search != nil || sendArgs.Schedule != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sig)
+		go func() {
+			select {
+			case <-sig:
+				logger.Out(logrus.InfoLevel, nil, "Interrupted, stop sending.")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	// Abort the run when the circuit breaker trips, the paused breaker resumes sending.
+	if breaker := sendArgs.Breaker; breaker != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		breaker.OnTrip = func(reason string) {
+			Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"Action": breaker.Action, "Reason": reason}, "Circuit breaker tripped.")
+			if breaker.Action == emul.AbortBreaker {
+				cancel()
+			}
+		}
+	}
+
+	// Control the running send through the control endpoint, stop completes the requests in flight.
+	if sendArgs.Control != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		em.Control = emul.NewControl(em, cancel)
+		em.Control.OnSnapshot = func(state emul.ControlState) {
+			outState("Interim results.", state)
+		}
+		server := emul.NewControlServer(logger, em.Control, sendArgs.Control)
+		if addr, errC := server.Start(); errC != nil {
+			Con.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"error": errC, "Control": sendArgs.Control}, "Cannot start the control endpoint.")
+			em.Control = nil
+		} else {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Control": addr}, "Control endpoint listening.")
+			defer server.Stop()
+		}
+	}
+
+	// Step the rate while the requests are sent, stop sending when the search completes.
+	var best chan uint
+	if sendArgs.Search != nil {
+		em.Search = sendArgs.Search
+		best = make(chan uint, 1)
+		searchCtx, cancel := context.WithCancel(ctx)
+		ctx = searchCtx
+		go func() {
+			rate, searchErr := sendArgs.Search.Run(ctx, em.Limiter)
+			if searchErr != nil {
+				logger.Out(logrus.WarnLevel, logrus.Fields{"err": searchErr}, "Capacity search interrupted.")
+			}
+			best <- rate
+			cancel()
+		}()
+	}
+
+	// Follow the rate schedule while the requests are sent, stop sending when the schedule ends.
+	schedule, _ := em.Limiter.(*throt.ScheduleLimiter)
+	if schedule != nil {
+		scheduleCtx, cancel := context.WithCancel(ctx)
+		ctx = scheduleCtx
+		go func() {
+			if scheduleErr := schedule.Run(ctx); scheduleErr != nil {
+				logger.Out(logrus.WarnLevel, logrus.Fields{"err": scheduleErr}, "Rate schedule interrupted.")
+			}
+			cancel()
+		}()
 	}
 
 	// Make channel large enough to store all requests.
@@ -111,12 +248,12 @@ func sendRun(cmd *cobra.Command, args []string) {
 		// Send a single request.
 		err = em.Dispatcher.SendReq(ctx, sendArgs.Data, &sendArgs)
 
-	case emul.RepeatReq, emul.MultiReq:
+	case emul.RepeatReq, emul.MultiReq, emul.ReplayReq, emul.ManifestReq, emul.WatchReq, emul.InputReq:
 		var wg sync.WaitGroup
 		wg.Add(2)
 
 		// Start gouroutine to prepare all requests
-		go prepareRequests(in, &sendArgs, &wg)
+		go prepareRequests(ctx, in, &sendArgs, em.Filer, &wg)
 
 		// Start gouroutine to send all requests.
 		go em.Dispatcher.MultiSend(ctx, in, &sendArgs, &wg)
@@ -125,6 +262,9 @@ func sendRun(cmd *cobra.Command, args []string) {
 		wg.Wait()
 	}
 
+	// Wait for the responses to be saved before the command exits.
+	em.WaitSaved()
+
 	// Log the histogramm.
 	flagmap := flagmapper.GetFlagmap()
 	if flag, ok := flagmap[LogHis]; ok {
@@ -148,12 +288,163 @@ func sendRun(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Output commanad results.
-	if err != nil {
-		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Send command execution failed.")
-	} else {
+	// Output the number of requests, transport errors and assertion failures.
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Count": em.Histogram.Count(), "Errors": em.Errors.Count(),
+		"Failures": em.Failures.Count()}, "Request results.")
+
+	// Output achieved request mix next to the target mix.
+	if sendArgs.Mix != nil {
+		for _, stat := range sendArgs.Mix.Report() {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Count": stat.Count,
+				"Target": fmt.Sprintf("%.2f%%", stat.Target), "Achieved": fmt.Sprintf("%.2f%%", stat.Achieved)}, stat.Name)
+		}
+	}
+
+	// Output capacity search steps and the highest rate within the SLO.
+	if sendArgs.Search != nil {
+		rate := <-best
+		for _, step := range sendArgs.Search.Steps {
+			fields := logrus.Fields{"Rate": step.Rate, "Achieved": fmt.Sprintf("%.1f", step.Achieved),
+				"Count": step.Count, "Errors": step.Errors, "ErrRate": fmt.Sprintf("%.2f%%", step.ErrRate*100),
+				"Pass": step.Pass}
+			for i, lat := range step.Latency {
+				fields[fmt.Sprintf("p%g", sendArgs.Search.SLO.Percentiles[i]*100)] = lat
+			}
+			if step.Reason != "" {
+				fields["Reason"] = step.Reason
+			}
+			Con.OutLogAndConsole(logrus.InfoLevel, fields, "Capacity search step.")
+		}
+		if rate > 0 {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Best": rate}, "Capacity search complete.")
+		} else {
+			Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"Min": sendArgs.Search.Min}, "Capacity search found no rate within the SLO.")
+		}
+	}
+
+	// Output the effective rate over time and the time spent throttled.
+	if adaptive, ok := em.Limiter.(*throt.AdaptiveLimiter); ok {
+		for _, sample := range adaptive.Samples() {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"At": sample.At.Round(time.Second), "Limit": fmt.Sprintf("%.1f", sample.Limit),
+				"Achieved": fmt.Sprintf("%.1f", sample.Achieved)}, "Effective rate.")
+		}
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Throttled": adaptive.Throttled().Round(time.Millisecond),
+			"Decreases": adaptive.Decreases, "Pauses": adaptive.Pauses}, "Adaptive rate.")
+	}
+
+	// Output the target and achieved rate over the schedule.
+	if schedule != nil {
+		for _, sample := range schedule.Samples() {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"At": sample.At.Round(time.Second), "Target": fmt.Sprintf("%.1f", sample.Limit),
+				"Achieved": fmt.Sprintf("%.1f", sample.Achieved)}, "Scheduled rate.")
+		}
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Points": len(schedule.Schedule.Points), "Speed": schedule.Schedule.Speed,
+			"Duration": schedule.Schedule.Duration().Round(time.Millisecond)}, "Rate schedule.")
+	}
+
+	// Output the adaptive concurrency limit over time.
+	if concurrency := em.Concurrency; concurrency != nil {
+		for _, sample := range concurrency.Samples() {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"At": sample.At.Round(time.Second), "Limit": fmt.Sprintf("%.1f", sample.Limit),
+				"Peak": sample.Peak, "Completed": sample.Completed, "Drops": sample.Drops, "Latency": sample.Latency.Round(time.Millisecond)}, "Concurrency limit.")
+		}
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Algo": concurrency.Args.Algo, "Limit": fmt.Sprintf("%.1f", float64(concurrency.Limit())),
+			"Lowest": fmt.Sprintf("%.1f", concurrency.Lowest), "Highest": fmt.Sprintf("%.1f", concurrency.Highest), "Drops": concurrency.Drops}, "Adaptive concurrency.")
+	}
+
+	// Output retry results, first-try success compared with success after retries.
+	if retry := sendArgs.Retry; retry != nil {
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"FirstTry": retry.FirstTry, "Recovered": retry.Recovered,
+			"Failed": retry.Failed, "Retries": retry.Retries}, "Retry results.")
+	}
+
+	// Output the time the requests spent waiting in the global and endpoint limiter queues.
+	outWait := func(name string, histo metrics.Histogram) {
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Queue": name, "Count": histo.Count(), "MeanMs": fmt.Sprintf("%.1f", histo.Mean()),
+			"P99Ms": fmt.Sprintf("%.0f", histo.Percentile(0.99)), "MaxMs": histo.Max()}, "Limiter wait.")
+	}
+	outWait("total", em.WaitHist)
+	for _, limit := range em.Limits {
+		outWait(limit.Name, limit.Wait)
+	}
+
+	// Output endpoint failover events and the endpoint active at the end of the run.
+	if failover := sendArgs.Failover; failover != nil {
+		failover.Close()
+		for _, event := range failover.Events() {
+			Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"At": event.At.Format("15:04:05.000"), "From": event.From,
+				"To": event.To, "Reason": event.Reason}, "Endpoint "+event.Action+".")
+		}
+		ept, _ := failover.Active()
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Endpoint": emul.EndpointName(ept), "Events": len(failover.Events())}, "Failover results.")
+	}
+
+	// Output circuit breaker trips, the tripped run fails.
+	if breaker := sendArgs.Breaker; breaker != nil && breaker.Trips > 0 {
+		Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"Trips": breaker.Trips, "Action": breaker.Action,
+			"Reason": breaker.Tripped()}, "Circuit breaker.")
+		if err == nil {
+			err = fmt.Errorf("circuit breaker tripped %d times, last %s", breaker.Trips, breaker.Tripped())
+		}
+	}
+
+	// Output shadow comparison summary and the mismatch count by the response field.
+	if sendArgs.Shadow != nil {
+		shadow := sendArgs.Shadow
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Compared": shadow.Compared, "Mismatched": shadow.Mismatched,
+			"Failed": shadow.Failed, "Compare": shadow.Compare}, "Shadow comparison.")
+		for _, stat := range shadow.Summary() {
+			Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"Count": stat.Count}, stat.Path)
+		}
+	}
+
+	// Output golden response mismatches with unified diffs, the command fails on mismatch.
+	if golden := sendArgs.Golden; golden != nil {
+		for _, diff := range golden.Diffs {
+			Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"ReqID": diff.ReqID, "FilePath": diff.FilePath}, "Golden response mismatch.")
+			for _, line := range util.SplitLines(diff.Diff) {
+				Con.OutLogAndConsole(logrus.WarnLevel, nil, line)
+			}
+		}
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Verified": golden.Verified, "Mismatched": len(golden.Diffs),
+			"Missing": golden.Missing, "Dir": golden.Dir}, "Golden verification.")
+		if len(golden.Diffs) > 0 && err == nil {
+			err = fmt.Errorf("%d of %d responses do not match the golden responses", len(golden.Diffs), golden.Verified)
+		}
+	}
+
+	// Output request cache statistics.
+	if cache, ok := em.Filer.(*sio.CacheFiler); ok {
+		stats := cache.Stats()
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Hits": stats.Hits, "Misses": stats.Misses,
+			"Evictions": stats.Evictions, "Entries": stats.Entries, "Bytes": stats.Bytes}, "Request cache.")
+	}
+
+	// Output commanad results, the failed run is reported by the root command.
+	if err == nil {
 		logger.Out(logrus.InfoLevel, nil, "Send command executed.")
 	}
+
+	return
+}
+
+// newClient creates network client of the type.
+func newClient(cltType conf.ClientType, filer sio.Filer) (client net.Client, err error) {
+	switch cltType {
+	case conf.TCP:
+		if client, err = net.NewTCPClient(logger, filer); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create TCP client.")
+		}
+	case conf.HTTPPost:
+		if client, err = net.NewHTTPClient(logger, filer); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create HTTP client.")
+		}
+	default:
+		err = fmt.Errorf("unknown client type %s", cltType)
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create client.")
+	}
+
+	return
 }
 
 // NewSendEmul creates new send emulator.
@@ -167,24 +458,35 @@ func NewSendEmul(sendArgs *emul.SendArgs) (em *emul.Emul, err error) {
 		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create filer.")
 	}
 
+	// Cache request bodies in memory to keep file I/O out of the send loop.
+	// Spooled files are sent once and may reuse the names, they are not cached.
+	if sendArgs.CacheMb > 0 && sendArgs.SendType != emul.WatchReq {
+		filer = sio.NewCacheFiler(filer, int64(sendArgs.CacheMb)<<20)
+	}
+
 	// Create emul limiter.
 	if limiter, err = throt.NewMultiLimiter(
 		&throt.MultiLimitArgs{RateSec: sendArgs.RateSec,
 			RateMin: sendArgs.RateMin,
+			Windows: sendArgs.Rate,
+			Burst:   sendArgs.Burst,
 			CxnNum:  sendArgs.CxnNum}); err != nil {
 		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create limiter.")
 	}
 
-	switch sendArgs.CltType {
-	case conf.TCP:
-		if client, err = net.NewTCPClient(logger, filer); err != nil {
-			logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create TCP client.")
-		}
-	case conf.HTTPPost:
-		if client, err = net.NewHTTPClient(logger, filer); err != nil {
-			logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create HTTP client.")
-		}
+	// Adapt the rate to the endpoint throttling.
+	var adaptive *throt.AdaptiveLimiter
+	if sendArgs.Adaptive != nil {
+		adaptive = throt.NewAdaptiveLimiter(limiter, *sendArgs.Adaptive)
+		limiter = adaptive
 	}
+
+	// Follow the rate schedule, the schedule starts with the run.
+	if sendArgs.Schedule != nil {
+		limiter = throt.NewScheduleLimiter(limiter, sendArgs.Schedule, sendArgs.RateInterval)
+	}
+
+	client, err = newClient(sendArgs.CltType, filer)
 	logger.Out(logrus.InfoLevel, logrus.Fields{"ClientType": sendArgs.CltType}, "Set client type.")
 
 	// Create histogram.
@@ -194,6 +496,91 @@ func NewSendEmul(sendArgs *emul.SendArgs) (em *emul.Emul, err error) {
 	if em, err = emul.NewEmul(client, filer, Con, limiter, logger, histo); err != nil {
 		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create emul.")
 	}
+	em.Registry = reg
+
+	// Create the clients of the endpoint types, the manifest and failover send to any endpoint.
+	em.Clients = map[conf.ClientType]net.Client{sendArgs.CltType: client}
+	for _, ept := range sendArgs.Endpoints {
+		if _, ok := em.Clients[ept.Type]; ok || ept.Type == conf.UnknownClient {
+			continue
+		}
+		if em.Clients[ept.Type], err = newClient(ept.Type, filer); err != nil {
+			return
+		}
+	}
+
+	if adaptive != nil {
+		em.Feedback = adaptive
+	}
+
+	// Adapt the number of requests in flight to the endpoint latency and drops.
+	if sendArgs.Concurrency != nil {
+		em.Concurrency = throt.NewConcurrencyLimiter(*sendArgs.Concurrency)
+	}
+
+	// Limit the rate and concurrency per endpoint, capture the time spent in the limiter queues.
+	em.WaitHist = metrics.NewHistogram(metrics.NewUniformSample(1028))
+	reg.Register("Limiter.wait", em.WaitHist)
+	if em.Limits, err = emul.NewEndpointLimits(sendArgs.Endpoints); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create endpoint limits.")
+		return
+	}
+	for _, limit := range em.Limits {
+		reg.Register("Limiter.wait."+limit.Name, limit.Wait)
+		logger.Out(logrus.InfoLevel, logrus.Fields{"Endpoint": limit.Name, "RateSec": limit.Limiter.Args.RateSec,
+			"RateMin": limit.Limiter.Args.RateMin, "Rate": limit.Limiter.Args.Windows, "Burst": limit.Limiter.Args.Burst,
+			"MaxConn": limit.Limiter.Args.MaxConn}, "Set endpoint limits.")
+	}
+
+	// Count transport errors and response assertion failures separately.
+	em.Errors = metrics.NewCounter()
+	em.Failures = metrics.NewCounter()
+	reg.Register("Client.errors", em.Errors)
+	reg.Register("Client.failures", em.Failures)
+
+	// Create warm-up histogram, the main histograms start clean after the warm-up.
+	if em.Warmup = emul.NewWarmup(sendArgs.WarmupDur, sendArgs.WarmupCount); em.Warmup != nil {
+		em.WarmupHist = metrics.NewHistogram(metrics.NewUniformSample(1028))
+		reg.Register("Warmup", em.WarmupHist)
+		em.Warmup.OnDone = func() {
+			logger.Out(logrus.InfoLevel, logrus.Fields{"Count": em.WarmupHist.Count()}, "Warm-up complete.")
+		}
+	}
+
+	// Create shadow client and histogram to compare with the primary endpoint.
+	if em.Shadow = sendArgs.Shadow; em.Shadow != nil {
+		if em.Shadow.Client, err = newClient(em.Shadow.Endpoint.Type, filer); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create shadow client.")
+		}
+		em.Shadow.Histogram = metrics.NewHistogram(metrics.NewUniformSample(1028))
+		reg.Register("Shadow", em.Shadow.Histogram)
+		logger.Out(logrus.InfoLevel, logrus.Fields{"Endpoint": em.Shadow.Endpoint.Address, "Compare": em.Shadow.Compare}, "Set shadow endpoint.")
+	}
+
+	// Verify the responses against the golden responses.
+	em.Golden = sendArgs.Golden
+
+	// Retry the failed requests.
+	em.Retry = sendArgs.Retry
+
+	// Trip the run circuit breaker on errors.
+	em.Breaker = sendArgs.Breaker
+
+	// Fail over to the next endpoint on connection errors.
+	if em.Failover = sendArgs.Failover; em.Failover != nil {
+		ept, _ := em.Failover.Active()
+		em.Failover.OnEvent = func(event emul.FailoverEvent) {
+			Con.OutLogAndConsole(logrus.WarnLevel, logrus.Fields{"From": event.From, "To": event.To, "Reason": event.Reason},
+				"Endpoint "+event.Action+".")
+		}
+		logger.Out(logrus.InfoLevel, logrus.Fields{"Endpoint": emul.EndpointName(ept), "Endpoints": len(em.Failover.Endpoints)}, "Set endpoint failover.")
+	}
+
+	// Create replay schedule drift histogram.
+	if sendArgs.SendType == emul.ReplayReq {
+		em.Drift = metrics.NewHistogram(metrics.NewUniformSample(1028))
+		reg.Register("Drift", em.Drift)
+	}
 
 	// Create SaveReq directory.
 	if sendArgs.SaveReq {
@@ -213,7 +600,7 @@ func NewSendEmul(sendArgs *emul.SendArgs) (em *emul.Emul, err error) {
 }
 
 // prepareRequests prepares requests to send.
-func prepareRequests(out chan<- interface{}, args *emul.SendArgs, wg *sync.WaitGroup) (err error) {
+func prepareRequests(ctx context.Context, out chan<- interface{}, args *emul.SendArgs, filer sio.Filer, wg *sync.WaitGroup) (err error) {
 	var i uint
 	var filePath string
 	var filelist []string
@@ -224,19 +611,28 @@ func prepareRequests(out chan<- interface{}, args *emul.SendArgs, wg *sync.WaitG
 	switch args.SendType {
 	case emul.RepeatReq:
 		// Prepare to send same repeat request.
-		for i = 1; i <= args.Repeat; i++ {
+		preload(filer, []string{args.Data})
+		for i = 1; args.Endless || i <= args.Repeat; i++ {
 			filePath = args.Data
-			out <- emul.Request{SesID: SessionID, ReqID: uint64(i), FilePath: filePath}
+			if !enqueue(ctx, out, emul.Request{SesID: SessionID, ReqID: uint64(i), FilePath: filePath}) {
+				return
+			}
 			logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Enqueued request.")
 		}
 
 	case emul.MultiReq:
-		// Use Glob to get file list by pattern.
-		filelist, err = filepath.Glob(args.SrcDir + "/" + args.Wildcard)
-		if err != nil {
+		// Use Glob to get file list by patterns, ordered by the send order.
+		var files []sio.GlobFile
+		if files, err = sio.Glob(args.SrcDirs, args.Wildcards, args.Excludes); err != nil {
 			log.Error("Cannot get files by pattern", err)
 			return
 		}
+		sio.SortFiles(files, args.Order, args.Seed)
+		for _, file := range files {
+			filelist = append(filelist, file.Path)
+		}
+		logger.Out(logrus.DebugLevel, logrus.Fields{"files": len(filelist), "order": args.Order, "seed": args.Seed}, "Ordered request files.")
+		preload(filer, filelist)
 
 		if args.Repeat == 0 {
 			// If repeat flag is not set, send all enumerated files.
@@ -245,41 +641,253 @@ func prepareRequests(out chan<- interface{}, args *emul.SendArgs, wg *sync.WaitG
 
 		if len(filelist) == 0 {
 			logger.Out(logrus.DebugLevel,
-				logrus.Fields{"args.RequestDir": args.SrcDir, "args.Pattern": args.Wildcard},
+				logrus.Fields{"args.RequestDir": args.SrcDirs, "args.Pattern": args.Wildcards, "args.Exclude": args.Excludes},
 				"Empty request directory, no requests to send.")
+		} else if args.MixMode == emul.WeightedMix {
+			// Select files at random by weight.
+			var names []string
+			var weights []float64
+			for _, filePath := range filelist {
+				if stat, err := os.Stat(filePath); err != nil {
+					logger.Out(logrus.WarnLevel, logrus.Fields{"filePath": filePath, "error": err}, "Cannot stat file.")
+					continue
+				} else if stat.IsDir() {
+					logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Skipping directory.")
+					continue
+				}
+
+				weight := 1.0
+				if args.Weights != nil {
+					if weight, err = emul.FileWeight(args.Weights, filePath); err != nil {
+						logger.Out(logrus.ErrorLevel, logrus.Fields{"filePath": filePath, "error": err}, "Invalid file weight.")
+						return
+					}
+				}
+				if weight == 0 {
+					logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Skipping file without weight.")
+					continue
+				}
+				names = append(names, filePath)
+				weights = append(weights, weight)
+			}
+
+			if args.Mix, err = emul.NewMix(names, weights, args.Seed); err != nil {
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "Cannot create request mix.")
+				return
+			}
+
+			for i = 1; args.Endless || i <= args.Repeat; i++ {
+				filePath = names[args.Mix.Next()]
+				if !enqueue(ctx, out, emul.Request{SesID: SessionID, ReqID: uint64(i), FilePath: filePath}) {
+					return
+				}
+				logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Enqueued request.")
+			}
 		} else {
 			var j int
-			for i = 1; i <= args.Repeat; {
+			for i = 1; args.Endless || i <= args.Repeat; {
+				if ctx.Err() != nil {
+					return
+				}
 				for _, filePath := range filelist {
 					if j == len(filelist) && i == 0 {
 						// No files, only directories.
 						i = args.Repeat
 						break
-					} else if i > args.Repeat {
+					} else if !args.Endless && i > args.Repeat {
 						// All requests sent.
 						break
 					}
 
-					if stat, err := os.Stat(filePath); err == nil {
+					if stat, statErr := os.Stat(filePath); statErr == nil {
 						if !stat.IsDir() {
 							logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Enqueue request.")
-							out <- emul.Request{SesID: SessionID, ReqID: uint64(i), FilePath: filePath}
+							if !enqueue(ctx, out, emul.Request{SesID: SessionID, ReqID: uint64(i), FilePath: filePath}) {
+								return
+							}
 							i++
 							logger.Out(logrus.DebugLevel, nil, "Enqueued request.")
 						} else {
 							logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Skipping directory.")
 						}
 					} else {
-						logger.Out(logrus.WarnLevel, logrus.Fields{"filePath": filePath, "error": err}, "Cannot stat file.")
+						logger.Out(logrus.WarnLevel, logrus.Fields{"filePath": filePath, "error": statErr}, "Cannot stat file.")
 					}
 					j++
 				}
 			}
 		}
 
+	case emul.ReplayReq:
+		// Schedule requests at the recorded offsets adjusted by the replay speed.
+		var paths []string
+		for _, entry := range args.Timeline {
+			paths = append(paths, entry.FilePath)
+		}
+		preload(filer, paths)
+
+		start := time.Now()
+		for i, entry := range args.Timeline {
+			req := entry.Request()
+			req.SesID, req.ReqID = SessionID, uint64(i+1)
+			req.Due = start.Add(time.Duration(float64(entry.Offset) / args.Speed))
+			if !enqueue(ctx, out, req) {
+				return
+			}
+			logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": entry.FilePath, "due": req.Due}, "Enqueued request.")
+		}
+
+	case emul.ManifestReq:
+		var paths []string
+		for _, req := range args.Manifest {
+			if req.Body == nil {
+				paths = append(paths, req.FilePath)
+			}
+		}
+		preload(filer, paths)
+
+		if args.MixMode == emul.WeightedMix {
+			// Select manifest requests at random by weight.
+			var names []string
+			var weights []float64
+			for i, req := range args.Manifest {
+				names = append(names, manifestName(i, req))
+				weights = append(weights, float64(req.Weight))
+			}
+
+			if args.Mix, err = emul.NewMix(names, weights, args.Seed); err != nil {
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "Cannot create request mix.")
+				return
+			}
+
+			for i = 1; args.Endless || i <= args.Repeat; i++ {
+				req := args.Manifest[args.Mix.Next()]
+				req.SesID = SessionID
+				req.ReqID = uint64(i)
+				if !enqueue(ctx, out, req) {
+					return
+				}
+				logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": req.FilePath, "endpoint": req.Endpoint, "tags": req.Tags}, "Enqueued request.")
+			}
+			break
+		}
+
+		// Cycle through manifest requests, each request is repeated weight times per cycle.
+		var cycle []emul.Request
+		for _, req := range args.Manifest {
+			for w := uint(0); w < req.Weight; w++ {
+				cycle = append(cycle, req)
+			}
+		}
+
+		for i = 1; (args.Endless || i <= args.Repeat) && len(cycle) > 0; i++ {
+			req := cycle[(i-1)%uint(len(cycle))]
+			req.SesID = SessionID
+			req.ReqID = uint64(i)
+			if !enqueue(ctx, out, req) {
+				return
+			}
+			logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": req.FilePath, "endpoint": req.Endpoint, "tags": req.Tags}, "Enqueued request.")
+		}
+
+	case emul.WatchReq:
+		// Enqueue spooled files as they arrive, move each file to sent or failed once sent.
+		var spool *sio.Spool
+		if spool, err = sio.NewSpool(args.SrcDirs, args.Wildcards, args.Excludes, time.Duration(args.SettleMs)*time.Millisecond); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "Cannot create spool.")
+			return
+		}
+
+		files := make(chan string)
+		go func() {
+			if err := spool.Run(ctx, files); err != nil {
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "Cannot watch spool.")
+			}
+		}()
+		logger.Out(logrus.InfoLevel, logrus.Fields{"dirs": args.SrcDirs, "pattern": args.Wildcards}, "Watching spool.")
+
+		var reqID uint64
+		for filePath := range files {
+			reqID++
+			path := filePath
+			done := func(err error) {
+				if err := spool.Finish(path, err); err != nil {
+					logger.Out(logrus.ErrorLevel, logrus.Fields{"filePath": path, "error": err}, "Cannot move spooled file.")
+				}
+			}
+			if !enqueue(ctx, out, emul.Request{SesID: SessionID, ReqID: reqID, FilePath: filePath, Done: done}) {
+				return
+			}
+			logger.Out(logrus.DebugLevel, logrus.Fields{"filePath": filePath}, "Enqueued request.")
+		}
+
+	case emul.InputReq:
+		// Split stdin or named pipe input into requests.
+		input := os.Stdin
+		if args.Input != "-" {
+			if input, err = os.Open(args.Input); err != nil {
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"input": args.Input, "error": err}, "Cannot open input.")
+				return
+			}
+			defer input.Close()
+		}
+
+		var splitter *sio.Splitter
+		if splitter, err = sio.NewSplitter(input, args.Split, args.Delim); err != nil {
+			logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "Cannot split input.")
+			return
+		}
+
+		var body []byte
+		for i = 1; ; i++ {
+			if body, err = splitter.Next(); err == io.EOF {
+				err = nil
+				break
+			} else if err != nil {
+				logger.Out(logrus.ErrorLevel, logrus.Fields{"input": args.Input, "error": err}, "Cannot read input.")
+				return
+			}
+			if !enqueue(ctx, out, emul.Request{SesID: SessionID, ReqID: uint64(i), Body: body}) {
+				return
+			}
+			logger.Out(logrus.DebugLevel, logrus.Fields{"input": args.Input, "length": len(body)}, "Enqueued request.")
+		}
+
 	default:
 		logger.Out(logrus.DebugLevel, logrus.Fields{"args.SendType": args.SendType}, "Invalide send type to prepare requests.")
 	}
 
 	return
 }
+
+// enqueue sends the request to the dispatcher, false if the context is done.
+func enqueue(ctx context.Context, out chan<- interface{}, req emul.Request) bool {
+	select {
+	case out <- req:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// preload loads the request files into the cache before sending.
+func preload(filer sio.Filer, paths []string) {
+	cache, ok := filer.(*sio.CacheFiler)
+	if !ok {
+		return
+	}
+
+	if err := cache.Preload(paths); err != nil {
+		logger.Out(logrus.WarnLevel, logrus.Fields{"error": err}, "Cannot preload requests.")
+	}
+	stats := cache.Stats()
+	logger.Out(logrus.InfoLevel, logrus.Fields{"Entries": stats.Entries, "Bytes": stats.Bytes, "Evictions": stats.Evictions}, "Preloaded requests.")
+}
+
+// manifestName names manifest request in the mix report.
+func manifestName(i int, req emul.Request) string {
+	if req.FilePath != "" {
+		return req.FilePath
+	}
+	return fmt.Sprintf("inline#%d", i+1)
+}
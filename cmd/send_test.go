@@ -16,6 +16,9 @@ package cmd_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/alexstov/sling/cmd"
 	"github.com/alexstov/sling/emul"
@@ -299,4 +302,58 @@ var _ = Describe("Send", func() {
 			})
 		})
 	})
+
+	Describe("Send command", func() {
+		Context("RunSend", func() {
+			var dir, file string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = ioutil.TempDir("", "send")
+				Expect(err).Should(BeNil())
+				file = filepath.Join(dir, "001.dat")
+				Expect(ioutil.WriteFile(file, []byte("ping"), 0644)).Should(Succeed())
+				testSendCmd.RunE = func(c *cobra.Command, args []string) error {
+					return cmd.RunSend(flagmapper)
+				}
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(dir)
+			})
+
+			It("fails on the invalid rate without sending.", func() {
+				defer GinkgoRecover()
+				testSendCmd.SetArgs([]string{"-f", file, "--rate", "bogus"})
+				err := testSendCmd.Execute()
+				Expect(err).ShouldNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("invalid rate count"))
+			})
+
+			It("fails on the invalid SLO before the search starts.", func() {
+				defer GinkgoRecover()
+				testSendCmd.SetArgs([]string{"-f", file, "--search", "step", "--searchMin", "10", "--searchMax", "100",
+					"--holdSec", "1", "--slo", "garbage"})
+				err := testSendCmd.Execute()
+				Expect(err).ShouldNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring(`invalid SLO "garbage"`))
+			})
+
+			It("fails on the combined request sources without sending.", func() {
+				defer GinkgoRecover()
+				testSendCmd.SetArgs([]string{"--input", file, "--manifest", filepath.Join(dir, "manifest.yml")})
+				err := testSendCmd.Execute()
+				Expect(err).ShouldNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("request sources input, manifest cannot be combined"))
+			})
+
+			It("fails on the expected status of TCP responses without sending.", func() {
+				defer GinkgoRecover()
+				testSendCmd.SetArgs([]string{"-f", file, "-c", "TCP", "--status", "200"})
+				err := testSendCmd.Execute()
+				Expect(err).ShouldNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("TCP responses have no status"))
+			})
+		})
+	})
 })
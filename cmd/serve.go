@@ -0,0 +1,108 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alexstov/sling/net"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ServeCmd command.
+var ServeCmd = &cobra.Command{
+	Use:   "serve [flags]",
+	Short: "Start the mock server",
+	Long: `
+	Start the mock TCP and HTTP server until interrupted and report the received request counts.
+	TCP requests end with the message terminator and get the response before the connection is closed,
+	the same framing the TCP client uses. Responses are echo, the file content, or the response file
+	of the first map rule matching the request.`,
+	Example: `
+	# Echo the requests on TCP port 8634 and HTTP port 8080
+	sling serve --tcp :8634 --http :8080
+
+	# Respond with the mapped response files after 10-50ms, fail 1% of the HTTP requests with 503
+	sling serve --tcp "" --respond map:/home/alexstov/sling/responses.yml --latency "uniform(10,50)" --errorRate 1% --errorStatus 503
+
+	# Reset 5% of the connections
+	sling serve --respond file:/home/alexstov/sling/ok.res --dropRate 0.05`,
+	Run: serveRun,
+}
+
+// Serve command flags
+var serveFlags Flagmapper
+
+func init() {
+	var err error
+
+	if serveFlags, err = NewCmdFlags(ServeCmd, CmdServe, sconf); err != nil {
+		logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot create serve flags.")
+	}
+
+	RootCmd.AddCommand(ServeCmd)
+}
+
+func serveRun(cmd *cobra.Command, args []string) {
+	var err error
+	var serveArgs net.ServeArgs
+
+	// Set explicit command flags to override config defaults.
+	RootFlags.SetExplicit()
+	serveFlags.SetExplicit()
+
+	if err = serveFlags.ResolveServeArgs(&serveArgs); err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Cannot resolve serve arguments.")
+		return
+	}
+
+	srv := net.NewMockServer(logger, serveArgs)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	tcpAddr, httpAddr, err := srv.Start()
+	if err != nil {
+		Con.OutLogAndConsole(logrus.FatalLevel, logrus.Fields{"error": err}, "Serve command execution failed.")
+		return
+	}
+	Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"TCP": tcpAddr, "HTTP": httpAddr, "Respond": serveArgs.Responder.Mode,
+		"Seed": serveArgs.Seed}, "Serving, interrupt to stop.")
+
+	<-sig
+	if err = srv.Stop(); err != nil {
+		logger.Out(logrus.WarnLevel, logrus.Fields{"err": err}, "Cannot stop the mock server cleanly.")
+	}
+
+	// Output the received request counts by protocol and map rule.
+	for _, proto := range []struct {
+		name   string
+		listen string
+		stat   *net.ServeStat
+	}{{"TCP", serveArgs.TCPListen, &srv.TCP}, {"HTTP", serveArgs.HTTPListen, &srv.HTTP}} {
+		if proto.listen == "" {
+			continue
+		}
+		stat := proto.stat
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Received": stat.Received, "Responded": stat.Responded,
+			"Errors": stat.Errors, "Drops": stat.Drops, "Unmatched": stat.Unmatched, "Bytes": stat.Bytes}, proto.name+" requests.")
+	}
+	for _, rule := range serveArgs.Responder.Rules {
+		Con.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"Count": rule.Count, "File": rule.File}, rule.Match)
+	}
+}
@@ -16,6 +16,8 @@ package conf
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -33,9 +35,15 @@ const (
 
 // Endpoint configuration
 type Endpoint struct {
+	Name    string
 	Address string
 	Port    uint
 	Type    ClientType
+	RateSec uint
+	RateMin uint
+	Rate    string
+	Burst   uint
+	MaxConn uint
 }
 
 // ParseClinetType parses string to ClinetType
@@ -48,3 +56,36 @@ func ParseClinetType(str string) ClientType {
 		return UnknownClient
 	}
 }
+
+// HostPort returns the endpoint host and port to dial, the HTTP port defaults by the scheme.
+func (ept Endpoint) HostPort() string {
+	if ept.Type == TCP {
+		return fmt.Sprintf("%s:%d", ept.Address, ept.Port)
+	}
+	u, err := url.Parse(ept.Address)
+	if err != nil || u.Host == "" {
+		return ept.Address
+	}
+	if u.Port() == "" && u.Scheme == "https" {
+		return u.Host + ":443"
+	} else if u.Port() == "" {
+		return u.Host + ":80"
+	}
+
+	return u.Host
+}
+
+// FindEndpoint finds the endpoint by name or zero-based index.
+func FindEndpoint(endpoints []Endpoint, name string) (endpoint Endpoint, index int, err error) {
+	for i, ept := range endpoints {
+		if ept.Name != "" && strings.EqualFold(ept.Name, name) {
+			return ept, i, nil
+		}
+	}
+
+	if index, err = strconv.Atoi(name); err == nil && index >= 0 && index < len(endpoints) {
+		return endpoints[index], index, nil
+	}
+
+	return endpoint, -1, fmt.Errorf("unknown endpoint %q", name)
+}
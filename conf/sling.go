@@ -20,14 +20,38 @@ type SlingConfig struct {
 	File          string
 	Dir           string
 	Wildcard      string
+	Exclude       string
+	Order         string
+	Manifest      string
 	SaveReqDir    string
 	SaveResDir    string
 	Repeat        uint
+	Seed          uint
+	CacheMb       uint
+	Warmup        string
 	SaveReq       bool
 	SaveRes       bool
 	EndpointIndex uint
 	Endpoints     []Endpoint
 	Throttle      Throttle
+	Replay        Replay
+	Mix           Mix
+	Watch         Watch
+	Input         Input
+	Search        Search
+	Adaptive      Adaptive
+	Schedule      Schedule
+	Concurrency   Concurrency
+	Retry         Retry
+	Breaker       Breaker
+	Failover      Failover
+	Shadow        Shadow
+	Golden        Golden
+	Assert        Assert
+	Record        Record
+	Serve         Serve
+	Proxy         Proxy
+	Control       Control
 	Log           Log
 	Console       Console
 }
@@ -19,8 +19,11 @@ type Throttle struct {
 	CxnNum  uint
 	CxnLim  bool
 	SleepMs uint
+	Think   string
 	RateSec uint
 	RateMin uint
+	Rate    string
+	Burst   uint
 	TmoSec  uint
 	TmoRdS  uint
 	TmoWrS  uint
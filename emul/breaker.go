@@ -0,0 +1,195 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BreakerAction circuit breaker action when tripped.
+type BreakerAction int
+
+const (
+	// AbortBreaker aborts the run.
+	AbortBreaker BreakerAction = iota
+	// PauseBreaker pauses sending and resumes with a fresh window.
+	PauseBreaker
+)
+
+func (a BreakerAction) String() string {
+	return [...]string{"abort", "pause"}[a]
+}
+
+// ParseBreakerAction parses string to BreakerAction.
+func ParseBreakerAction(str string) (action BreakerAction, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "abort", "":
+		return AbortBreaker, nil
+	case "pause":
+		return PauseBreaker, nil
+	}
+
+	return AbortBreaker, fmt.Errorf("unknown circuit breaker action %q", str)
+}
+
+// breakerBucket request outcomes within one second of the sliding window.
+type breakerBucket struct {
+	sec    int64
+	count  uint
+	failed uint
+}
+
+// Breaker run-level circuit breaker tripped by the error rate over the sliding window,
+// the consecutive failures or, fail-fast, the first failure.
+type Breaker struct {
+	Action      BreakerAction
+	Window      time.Duration
+	ErrRate     float64
+	MinRequests uint
+	Consecutive uint
+	FailFast    bool
+	Pause       time.Duration
+	Trips       int
+	Reason      string
+	OnTrip      func(reason string)
+	buckets     []breakerBucket
+	failures    uint
+	open        bool
+	until       time.Time
+	mu          sync.Mutex
+}
+
+// Enabled reports whether any trip condition is set.
+func (b *Breaker) Enabled() bool {
+	return b != nil && (b.FailFast || b.Consecutive > 0 || b.ErrRate > 0)
+}
+
+// Observe records the request outcome and trips the breaker when a condition is met.
+func (b *Breaker) Observe(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	if b.open || time.Now().Before(b.until) {
+		// Requests in flight when tripped are not counted.
+		b.mu.Unlock()
+		return
+	}
+
+	sec := time.Now().Unix()
+	if n := len(b.buckets); n == 0 || b.buckets[n-1].sec != sec {
+		b.buckets = append(b.buckets, breakerBucket{sec: sec})
+	}
+	last := &b.buckets[len(b.buckets)-1]
+	last.count++
+	if err != nil {
+		last.failed++
+		b.failures++
+	} else {
+		b.failures = 0
+	}
+
+	// Drop the buckets out of the window.
+	window := int64(b.Window / time.Second)
+	if window < 1 {
+		window = 1
+	}
+	i := 0
+	for i < len(b.buckets) && b.buckets[i].sec <= sec-window {
+		i++
+	}
+	b.buckets = b.buckets[i:]
+
+	var reason string
+	switch {
+	case err != nil && b.FailFast:
+		reason = fmt.Sprintf("fail-fast on the first failure: %v", err)
+	case b.Consecutive > 0 && b.failures >= b.Consecutive:
+		reason = fmt.Sprintf("%d consecutive failures", b.failures)
+	case b.ErrRate > 0:
+		var count, failed uint
+		for _, bucket := range b.buckets {
+			count, failed = count+bucket.count, failed+bucket.failed
+		}
+		if count > 0 && count >= b.MinRequests {
+			if rate := float64(failed) / float64(count); rate > b.ErrRate {
+				reason = fmt.Sprintf("error rate %.2f%% of %d requests over %v exceeds %.2f%%", rate*100, count,
+					time.Duration(window)*time.Second, b.ErrRate*100)
+			}
+		}
+	}
+	if reason == "" {
+		b.mu.Unlock()
+		return
+	}
+
+	// Trip, the paused breaker resumes with a fresh window.
+	b.Trips++
+	b.Reason = reason
+	b.buckets, b.failures = nil, 0
+	if b.Action == PauseBreaker {
+		b.until = time.Now().Add(b.Pause)
+	} else {
+		b.open = true
+	}
+	onTrip := b.OnTrip
+	b.mu.Unlock()
+
+	if onTrip != nil {
+		onTrip(reason)
+	}
+}
+
+// Wait waits while the breaker is paused, fails if the breaker aborted the run.
+func (b *Breaker) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	open, pause := b.open, time.Until(b.until)
+	b.mu.Unlock()
+	if open {
+		return fmt.Errorf("circuit breaker open, %s", b.Tripped())
+	}
+	if pause <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Tripped returns the last trip reason, empty if the breaker never tripped.
+func (b *Breaker) Tripped() string {
+	if b == nil {
+		return ""
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Reason
+}
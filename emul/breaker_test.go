@@ -0,0 +1,105 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("Breaker", func() {
+	failure := errors.New("connection refused")
+
+	Describe("ParseBreakerAction", func() {
+		It("abort or pause.", func() {
+			defer GinkgoRecover()
+			Expect(emul.ParseBreakerAction("")).To(Equal(emul.AbortBreaker))
+			Expect(emul.ParseBreakerAction("Pause")).To(Equal(emul.PauseBreaker))
+			_, err := emul.ParseBreakerAction("retry")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("Observe", func() {
+		It("fail-fast on the first failure.", func() {
+			defer GinkgoRecover()
+			var reasons []string
+			breaker := &emul.Breaker{FailFast: true, OnTrip: func(reason string) { reasons = append(reasons, reason) }}
+			breaker.Observe(nil)
+			Expect(breaker.Tripped()).To(BeEmpty())
+			breaker.Observe(failure)
+			breaker.Observe(failure)
+			Expect(reasons).To(Equal([]string{"fail-fast on the first failure: connection refused"}))
+			Expect(breaker.Wait(context.TODO())).ShouldNot(Succeed())
+		})
+		It("consecutive failures.", func() {
+			defer GinkgoRecover()
+			breaker := &emul.Breaker{Consecutive: 3}
+			breaker.Observe(failure)
+			breaker.Observe(failure)
+			breaker.Observe(nil)
+			breaker.Observe(failure)
+			breaker.Observe(failure)
+			Expect(breaker.Trips).To(BeZero())
+			breaker.Observe(failure)
+			Expect(breaker.Trips).To(Equal(1))
+			Expect(breaker.Tripped()).To(Equal("3 consecutive failures"))
+		})
+		It("error rate over the window.", func() {
+			defer GinkgoRecover()
+			breaker := &emul.Breaker{ErrRate: 0.5, Window: 10 * time.Second, MinRequests: 6}
+			for _, err := range []error{nil, failure, nil, failure, failure} {
+				breaker.Observe(err)
+			}
+			Expect(breaker.Trips).To(BeZero())
+			breaker.Observe(failure)
+			Expect(breaker.Trips).To(Equal(1))
+			Expect(breaker.Tripped()).To(HavePrefix("error rate 66.67% of 6 requests over 10s exceeds 50.00%"))
+		})
+		It("paused breaker resumes.", func() {
+			defer GinkgoRecover()
+			breaker := &emul.Breaker{Action: emul.PauseBreaker, Consecutive: 2, Pause: 50 * time.Millisecond}
+			breaker.Observe(failure)
+			breaker.Observe(failure)
+			Expect(breaker.Trips).To(Equal(1))
+
+			// Requests in flight while paused are not counted.
+			breaker.Observe(failure)
+			breaker.Observe(failure)
+			start := time.Now()
+			Expect(breaker.Wait(context.TODO())).Should(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", 40*time.Millisecond))
+			Expect(breaker.Trips).To(Equal(1))
+
+			breaker.Observe(failure)
+			breaker.Observe(failure)
+			Expect(breaker.Trips).To(Equal(2))
+		})
+		It("no trip condition.", func() {
+			defer GinkgoRecover()
+			var breaker *emul.Breaker
+			Expect(breaker.Enabled()).To(BeFalse())
+			breaker.Observe(failure)
+			Expect(breaker.Wait(context.TODO())).Should(Succeed())
+			Expect((&emul.Breaker{Window: time.Second}).Enabled()).To(BeFalse())
+		})
+	})
+})
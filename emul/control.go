@@ -0,0 +1,445 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexstov/sling/slog"
+	"github.com/alexstov/sling/throt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// ControlUnixPrefix control address prefix of the unix socket path.
+const ControlUnixPrefix = "unix:"
+
+// Control actions.
+const (
+	CtlState    = "state"
+	CtlRate     = "rate"
+	CtlWorkers  = "workers"
+	CtlPause    = "pause"
+	CtlResume   = "resume"
+	CtlSnapshot = "snapshot"
+	CtlStop     = "stop"
+)
+
+// CtlArgs ctl command arguments.
+type CtlArgs struct {
+	Control string
+	Timeout time.Duration
+}
+
+// ControlState running send state and the interim results.
+type ControlState struct {
+	Elapsed     string  `json:"elapsed"`
+	Paused      bool    `json:"paused"`
+	Stopping    bool    `json:"stopping"`
+	Workers     int     `json:"workers"`
+	Rate        string  `json:"rate"`
+	Concurrency float64 `json:"concurrency,omitempty"`
+	Count       int64   `json:"count"`
+	Errors      int64   `json:"errors"`
+	Failures    int64   `json:"failures"`
+	MeanMs      float64 `json:"meanMs"`
+	P50Ms       float64 `json:"p50Ms"`
+	P95Ms       float64 `json:"p95Ms"`
+	P99Ms       float64 `json:"p99Ms"`
+	MaxMs       int64   `json:"maxMs"`
+}
+
+// ConflictError control action conflicting with the run, e.g. the rate set by the rate schedule.
+type ConflictError struct {
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Reason
+}
+
+// Control changes the running send, the rate limit and the number of workers, pauses, resumes
+// and stops it. The workers above the worker count and all the workers while paused wait
+// before taking the next request.
+type Control struct {
+	OnSnapshot func(state ControlState)
+	em         *Emul
+	stop       context.CancelFunc
+	start      time.Time
+	mu         sync.Mutex
+	workers    int
+	started    int
+	paused     bool
+	stopping   bool
+	drained    bool
+	changed    chan struct{}
+	spawn      func(worker int, wg *sync.WaitGroup)
+	spawned    sync.WaitGroup
+}
+
+// NewControl creates the control of the emulator run, stop cancels the run.
+func NewControl(em *Emul, stop context.CancelFunc) *Control {
+	return &Control{em: em, stop: stop, start: time.Now(), changed: make(chan struct{})}
+}
+
+// Gate waits while the run is paused or the worker is above the worker count. The gate opens
+// when the requests are drained so the waiting workers exit.
+func (c *Control) Gate(ctx context.Context, worker int) error {
+	if c == nil {
+		return nil
+	}
+
+	for {
+		c.mu.Lock()
+		open := c.drained || (!c.paused && worker < c.workers)
+		changed := c.changed
+		c.mu.Unlock()
+		if open {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Hold waits while the run is paused, the requests waiting for the rate limit when the run is
+// paused are held before they are sent.
+func (c *Control) Hold(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	for {
+		c.mu.Lock()
+		paused := c.paused
+		changed := c.changed
+		c.mu.Unlock()
+		if !paused {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Drained opens the gate when no requests are left to send.
+func (c *Control) Drained() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drained = true
+	c.notify()
+}
+
+// attach sets the started workers and the function starting the added workers.
+func (c *Control) attach(workers int, spawn func(worker int, wg *sync.WaitGroup)) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.workers, c.started, c.spawn = workers, workers, spawn
+}
+
+// detach stops adding workers and waits for the added workers to complete.
+func (c *Control) detach() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.spawn = nil
+	c.mu.Unlock()
+	c.spawned.Wait()
+}
+
+// SetWorkers sets the number of workers sending the requests, the workers are started as needed.
+func (c *Control) SetWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("invalid worker count %d", workers)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.spawn == nil {
+		return fmt.Errorf("no workers are sending")
+	}
+	for ; c.started < workers; c.started++ {
+		c.spawned.Add(1)
+		c.spawn(c.started, &c.spawned)
+	}
+	c.workers = workers
+	c.notify()
+
+	return nil
+}
+
+// SetRate sets the rate limit by the rate expression of a single window, zero is not limited.
+// The rate set by the rate schedule or the capacity search is a *ConflictError.
+func (c *Control) SetRate(expr string) error {
+	if _, ok := c.em.Limiter.(*throt.ScheduleLimiter); ok {
+		return &ConflictError{Reason: "the rate is set by the rate schedule"}
+	}
+	if c.em.Search != nil {
+		return &ConflictError{Reason: "the rate is set by the capacity search"}
+	}
+
+	windows, err := throt.ParseRate(expr)
+	if err != nil {
+		return errors.Wrap(err, "throt.ParseRate")
+	}
+	if len(windows) > 1 {
+		return fmt.Errorf("rate %q has more than one window", expr)
+	}
+
+	limit := rate.Inf
+	if len(windows) == 1 {
+		limit = windows[0].Limit()
+	}
+	c.em.Limiter.SetLimit(limit)
+
+	return nil
+}
+
+// Pause pauses or resumes sending, the requests in flight are completed.
+func (c *Control) Pause(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = paused
+	c.notify()
+}
+
+// Stop stops the run gracefully, the requests in flight are completed.
+func (c *Control) Stop() {
+	c.mu.Lock()
+	c.stopping = true
+	c.mu.Unlock()
+	c.stop()
+}
+
+// Snapshot returns the state with the interim results and reports it.
+func (c *Control) Snapshot() ControlState {
+	state := c.State()
+	if c.OnSnapshot != nil {
+		c.OnSnapshot(state)
+	}
+	return state
+}
+
+// State returns the running send state and the interim results.
+func (c *Control) State() (state ControlState) {
+	c.mu.Lock()
+	state = ControlState{Elapsed: time.Since(c.start).Round(time.Millisecond).String(), Paused: c.paused,
+		Stopping: c.stopping, Workers: c.workers}
+	c.mu.Unlock()
+
+	state.Rate = "unlimited"
+	if limit := c.em.Limiter.Limit(); limit != rate.Inf {
+		state.Rate = fmt.Sprintf("%g/s", float64(limit))
+	}
+	if c.em.Concurrency != nil {
+		state.Concurrency = float64(c.em.Concurrency.Limit())
+	}
+	if histo := c.em.Histogram; histo != nil {
+		ps := histo.Percentiles([]float64{0.5, 0.95, 0.99})
+		state.Count, state.MeanMs, state.MaxMs = histo.Count(), histo.Mean(), histo.Max()
+		state.P50Ms, state.P95Ms, state.P99Ms = ps[0], ps[1], ps[2]
+	}
+	if c.em.Errors != nil {
+		state.Errors = c.em.Errors.Count()
+	}
+	if c.em.Failures != nil {
+		state.Failures = c.em.Failures.Count()
+	}
+
+	return state
+}
+
+// notify wakes the workers waiting at the gate.
+func (c *Control) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// ControlServer local HTTP control endpoint of the running send, listening on the TCP address
+// or the unix socket.
+type ControlServer struct {
+	Control  *Control
+	Listen   string
+	logger   slog.Logger
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+// NewControlServer creates new ControlServer instance.
+func NewControlServer(slog slog.Logger, control *Control, listen string) *ControlServer {
+	return &ControlServer{Control: control, Listen: listen, logger: slog}
+}
+
+// controlNetwork returns the network and the address of the control address.
+func controlNetwork(addr string) (network string, address string) {
+	if strings.HasPrefix(addr, ControlUnixPrefix) {
+		return "unix", strings.TrimPrefix(addr, ControlUnixPrefix)
+	}
+	return "tcp", addr
+}
+
+// Start starts listening, returns the listening address.
+func (s *ControlServer) Start() (addr net.Addr, err error) {
+	network, address := controlNetwork(s.Listen)
+	if network == "unix" {
+		// Remove the socket left by the run that did not stop cleanly.
+		if conn, errD := net.Dial(network, address); errD == nil {
+			conn.Close()
+			return nil, fmt.Errorf("control socket %s is in use", address)
+		}
+		os.Remove(address)
+	}
+	if s.listener, err = net.Listen(network, address); err != nil {
+		return nil, errors.Wrap(err, "net.Listen")
+	}
+
+	s.server = &http.Server{Handler: http.HandlerFunc(s.serveHTTP)}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.server.Serve(s.listener)
+	}()
+
+	s.logger.Out(logrus.InfoLevel, logrus.Fields{"addr": s.listener.Addr()}, "Control endpoint started.")
+	return s.listener.Addr(), nil
+}
+
+// Stop stops listening and waits for the control requests in progress.
+func (s *ControlServer) Stop() (err error) {
+	err = s.server.Shutdown(context.Background())
+	s.wg.Wait()
+	return
+}
+
+// serveHTTP applies the control action of the path and responds with the state.
+func (s *ControlServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(r.URL.Path, "/")
+	value := r.URL.Query().Get("value")
+	if action != CtlState && r.Method != http.MethodPost {
+		http.Error(w, "control actions require POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var err error
+	var state ControlState
+	switch action {
+	case CtlState:
+	case CtlRate:
+		err = s.Control.SetRate(value)
+	case CtlWorkers:
+		var workers int
+		if workers, err = strconv.Atoi(value); err == nil {
+			err = s.Control.SetWorkers(workers)
+		}
+	case CtlPause, CtlResume:
+		s.Control.Pause(action == CtlPause)
+	case CtlSnapshot:
+		state = s.Control.Snapshot()
+	case CtlStop:
+		s.Control.Stop()
+	default:
+		http.Error(w, fmt.Sprintf("unknown control action %q", action), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Out(logrus.WarnLevel, logrus.Fields{"action": action, "value": value, "error": err}, "Invalid control action.")
+		status := http.StatusBadRequest
+		if _, ok := err.(*ConflictError); ok {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if action != CtlState {
+		s.logger.Out(logrus.InfoLevel, logrus.Fields{"action": action, "value": value}, "Control action applied.")
+	}
+
+	if action != CtlSnapshot {
+		state = s.Control.State()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// SendControl sends the control action with the value to the control endpoint of the running
+// send and returns its state.
+func SendControl(args CtlArgs, action string, value string) (state ControlState, err error) {
+	network, address := controlNetwork(args.Control)
+	client := &http.Client{Timeout: args.Timeout, Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		}}}
+
+	method := http.MethodPost
+	if action == CtlState {
+		method = http.MethodGet
+	}
+	target := "http://sling/" + url.PathEscape(action)
+	if value != "" {
+		target += "?value=" + url.QueryEscape(value)
+	}
+	var req *http.Request
+	if req, err = http.NewRequest(method, target, nil); err != nil {
+		return state, errors.Wrap(err, "http.NewRequest")
+	}
+
+	var res *http.Response
+	if res, err = client.Do(req); err != nil {
+		return state, errors.Wrap(err, "client.Do")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return state, fmt.Errorf("control %s failed: %s", action, strings.TrimSpace(string(body)))
+	}
+	if err = json.NewDecoder(res.Body).Decode(&state); err != nil {
+		return state, errors.Wrap(err, "json.Decode")
+	}
+
+	return state, nil
+}
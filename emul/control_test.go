@@ -0,0 +1,208 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rcrowley/go-metrics"
+	"golang.org/x/time/rate"
+
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("Control", func() {
+	var (
+		t              testing.T
+		mockCtrl       *gomock.Controller
+		mockLogger     *mock.MockLogger
+		mockDispatcher *mock.MockDispatcher
+		limiter        throt.Limiter
+		testEmul       *emul.Emul
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(&t)
+		mockLogger = mock.NewMockLogger(mockCtrl)
+		mockLogger.EXPECT().Out(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		mockDispatcher = mock.NewMockDispatcher(mockCtrl)
+		limiter, _ = throt.NewMultiLimiter(&throt.MultiLimitArgs{RateSec: 10, CxnNum: 1})
+		testEmul = &emul.Emul{Dispatcher: mockDispatcher, Limiter: limiter, Logger: mockLogger,
+			Histogram: metrics.NewHistogram(metrics.NewUniformSample(100)), Errors: metrics.NewCounter(), Failures: metrics.NewCounter()}
+	})
+
+	It("pauses, resumes and sets the rate.", func() {
+		defer GinkgoRecover()
+		control := emul.NewControl(testEmul, func() {})
+		Expect(control.Hold(context.Background())).Should(Succeed())
+
+		control.Pause(true)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(control.Hold(ctx)).Should(Equal(context.DeadlineExceeded))
+		Expect(control.State().Paused).To(BeTrue())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			control.Pause(false)
+		}()
+		Expect(control.Hold(context.Background())).Should(Succeed())
+
+		Expect(control.SetRate("50/s")).Should(Succeed())
+		Expect(limiter.Limit()).To(Equal(rate.Limit(50)))
+		Expect(control.State().Rate).To(Equal("50/s"))
+		Expect(control.SetRate("0")).Should(Succeed())
+		Expect(control.State().Rate).To(Equal("unlimited"))
+		Expect(control.SetRate("1/s,5/m")).ShouldNot(Succeed())
+		Expect(control.SetRate("fast")).ShouldNot(Succeed())
+	})
+	It("sets the rate of the adaptive limiter and not of the schedule or search.", func() {
+		defer GinkgoRecover()
+		adaptive := throt.NewAdaptiveLimiter(limiter, throt.AdaptiveArgs{Decrease: 0.5, Step: 1, MinRate: 1, Statuses: []int{429}})
+		testEmul.Limiter = adaptive
+		control := emul.NewControl(testEmul, func() {})
+		Expect(control.SetRate("50/s")).Should(Succeed())
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(50)))
+		adaptive.Observe(429, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(25)))
+
+		schedule, err := throt.ParseRateSchedule(strings.NewReader("0,5\n10,5\n"), 1)
+		Expect(err).Should(BeNil())
+		testEmul.Limiter = throt.NewScheduleLimiter(limiter, schedule, time.Second)
+		err = control.SetRate("50/s")
+		Expect(err).To(BeAssignableToTypeOf(&emul.ConflictError{}))
+		Expect(err.Error()).To(ContainSubstring("rate schedule"))
+		Expect(limiter.Limit()).To(Equal(rate.Limit(25)))
+
+		server := emul.NewControlServer(mockLogger, control, "127.0.0.1:0")
+		addr, err := server.Start()
+		Expect(err).Should(BeNil())
+		defer server.Stop()
+		res, err := http.Post("http://"+addr.String()+"/rate?value=50/s", "text/plain", nil)
+		Expect(err).Should(BeNil())
+		res.Body.Close()
+		Expect(res.StatusCode).To(Equal(http.StatusConflict))
+
+		testEmul.Limiter = limiter
+		testEmul.Search = &emul.Search{}
+		Expect(control.SetRate("50/s")).To(BeAssignableToTypeOf(&emul.ConflictError{}))
+	})
+	It("stops the run.", func() {
+		defer GinkgoRecover()
+		ctx, cancel := context.WithCancel(context.Background())
+		control := emul.NewControl(testEmul, cancel)
+		Expect(control.SetWorkers(2)).ShouldNot(Succeed())
+		Expect(control.SetWorkers(0)).ShouldNot(Succeed())
+
+		control.Stop()
+		Expect(ctx.Err()).Should(Equal(context.Canceled))
+		Expect(control.State().Stopping).To(BeTrue())
+		Expect(control.Gate(ctx, 0)).Should(Equal(context.Canceled))
+	})
+	It("pauses MultiSend and adds workers.", func() {
+		defer mockCtrl.Finish()
+		defer GinkgoRecover()
+		var sent int32
+		mockDispatcher.EXPECT().SendRequest(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req emul.Request, args *emul.SendArgs) error {
+				atomic.AddInt32(&sent, 1)
+				return nil
+			}).Times(5)
+
+		sendArgs := emul.SendArgs{SendType: emul.RepeatReq, Repeat: 5, CxnNum: 1, CxnLim: true}
+		in := make(chan interface{}, sendArgs.Repeat)
+		for i := uint(0); i < sendArgs.Repeat; i++ {
+			in <- emul.Request{FilePath: "myfile.dat", ReqID: uint64(i)}
+		}
+		close(in)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		control := emul.NewControl(testEmul, cancel)
+		testEmul.Control = control
+		control.Pause(true)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go testEmul.MultiSend(ctx, in, &sendArgs, &wg)
+		Eventually(func() error { return control.SetWorkers(3) }).Should(Succeed())
+		Consistently(func() int32 { return atomic.LoadInt32(&sent) }, 50*time.Millisecond).Should(BeZero())
+		Expect(control.State().Workers).To(Equal(3))
+
+		control.Pause(false)
+		wg.Wait()
+		Expect(atomic.LoadInt32(&sent)).To(Equal(int32(5)))
+	})
+	It("serves the control actions.", func() {
+		defer GinkgoRecover()
+		dir, err := ioutil.TempDir("", "control")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+		addr := emul.ControlUnixPrefix + filepath.Join(dir, "sling.sock")
+
+		var snapshots int32
+		stopped := make(chan struct{})
+		control := emul.NewControl(testEmul, func() { close(stopped) })
+		control.OnSnapshot = func(state emul.ControlState) {
+			atomic.AddInt32(&snapshots, 1)
+		}
+		server := emul.NewControlServer(mockLogger, control, addr)
+		_, err = server.Start()
+		Expect(err).Should(BeNil())
+		_, err = emul.NewControlServer(mockLogger, control, addr).Start()
+		Expect(err).ShouldNot(BeNil())
+
+		args := emul.CtlArgs{Control: addr, Timeout: time.Second}
+		state, err := emul.SendControl(args, emul.CtlRate, "20/s")
+		Expect(err).Should(BeNil())
+		Expect(state.Rate).To(Equal("20/s"))
+		state, err = emul.SendControl(args, emul.CtlPause, "")
+		Expect(err).Should(BeNil())
+		Expect(state.Paused).To(BeTrue())
+		_, err = emul.SendControl(args, emul.CtlSnapshot, "")
+		Expect(err).Should(BeNil())
+		Expect(atomic.LoadInt32(&snapshots)).To(Equal(int32(1)))
+		_, err = emul.SendControl(args, emul.CtlWorkers, "many")
+		Expect(err).ShouldNot(BeNil())
+		_, err = emul.SendControl(args, "faster", "")
+		Expect(err).ShouldNot(BeNil())
+		state, err = emul.SendControl(args, emul.CtlStop, "")
+		Expect(err).Should(BeNil())
+		Expect(state.Stopping).To(BeTrue())
+		Eventually(stopped).Should(BeClosed())
+		Expect(server.Stop()).Should(Succeed())
+
+		tcp := emul.NewControlServer(mockLogger, control, "127.0.0.1:0")
+		tcpAddr, err := tcp.Start()
+		Expect(err).Should(BeNil())
+		defer tcp.Stop()
+		state, err = emul.SendControl(emul.CtlArgs{Control: tcpAddr.String(), Timeout: time.Second}, emul.CtlState, "")
+		Expect(err).Should(BeNil())
+		Expect(state.Paused).To(BeTrue())
+	})
+})
@@ -24,6 +24,7 @@ import (
 // Dispatcher sends requests to the endpoint.
 type Dispatcher interface {
 	SendReq(ctx context.Context, filePath string, args *SendArgs) (err error)
+	SendRequest(ctx context.Context, req Request, args *SendArgs) (err error)
 	MultiSend(ctx context.Context, req <-chan interface{}, args *SendArgs, wgSend *sync.WaitGroup) (err error)
 	GetHisto() (histo metrics.Histogram, err error)
 }
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"sync"
@@ -32,24 +33,46 @@ import (
 	"github.com/pkg/errors" //"errors"
 	"github.com/rcrowley/go-metrics"
 	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Emul - Emulatator interface implementation.
 type Emul struct {
-	Dispatcher Dispatcher
-	Client     net.Client
-	Filer      sio.Filer
-	Consoler   cui.Consoler
-	Logger     slog.Logger
-	Limiter    throt.Limiter
-	Histogram  metrics.Histogram
+	Dispatcher  Dispatcher
+	Client      net.Client
+	Clients     map[conf.ClientType]net.Client
+	Filer       sio.Filer
+	Consoler    cui.Consoler
+	Logger      slog.Logger
+	Limiter     throt.Limiter
+	Feedback    throt.Feedback
+	Histogram   metrics.Histogram
+	Drift       metrics.Histogram
+	Registry    metrics.Registry
+	Warmup      *Warmup
+	WarmupHist  metrics.Histogram
+	Search      *Search
+	Shadow      *Shadow
+	Golden      *Golden
+	Retry       *Retry
+	Breaker     *Breaker
+	Failover    *Failover
+	Limits      EndpointLimits
+	Concurrency *throt.ConcurrencyLimiter
+	Control     *Control
+	WaitHist    metrics.Histogram
+	Errors      metrics.Counter
+	Failures    metrics.Counter
+	saving      sync.WaitGroup
 }
 
 // SendArgs send command arguments.
 type SendArgs struct {
 	Data            string
-	SrcDir          string
-	Wildcard        string
+	SrcDirs         []string
+	Wildcards       []string
+	Excludes        []string
+	Order           sio.Order
 	SaveReqDir      string
 	SaveReqFilepath string
 	SaveResDir      string
@@ -61,6 +84,8 @@ type SendArgs struct {
 	Port            uint
 	RateSec         uint
 	RateMin         uint
+	Rate            []throt.RateWindow
+	Burst           uint
 	SendType        SendType
 	TmoSec          uint
 	TmoRdS          uint
@@ -71,6 +96,36 @@ type SendArgs struct {
 	SaveReq         bool
 	SaveRes         bool
 	ReqID           uint64
+	Timeline        []TimelineEntry
+	Speed           float64
+	DriftMs         uint
+	Manifest        []Request
+	Endpoints       []conf.Endpoint
+	MixMode         MixMode
+	Weights         yaml.MapSlice
+	Seed            int64
+	Mix             *Mix
+	SettleMs        uint
+	Input           string
+	Split           sio.SplitMode
+	Delim           []byte
+	CacheMb         uint
+	Think           *throt.Think
+	WarmupDur       time.Duration
+	WarmupCount     uint64
+	Endless         bool
+	Search          *Search
+	Shadow          *Shadow
+	Golden          *Golden
+	Expect          *Expect
+	Adaptive        *throt.AdaptiveArgs
+	Schedule        *throt.RateSchedule
+	Concurrency     *throt.ConcurrencyArgs
+	Control         string
+	RateInterval    time.Duration
+	Retry           *Retry
+	Breaker         *Breaker
+	Failover        *Failover
 }
 
 // NewEmul creates new emul instance.
@@ -88,151 +143,417 @@ func (em *Emul) MultiSend(ctx context.Context, in <-chan interface{}, args *Send
 	var wg sync.WaitGroup
 	res := make(chan interface{}, args.Repeat)
 
+	workers := int(args.Repeat)
 	if args.CxnLim {
-		wg.Add(int(args.CxnNum))
-		for w := 0; w < int(args.CxnNum); w++ {
-			go em.dispatch(ctx, args, in, res, &wg)
-		}
-	} else {
-		wg.Add(int(args.Repeat))
-		for w := 0; w < int(args.Repeat); w++ {
-			go em.dispatch(ctx, args, in, res, &wg)
-		}
+		workers = int(args.CxnNum)
+	}
+	// The control starts more workers while the requests are sent.
+	em.Control.attach(workers, func(worker int, wg *sync.WaitGroup) {
+		go em.dispatch(ctx, args, worker, in, res, wg)
+	})
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go em.dispatch(ctx, args, w, in, res, &wg)
 	}
 	wg.Wait()
+	em.Control.detach()
 	return
 }
 
 // dispatch displatches input requests routed via inbound channel and sends responses
-// through outbound channel. Send rate is adjusted using SleepMs.
-func (em *Emul) dispatch(ctx context.Context, args *SendArgs, in <-chan interface{}, res chan<- interface{}, wg *sync.WaitGroup) (err error) {
+// through outbound channel. Send rate is adjusted using SleepMs or the think time.
+func (em *Emul) dispatch(ctx context.Context, args *SendArgs, worker int, in <-chan interface{}, res chan<- interface{}, wg *sync.WaitGroup) (err error) {
 	defer wg.Done()
 
-	for r := range in {
-		args.ReqID = r.(Request).ReqID
-		if err = em.Dispatcher.SendReq(ctx, r.(Request).FilePath, args); err != nil {
-			// Log an error. Do not return, attempt to send all requests.
-			err = errors.Wrap(err, "SendReq")
-			em.Logger.Out(logrus.ErrorLevel, logrus.Fields{"filePath": "filePath", "error": err}, "Failed to send the request.")
+	// Each worker draws the think time from its own generator and starts at a random offset
+	// within the think time or the fixed delay so the workers do not send in lockstep.
+	rng := rand.New(rand.NewSource(args.Seed + int64(worker)))
+	time.Sleep(throt.StartOffset(args.Think, time.Duration(args.SleepMs)*time.Millisecond, rng))
+
+	for {
+		// Wait while the run is paused or the worker is above the control worker count.
+		if em.Control.Gate(ctx, worker) != nil {
+			return
+		}
+		r, ok := <-in
+		if !ok {
+			em.Control.Drained()
+			return
+		}
+
+		// Stop sending when the run is cancelled, e.g. aborted by the circuit breaker.
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Wait for the scheduled time of the replayed request.
+		em.schedule(r.(Request), args)
+
+		if err = em.Dispatcher.SendRequest(ctx, r.(Request), args); err != nil {
+			// Log an error, the assertion failures are reported by SendRequest. Do not return, attempt to send all requests.
+			if _, ok := err.(*AssertError); !ok {
+				err = errors.Wrap(err, "SendRequest")
+				em.Logger.Out(logrus.ErrorLevel, logrus.Fields{"filePath": r.(Request).FilePath, "error": err}, "Failed to send the request.")
+			}
+		}
+
+		// Notify the request source the request is sent.
+		if done := r.(Request).Done; done != nil {
+			done(err)
+		}
+		if args.Think != nil {
+			time.Sleep(args.Think.Next(rng))
+		} else {
+			time.Sleep(time.Duration(args.SleepMs) * time.Millisecond)
 		}
-		time.Sleep(time.Duration(args.SleepMs) * time.Millisecond)
 	}
-	return
+}
+
+// schedule waits until the request is due and captures the schedule drift.
+func (em *Emul) schedule(req Request, args *SendArgs) {
+	if req.Due.IsZero() {
+		return
+	}
+
+	wait := time.Until(req.Due)
+	if wait > 0 {
+		time.Sleep(wait)
+		wait = 0
+	}
+
+	// Request is sent late when sling falls behind the recorded schedule.
+	drift := -wait
+	if em.Drift != nil {
+		em.Drift.Update(drift.Milliseconds())
+	}
+	if drift > time.Duration(args.DriftMs)*time.Millisecond {
+		em.Logger.Out(logrus.WarnLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "DriftMs": drift.Milliseconds()}, "Replay is behind schedule.")
+	}
 }
 
 // SendReq sends a single request to destination.
 func (em *Emul) SendReq(ctx context.Context, filePath string, args *SendArgs) (err error) {
-	var contentType sio.ContentType
+	return em.SendRequest(ctx, Request{ReqID: args.ReqID, FilePath: filePath}, args)
+}
+
+// SendRequest sends the request to destination honouring the request metadata.
+func (em *Emul) SendRequest(ctx context.Context, req Request, args *SendArgs) (err error) {
 	var buf []byte
+	if buf, err = em.readRequest(req); err != nil {
+		return
+	}
+
+	// Delay the request.
+	if req.DelayMs > 0 {
+		time.Sleep(time.Duration(req.DelayMs) * time.Millisecond)
+	}
+
+	var writeArgs net.WriteArgs
+	if writeArgs, err = em.newWriteArgs(req, args); err != nil {
+		return
+	}
+
+	// The client saves the response asynchronously, the save is released when there is no response to save.
+	var sendErr error
+	saved := em.saveResponse(&writeArgs, args, req.FilePath)
+	defer func() {
+		if sendErr != nil {
+			saved()
+		}
+	}()
+	em.saveRequest(&writeArgs, args, req, buf)
+
+	// The request aborted while waiting to be sent is not recorded.
+	var res sendResult
+	if res, sendErr = em.sendWithRetry(ctx, req, buf, &writeArgs); res.aborted {
+		return sendErr
+	}
+
+	return em.record(req, args, buf, &writeArgs, res, sendErr)
+}
+
+// readRequest reads the inline body or the request file, the archives are decompressed.
+func (em *Emul) readRequest(req Request) (buf []byte, err error) {
+	if req.Body != nil {
+		return req.Body, nil
+	}
 
 	// Determine request content type.
-	contentType, err = em.Filer.DetermineContentType(filePath)
-	if err != nil {
+	var contentType sio.ContentType
+	if contentType, err = em.Filer.DetermineContentType(req.FilePath); err != nil {
 		em.Logger.Out(logrus.ErrorLevel, nil, "Unknown file content type", err)
 		return
 	}
 
 	// Read request.
 	switch contentType {
-	case sio.GzipType:
-		buf, err = em.Filer.ReadArchive(filePath)
-		if err != nil {
+	case sio.GzipType, sio.ZipType:
+		if buf, err = em.Filer.ReadArchive(req.FilePath); err != nil {
 			err = errors.Wrap(err, "readArchive(filepath)")
-			return
-		}
-	case sio.ZipType:
-		buf, err = em.Filer.ReadArchive(filePath)
-		if err != nil {
-			err = errors.Wrap(err, "readArchive(filepath)")
-			return
 		}
 	case sio.UnknownType:
-		buf, err = em.Filer.ReadFile(filePath)
-		if err != nil {
+		if buf, err = em.Filer.ReadFile(req.FilePath); err != nil {
 			err = errors.Wrap(err, "io.ReadFile(filepath)")
-			return
 		}
 	}
 
-	// Limit the rate.
-	if err = em.Limiter.Wait(ctx); err != nil {
-		err = errors.Wrap(err, "em.Limiter.Wait(ctx)")
-		return
-	}
+	return
+}
 
-	// Send request and capture execution time.
-	writeArgs := net.WriteArgs{IPAddress: args.Address,
+// newWriteArgs creates the client write arguments of the request, the request endpoint replaces the active one.
+func (em *Emul) newWriteArgs(req Request, args *SendArgs) (writeArgs net.WriteArgs, err error) {
+	writeArgs = net.WriteArgs{IPAddress: args.Address,
 		Port:            args.Port,
 		TmoSec:          args.TmoSec,
 		TmoRdS:          args.TmoRdS,
 		TmoWrS:          args.TmoWrS,
 		TmoCxn:          args.TmoCxn,
-		ReqID:           args.ReqID,
-		RequestFilepath: filePath,
+		ReqID:           req.ReqID,
+		RequestFilepath: req.FilePath,
 		SaveReq:         args.SaveReq,
 		SaveReqDir:      args.SaveReqDir,
 		SaveRes:         args.SaveRes,
 		SaveResDir:      args.SaveResDir,
-		CltType:         args.CltType}
-
-	// Save response callback, defined here to reuse by any client after receiving response.
-	if args.SaveRes {
-		writeArgs.SaveResCallback = func(filepath string, buff *bytes.Buffer) (err error) {
-			var wrLen int
-			var f *os.File
-			f, err = em.Filer.CreateFile(filepath)
-			if err != nil {
-				err = errors.Wrap(err, "os.Create(filepath)")
-				return
-			}
-			defer em.Filer.CloseFile(f)
+		CltType:         args.CltType,
+		Method:          req.Method,
+		Path:            req.Path,
+		Headers:         req.Headers,
+		Response:        &net.Response{},
+		Feedback:        em.Feedback}
 
-			if wrLen, err = em.Filer.WriteFile(f, buff.Bytes()); err != nil {
-				err = errors.Wrap(err, "f.Write(buff)")
-				return
-			}
-			em.Logger.Out(logrus.InfoLevel, logrus.Fields{"filepath": filepath, "wrLen": wrLen}, "Saved request to a file.")
+	// Send to the request endpoint instead of the active one.
+	if req.Endpoint != "" {
+		var ept conf.Endpoint
+		if ept, _, err = conf.FindEndpoint(args.Endpoints, req.Endpoint); err != nil {
+			err = errors.Wrap(err, "conf.FindEndpoint")
 			return
 		}
+		writeArgs.IPAddress = ept.Address
+		writeArgs.Port = ept.Port
+		writeArgs.CltType = ept.Type
+	}
+
+	return
+}
+
+// saveResponse sets the callback saving the response, tracked to wait for all responses saved.
+// The returned saved releases the tracked save once, by the callback or when there is no response.
+func (em *Emul) saveResponse(writeArgs *net.WriteArgs, args *SendArgs, filePath string) (saved func()) {
+	if !args.SaveRes {
+		return func() {}
 	}
 
-	// Save the request.
-	if args.SaveReq {
-		if args.SaveReqFilepath, err = em.Filer.BuildFilePath(args.SaveReqDir, fmt.Sprintf("%03d", args.ReqID)+"."+filepath.Base(filePath)+".req"); err != nil {
-			em.Logger.Out(logrus.ErrorLevel, logrus.Fields{"filepath": args.SaveReqFilepath}, "Cannot save the request.")
-			err = errors.Wrap(err, "os.Stat")
+	var once sync.Once
+	saved = func() { once.Do(em.saving.Done) }
+	em.saving.Add(1)
+
+	writeArgs.SaveResCallback = func(resPath string, buff *bytes.Buffer) (err error) {
+		defer saved()
+
+		var wrLen int
+		var f *os.File
+		f, err = em.Filer.CreateFile(resPath)
+		if err != nil {
+			err = errors.Wrap(err, "os.Create(filepath)")
+			return
 		}
+		defer em.Filer.CloseFile(f)
 
-		go func(filepath string, buff []byte) (err error) {
-			var wrLen int
-			var f *os.File
-			f, err = em.Filer.CreateFile(filepath)
-			if err != nil {
-				err = errors.Wrap(err, "os.Create(filepath)")
-				return
-			}
-			defer em.Filer.CloseFile(f)
+		if wrLen, err = em.Filer.WriteFile(f, buff.Bytes()); err != nil {
+			err = errors.Wrap(err, "f.Write(buff)")
+			return
+		}
+		em.Logger.Out(logrus.InfoLevel, logrus.Fields{"filepath": resPath, "wrLen": wrLen}, "Saved request to a file.")
 
-			if wrLen, err = em.Filer.WriteFile(f, buff); err != nil {
-				err = errors.Wrap(err, "f.Write(buff)")
-				return
+		// Index the saved response by the request file to approve the goldens.
+		if filePath != "" {
+			if err = appendResIndex(args.SaveResDir, filepath.Base(resPath), filePath, GoldenKey(args.SrcDirs, filePath)); err != nil {
+				em.Logger.Out(logrus.WarnLevel, logrus.Fields{"error": err}, "Cannot index the saved response.")
 			}
-			em.Logger.Out(logrus.InfoLevel, logrus.Fields{"filepath": filepath, "wrLen": wrLen}, "Saved request to a file.")
+		}
+		return
+	}
+
+	return saved
+}
+
+// saveRequest saves the request asynchronously.
+func (em *Emul) saveRequest(writeArgs *net.WriteArgs, args *SendArgs, req Request, buf []byte) {
+	if !args.SaveReq {
+		return
+	}
+
+	var err error
+	name := filepath.Base(req.FilePath)
+	if req.FilePath == "" {
+		name = "inline"
+	}
+	if writeArgs.SaveReqFilepath, err = em.Filer.BuildFilePath(args.SaveReqDir, fmt.Sprintf("%03d", req.ReqID)+"."+name+".req"); err != nil {
+		em.Logger.Out(logrus.ErrorLevel, logrus.Fields{"filepath": writeArgs.SaveReqFilepath, "error": err}, "Cannot save the request.")
+	}
+
+	go func(filepath string, buff []byte) (err error) {
+		var wrLen int
+		var f *os.File
+		f, err = em.Filer.CreateFile(filepath)
+		if err != nil {
+			err = errors.Wrap(err, "os.Create(filepath)")
 			return
-		}(args.SaveReqFilepath, buf)
+		}
+		defer em.Filer.CloseFile(f)
+
+		if wrLen, err = em.Filer.WriteFile(f, buff); err != nil {
+			err = errors.Wrap(err, "f.Write(buff)")
+			return
+		}
+		em.Logger.Out(logrus.InfoLevel, logrus.Fields{"filepath": filepath, "wrLen": wrLen}, "Saved request to a file.")
+		return
+	}(writeArgs.SaveReqFilepath, buf)
+}
+
+// sendResult is the outcome of sending the request with retries.
+type sendResult struct {
+	warm    bool
+	shadow  <-chan shadowResult
+	latency time.Duration
+	aborted bool
+}
+
+// sendWithRetry sends the request, warm-up requests are reported separately. The failed request is
+// retried by the retry policy, every attempt counts against the limits. The error is the last attempt
+// error, the request is aborted when it fails waiting to be sent.
+func (em *Emul) sendWithRetry(ctx context.Context, req Request, buf []byte, writeArgs *net.WriteArgs) (res sendResult, err error) {
+	attempt := uint(1)
+	for ; ; attempt++ {
+		var release func()
+		var active int
+		if release, active, err = em.acquire(ctx, req, writeArgs); err != nil {
+			res.aborted = true
+			return
+		}
+
+		if attempt == 1 {
+			// The request is classified when it is sent, after the limiter and concurrency waits.
+			res.warm = em.Warmup.Next()
+			res.shadow = em.sendShadow(buf, *writeArgs)
+		}
+		writeArgs.Response = &net.Response{}
+		start := time.Now()
+		err = em.client(writeArgs.CltType).Write(buf, writeArgs)
+		res.latency = time.Since(start)
+		em.Concurrency.Done(writeArgs.Response.Status, res.latency, err)
+		release()
+		if active >= 0 {
+			em.Failover.Observe(active, err)
+		}
+		if !em.backoff(ctx, req, attempt, writeArgs.Response, err) {
+			break
+		}
 	}
+	em.Retry.Record(attempt, err == nil && !em.Retry.Retryable(writeArgs.Response, nil))
 
-	// Send the request.
-	start := time.Now()
-	err = em.Client.Write(buf, &writeArgs)
-	elapsed := int64(time.Since(start)) / int64(time.Millisecond)
-	if err != nil {
-		em.Consoler.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"FilePath": filePath, "error": err}, "Failed to send the request.")
-	} else {
-		em.Consoler.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"FilePath": filePath, "Length": len(buf)}, "Request sent successfully.")
+	return
+}
+
+// acquire waits while the circuit breaker is paused, for the global and then the endpoint limits and
+// the adaptive concurrency, the time spent waiting is captured. The failover sets the active endpoint,
+// the index is -1 without the failover. The release frees the endpoint limit after sending.
+func (em *Emul) acquire(ctx context.Context, req Request, writeArgs *net.WriteArgs) (release func(), active int, err error) {
+	active = -1
+	if err = em.Breaker.Wait(ctx); err != nil {
+		return nil, active, errors.Wrap(err, "em.Breaker.Wait(ctx)")
+	}
+
+	// Send to the active endpoint, the failover switches it on connection errors.
+	if em.Failover != nil && req.Endpoint == "" {
+		var ept conf.Endpoint
+		ept, active = em.Failover.Active()
+		writeArgs.IPAddress, writeArgs.Port, writeArgs.CltType = ept.Address, ept.Port, ept.Type
+	}
+
+	queued := time.Now()
+	if err = em.Limiter.Wait(ctx); err != nil {
+		return nil, active, errors.Wrap(err, "em.Limiter.Wait(ctx)")
+	}
+	// Hold the request while the run is paused, the paused time is not the limiter wait.
+	held := time.Now()
+	if err = em.Control.Hold(ctx); err != nil {
+		return nil, active, errors.Wrap(err, "em.Control.Hold(ctx)")
+	}
+	queued = queued.Add(time.Since(held))
+	limit := em.Limits.Get(writeArgs.IPAddress, writeArgs.Port)
+	if release, err = limit.Acquire(ctx); err != nil {
+		return nil, active, errors.Wrap(err, "limit.Acquire(ctx)")
+	}
+	if err = em.Concurrency.Wait(ctx); err != nil {
+		release()
+		return nil, active, errors.Wrap(err, "em.Concurrency.Wait(ctx)")
+	}
+	wait := int64(time.Since(queued) / time.Millisecond)
+	limit.Observe(wait)
+	if em.WaitHist != nil {
+		em.WaitHist.Update(wait)
+	}
+
+	return release, active, nil
+}
+
+// backoff waits before retrying the failed request, at least the endpoint Retry-After. It returns
+// false when the request is not retried or the run is stopped while waiting.
+func (em *Emul) backoff(ctx context.Context, req Request, attempt uint, res *net.Response, err error) bool {
+	if !em.Retry.Retryable(res, err) {
+		return false
+	}
+	delay, ok := em.Retry.Next(attempt)
+	if !ok {
+		return false
 	}
 
+	if after := net.ParseRetryAfter(res.Header.Get("Retry-After"), time.Now()); after > delay {
+		delay = after
+	}
+	em.Logger.Out(logrus.WarnLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "Attempt": attempt,
+		"Status": res.Status, "Delay": delay, "error": err}, "Retrying the request.")
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	// Report the last attempt when stopped.
+	return ctx.Err() == nil
+}
+
+// record compares and verifies the response, reports the request and updates the run stats. It returns
+// the send error or the failed response assertion.
+func (em *Emul) record(req Request, args *SendArgs, buf []byte, writeArgs *net.WriteArgs, res sendResult, err error) error {
+	elapsed := int64(res.latency) / int64(time.Millisecond)
+	em.compareShadow(req, writeArgs.Response, err, res.shadow, res.warm)
+	em.verifyGolden(req, args, writeArgs.Response, err)
+	if err == nil {
+		// Verify the response meets the global and request expectations.
+		err = args.Expect.Merge(req.Expect).ForClient(writeArgs.CltType).Check(writeArgs.Response, res.latency)
+	}
+	switch err.(type) {
+	case nil:
+		em.Consoler.OutLogAndConsole(logrus.InfoLevel, logrus.Fields{"FilePath": req.FilePath, "Length": len(buf)}, "Request sent successfully.")
+	case *AssertError:
+		em.Consoler.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"FilePath": req.FilePath, "Status": writeArgs.Response.Status, "error": err}, "Response assertion failed.")
+	default:
+		em.Consoler.OutLogAndConsole(logrus.ErrorLevel, logrus.Fields{"FilePath": req.FilePath, "error": err}, "Failed to send the request.")
+	}
+
+	// Trip the circuit breaker on the run errors.
+	em.Breaker.Observe(err)
+
 	// Update stats.
+	if res.warm && em.WarmupHist != nil {
+		em.WarmupHist.Update(elapsed)
+		return err
+	}
+	em.Search.Observe(elapsed, err)
+	em.count(err)
 	if histo, errH := em.GetHisto(); errH != nil {
 		em.Logger.Out(logrus.WarnLevel, logrus.Fields{"error": errH}, "Cannot capture Client execution stats.")
 	} else {
@@ -240,7 +561,128 @@ func (em *Emul) SendReq(ctx context.Context, filePath string, args *SendArgs) (e
 		histo.Update(elapsed)
 	}
 
-	return
+	// Break down the stats by request tags.
+	if em.Registry != nil {
+		for _, tag := range req.Tags {
+			metrics.GetOrRegisterHistogram("Client.tag."+tag, em.Registry, metrics.NewUniformSample(1028)).Update(elapsed)
+		}
+	}
+
+	return err
+}
+
+// client returns the network client of the endpoint type, the active endpoint client by default.
+func (em *Emul) client(cltType conf.ClientType) net.Client {
+	if clt, ok := em.Clients[cltType]; ok {
+		return clt
+	}
+	return em.Client
+}
+
+// shadowResult secondary endpoint response.
+type shadowResult struct {
+	response *net.Response
+	elapsed  int64
+	err      error
+}
+
+// sendShadow sends the request to the shadow endpoint side by side with the primary one.
+func (em *Emul) sendShadow(buf []byte, writeArgs net.WriteArgs) <-chan shadowResult {
+	if em.Shadow == nil {
+		return nil
+	}
+
+	// The primary response is saved, the shadow one is compared only.
+	writeArgs.IPAddress = em.Shadow.Endpoint.Address
+	writeArgs.Port = em.Shadow.Endpoint.Port
+	writeArgs.CltType = em.Shadow.Endpoint.Type
+	writeArgs.SaveReq = false
+	writeArgs.SaveRes = false
+	writeArgs.Response = &net.Response{}
+	writeArgs.Feedback = nil
+
+	res := make(chan shadowResult, 1)
+	go func() {
+		start := time.Now()
+		err := em.Shadow.Client.Write(buf, &writeArgs)
+		res <- shadowResult{response: writeArgs.Response, elapsed: int64(time.Since(start)) / int64(time.Millisecond), err: err}
+	}()
+	return res
+}
+
+// compareShadow compares the primary response with the shadow one and records the difference.
+func (em *Emul) compareShadow(req Request, primary *net.Response, err error, shadow <-chan shadowResult, warm bool) {
+	if shadow == nil {
+		return
+	}
+
+	res := <-shadow
+	if !warm && em.Shadow.Histogram != nil {
+		em.Shadow.Histogram.Update(res.elapsed)
+	}
+	if res.err != nil {
+		em.Shadow.Record(nil, res.err)
+		em.Logger.Out(logrus.WarnLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "error": res.err}, "Shadow request failed.")
+		return
+	}
+	if err != nil {
+		// Nothing to compare with.
+		return
+	}
+
+	diff := em.Shadow.Diff(primary, res.response)
+	if diff != nil {
+		diff.ReqID, diff.FilePath = req.ReqID, req.FilePath
+		em.Logger.Out(logrus.WarnLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "Diff": diff.Detail}, "Shadow response mismatch.")
+	}
+	em.Shadow.Record(diff, nil)
+}
+
+// verifyGolden compares the response with the golden response of the request file.
+func (em *Emul) verifyGolden(req Request, args *SendArgs, res *net.Response, err error) {
+	if em.Golden == nil || err != nil || req.FilePath == "" {
+		return
+	}
+
+	// The request files sharing the golden response are reported as mismatches.
+	key := GoldenKey(args.SrcDirs, req.FilePath)
+	if errC := em.Golden.Claim(key, req.FilePath); errC != nil {
+		em.Logger.Out(logrus.ErrorLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "error": errC}, "Golden response conflict.")
+		em.Golden.Record(&GoldenDiff{ReqID: req.ReqID, FilePath: req.FilePath, Diff: errC.Error() + "\n"}, false)
+		return
+	}
+	goldenPath, golden, errR := em.Golden.Read(key)
+	if errR != nil {
+		em.Golden.Record(nil, true)
+		em.Logger.Out(logrus.WarnLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "Golden": goldenPath, "error": errR}, "Golden response missing.")
+		return
+	}
+
+	diff := em.Golden.Compare(req, golden, res.Body)
+	if diff != nil {
+		em.Logger.Out(logrus.WarnLevel, logrus.Fields{"ReqID": req.ReqID, "FilePath": req.FilePath, "Diff": diff.Diff}, "Golden response mismatch.")
+	}
+	em.Golden.Record(diff, false)
+}
+
+// count counts transport errors and assertion failures separately.
+func (em *Emul) count(err error) {
+	switch err.(type) {
+	case nil:
+	case *AssertError:
+		if em.Failures != nil {
+			em.Failures.Inc(1)
+		}
+	default:
+		if em.Errors != nil {
+			em.Errors.Inc(1)
+		}
+	}
+}
+
+// WaitSaved waits for the responses being saved.
+func (em *Emul) WaitSaved() {
+	em.saving.Wait()
 }
 
 // GetHisto implements interface method to returns histogram.
@@ -16,19 +16,26 @@ package emul_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/alexstov/sling/conf"
 	"github.com/alexstov/sling/emul"
 	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/net"
 	"github.com/alexstov/sling/sio"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo" //"errors"
-
-	// . "github.com/onsi/gomega"
+	. "github.com/onsi/gomega"
+	"github.com/rcrowley/go-metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -223,6 +230,197 @@ var _ = Describe("Emul", func() {
 				testEmul.SendReq(ctx, filepath, &sendArgs)
 			})
 		})
+
+		Context("Assertions", func() {
+			It("failures counted separately from transport errors.", func() {
+				// Defer asserts.
+				defer mockCtrl.Finish()
+				defer GinkgoRecover()
+
+				testEmul.Errors = metrics.NewCounter()
+				testEmul.Failures = metrics.NewCounter()
+				sendArgs.Expect = &emul.Expect{Status: []int{200}}
+				defer func() { sendArgs.Expect = nil }()
+
+				testFileContent = []byte("mock file content")
+				mockFiler.EXPECT().DetermineContentType(sendArgs.Data).Return(sio.UnknownType, nil).Times(2)
+				mockFiler.EXPECT().ReadFile(sendArgs.Data).Return(testFileContent, nil).Times(2)
+				mockLimiter.EXPECT().Wait(ctx).Return(nil).Times(2)
+				mockClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+					args.Response.Status = 500
+					return nil
+				})
+				mockClient.EXPECT().Write(testFileContent, gomock.Any()).Return(errors.New("connection refused"))
+				mockConsoler.EXPECT().OutLogAndConsole(logrus.ErrorLevel, gomock.Any(), "Response assertion failed.").Return(nil)
+				mockConsoler.EXPECT().OutLogAndConsole(logrus.ErrorLevel, gomock.Any(), "Failed to send the request.").Return(nil)
+				mockLogger.EXPECT().Out(logrus.DebugLevel, nil, "Capturing Client execution stats.").Return(nil).Times(2)
+				mockHisto.EXPECT().Update(gomock.Any()).Times(2)
+
+				testEmul.SetLogger(mockLogger)
+				err := testEmul.SendReq(ctx, sendArgs.Data, &sendArgs)
+				Expect(err).To(BeAssignableToTypeOf(&emul.AssertError{}))
+				Expect(testEmul.SendReq(ctx, sendArgs.Data, &sendArgs)).ShouldNot(Succeed())
+				Expect(testEmul.Failures.Count()).To(Equal(int64(1)))
+				Expect(testEmul.Errors.Count()).To(Equal(int64(1)))
+			})
+		})
+
+		Context("Shadow", func() {
+			It("responses compared.", func() {
+				// Defer asserts.
+				defer mockCtrl.Finish()
+				defer GinkgoRecover()
+
+				mockShadowClient := mock.NewMockClient(mockCtrl)
+				shadow, _ := emul.NewShadow(conf.Endpoint{Address: "http://127.0.0.1:9898/TR", Type: conf.HTTPPost}, emul.JSONCompare, nil)
+				shadow.Client = mockShadowClient
+				shadow.Histogram = metrics.NewHistogram(metrics.NewUniformSample(1028))
+				testEmul.Shadow = shadow
+
+				testFileContent = []byte("mock file content")
+				respond := func(body string) func([]byte, *net.WriteArgs) error {
+					return func(msg []byte, args *net.WriteArgs) error {
+						args.Response.Status, args.Response.Body = 200, []byte(body)
+						return nil
+					}
+				}
+				mockFiler.EXPECT().DetermineContentType(sendArgs.Data).Return(sio.UnknownType, nil).Times(2)
+				mockFiler.EXPECT().ReadFile(sendArgs.Data).Return(testFileContent, nil).Times(2)
+				mockLimiter.EXPECT().Wait(ctx).Return(nil).Times(2)
+				mockClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(respond(`{"id": 1}`)).Times(2)
+				mockShadowClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(respond(`{"id":1}`))
+				mockShadowClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+					Expect(args.IPAddress).To(Equal("http://127.0.0.1:9898/TR"))
+					Expect(args.SaveRes).To(BeFalse())
+					args.Response.Status, args.Response.Body = 200, []byte(`{"id": 2}`)
+					return nil
+				})
+				mockLogger.EXPECT().Out(logrus.WarnLevel, gomock.Any(), "Shadow response mismatch.").Times(1)
+				mockLogger.EXPECT().Out(logrus.DebugLevel, nil, "Capturing Client execution stats.").Return(nil).Times(2)
+				mockHisto.EXPECT().Update(gomock.Any()).Times(2)
+				mockConsoler.EXPECT().OutLogAndConsole(logrus.InfoLevel, gomock.Any(), "Request sent successfully.").Return(nil).Times(2)
+
+				testEmul.SetLogger(mockLogger)
+				testEmul.SendReq(ctx, sendArgs.Data, &sendArgs)
+				testEmul.SendReq(ctx, sendArgs.Data, &sendArgs)
+				Expect(shadow.Compared).To(Equal(2))
+				Expect(shadow.Diffs).To(HaveLen(1))
+				Expect(shadow.Diffs[0].Paths).To(Equal([]string{"$.id"}))
+				Expect(shadow.Histogram.Count()).To(Equal(int64(2)))
+			})
+		})
+
+		Context("Retry", func() {
+			It("failed request retried against the limiter.", func() {
+				// Defer asserts.
+				defer mockCtrl.Finish()
+				defer GinkgoRecover()
+
+				testEmul.Retry, _ = emul.NewRetry(3, time.Millisecond, 10*time.Millisecond, 0, []string{"reset", "503"}, 1)
+				defer func() { testEmul.Retry = nil }()
+
+				testFileContent = []byte("mock file content")
+				mockFiler.EXPECT().DetermineContentType(sendArgs.Data).Return(sio.UnknownType, nil)
+				mockFiler.EXPECT().ReadFile(sendArgs.Data).Return(testFileContent, nil)
+				mockLimiter.EXPECT().Wait(ctx).Return(nil).Times(3)
+				gomock.InOrder(
+					mockClient.EXPECT().Write(testFileContent, gomock.Any()).Return(fmt.Errorf("read: %w", syscall.ECONNRESET)),
+					mockClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+						args.Response.Status = 503
+						return nil
+					}),
+					mockClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+						args.Response.Status = 200
+						return nil
+					}))
+				mockLogger.EXPECT().Out(logrus.WarnLevel, gomock.Any(), "Retrying the request.").Times(2)
+				mockLogger.EXPECT().Out(logrus.DebugLevel, nil, "Capturing Client execution stats.").Return(nil)
+				mockHisto.EXPECT().Update(gomock.Any())
+				mockConsoler.EXPECT().OutLogAndConsole(logrus.InfoLevel, gomock.Any(), "Request sent successfully.").Return(nil)
+
+				testEmul.SetLogger(mockLogger)
+				Expect(testEmul.SendReq(ctx, sendArgs.Data, &sendArgs)).Should(Succeed())
+				Expect([]int64{testEmul.Retry.FirstTry, testEmul.Retry.Recovered, testEmul.Retry.Retries}).To(Equal([]int64{0, 1, 2}))
+			})
+		})
+
+		Context("Golden", func() {
+			It("golden response read outside the request filer.", func() {
+				// Defer asserts.
+				defer mockCtrl.Finish()
+				defer GinkgoRecover()
+
+				dir, err := ioutil.TempDir("", "golden")
+				Expect(err).Should(BeNil())
+				defer os.RemoveAll(dir)
+				Expect(ioutil.WriteFile(emul.GoldenPath(dir, sendArgs.Data), []byte("pong"), 0644)).Should(Succeed())
+				testEmul.Golden, err = emul.NewGolden(dir, nil)
+				Expect(err).Should(BeNil())
+				defer func() { testEmul.Golden = nil }()
+
+				testFileContent = []byte("mock file content")
+				mockFiler.EXPECT().DetermineContentType(sendArgs.Data).Return(sio.UnknownType, nil)
+				mockFiler.EXPECT().ReadFile(sendArgs.Data).Return(testFileContent, nil).Times(1)
+				mockLimiter.EXPECT().Wait(ctx).Return(nil)
+				mockClient.EXPECT().Write(testFileContent, gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+					args.Response.Body = []byte("pong")
+					return nil
+				})
+				mockLogger.EXPECT().Out(logrus.DebugLevel, nil, "Capturing Client execution stats.").Return(nil)
+				mockHisto.EXPECT().Update(gomock.Any())
+				mockConsoler.EXPECT().OutLogAndConsole(logrus.InfoLevel, gomock.Any(), "Request sent successfully.").Return(nil)
+
+				testEmul.SetLogger(mockLogger)
+				Expect(testEmul.SendReq(ctx, sendArgs.Data, &sendArgs)).Should(Succeed())
+				Expect(testEmul.Golden.Verified).To(Equal(1))
+				Expect(testEmul.Golden.Missing).To(Equal(0))
+				Expect(testEmul.Golden.Diffs).To(BeEmpty())
+			})
+		})
+
+		Context("Manifest", func() {
+			It("requests sent with the client of the endpoint type.", func() {
+				// Defer asserts.
+				defer mockCtrl.Finish()
+				defer GinkgoRecover()
+
+				dir, err := ioutil.TempDir("", "manifest")
+				Expect(err).Should(BeNil())
+				defer os.RemoveAll(dir)
+				path := filepath.Join(dir, "manifest.yml")
+				Expect(ioutil.WriteFile(path, []byte("- body: http\n- endpoint: tcp\n  body: tcp\n"), 0644)).Should(Succeed())
+				requests, err := emul.ReadManifest(path)
+				Expect(err).Should(BeNil())
+
+				mockTCPClient := mock.NewMockClient(mockCtrl)
+				testEmul.Clients = map[conf.ClientType]net.Client{conf.HTTPPost: mockClient, conf.TCP: mockTCPClient}
+				sendArgs.CltType = conf.HTTPPost
+				sendArgs.Endpoints = []conf.Endpoint{{Name: "http", Address: "http://127.0.0.1:9897/TR", Type: conf.HTTPPost},
+					{Name: "tcp", Address: "127.0.0.1", Port: 9898, Type: conf.TCP}}
+				defer func() { sendArgs.CltType, sendArgs.Endpoints = conf.UnknownClient, nil }()
+
+				mockLimiter.EXPECT().Wait(ctx).Return(nil).Times(2)
+				mockClient.EXPECT().Write([]byte("http"), gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+					Expect(args.CltType).To(Equal(conf.HTTPPost))
+					return nil
+				})
+				mockTCPClient.EXPECT().Write([]byte("tcp"), gomock.Any()).DoAndReturn(func(msg []byte, args *net.WriteArgs) error {
+					Expect(args.CltType).To(Equal(conf.TCP))
+					Expect(args.IPAddress).To(Equal("127.0.0.1"))
+					Expect(args.Port).To(Equal(uint(9898)))
+					return nil
+				})
+				mockLogger.EXPECT().Out(logrus.DebugLevel, nil, "Capturing Client execution stats.").Return(nil).Times(2)
+				mockHisto.EXPECT().Update(gomock.Any()).Times(2)
+				mockConsoler.EXPECT().OutLogAndConsole(logrus.InfoLevel, gomock.Any(), "Request sent successfully.").Return(nil).Times(2)
+
+				testEmul.SetLogger(mockLogger)
+				for i, req := range requests {
+					req.ReqID = uint64(i + 1)
+					Expect(testEmul.SendRequest(ctx, req, &sendArgs)).Should(Succeed())
+				}
+			})
+		})
 	})
 
 	Describe("Dispatcher MultiSend", func() {
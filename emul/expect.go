@@ -0,0 +1,229 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/net"
+	"github.com/alexstov/sling/util"
+	"github.com/pkg/errors"
+)
+
+// Expect expected response assertions.
+type Expect struct {
+	Status       []int    `yaml:"status" json:"status"`
+	Match        []string `yaml:"match" json:"match"`
+	NotMatch     []string `yaml:"notMatch" json:"notMatch"`
+	JSONEquals   []string `yaml:"jsonEquals" json:"jsonEquals"`
+	JSONExists   []string `yaml:"jsonExists" json:"jsonExists"`
+	MinSize      uint     `yaml:"minSize" json:"minSize"`
+	MaxSize      uint     `yaml:"maxSize" json:"maxSize"`
+	MaxLatencyMs uint     `yaml:"maxLatencyMs" json:"maxLatencyMs"`
+	match        []*regexp.Regexp
+	notMatch     []*regexp.Regexp
+	equals       []jsonEquals
+	exists       []util.JSONPath
+}
+
+// jsonEquals JSON path and the expected value.
+type jsonEquals struct {
+	path  util.JSONPath
+	value interface{}
+}
+
+// AssertError response assertion failure, the request was sent and the response received.
+type AssertError struct {
+	Reason string
+}
+
+func (e *AssertError) Error() string {
+	return e.Reason
+}
+
+// assertf creates assertion failure.
+func assertf(format string, args ...interface{}) error {
+	return &AssertError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Compile compiles the patterns and JSON paths, call before Check.
+func (ex *Expect) Compile() (err error) {
+	if ex == nil {
+		return nil
+	}
+
+	ex.match, ex.notMatch, ex.equals, ex.exists = nil, nil, nil, nil
+	for _, pattern := range ex.Match {
+		var re *regexp.Regexp
+		if re, err = regexp.Compile(pattern); err != nil {
+			return errors.Wrap(err, "regexp.Compile")
+		}
+		ex.match = append(ex.match, re)
+	}
+	for _, pattern := range ex.NotMatch {
+		var re *regexp.Regexp
+		if re, err = regexp.Compile(pattern); err != nil {
+			return errors.Wrap(err, "regexp.Compile")
+		}
+		ex.notMatch = append(ex.notMatch, re)
+	}
+
+	// The expected value is JSON, e.g. $.count=3 or $.name="x", or a string, e.g. $.name=x.
+	for _, expr := range ex.JSONEquals {
+		i := strings.Index(expr, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid JSON path equals %q, expected path=value", expr)
+		}
+		eq := jsonEquals{}
+		if eq.path, err = util.ParseJSONPath(expr[:i]); err != nil {
+			return err
+		}
+		if eq.value, err = util.DecodeJSON([]byte(expr[i+1:])); err != nil {
+			eq.value, err = expr[i+1:], nil
+		}
+		ex.equals = append(ex.equals, eq)
+	}
+	for _, path := range ex.JSONExists {
+		var p util.JSONPath
+		if p, err = util.ParseJSONPath(path); err != nil {
+			return err
+		}
+		ex.exists = append(ex.exists, p)
+	}
+	if ex.MaxSize > 0 && ex.MinSize > ex.MaxSize {
+		return fmt.Errorf("invalid response size range %d..%d", ex.MinSize, ex.MaxSize)
+	}
+
+	return nil
+}
+
+// Merge returns the request expectations overriding the global ones field by field.
+func (ex *Expect) Merge(req *Expect) *Expect {
+	if ex == nil {
+		return req
+	}
+	if req == nil {
+		return ex
+	}
+
+	merged := *ex
+	if len(req.Status) > 0 {
+		merged.Status = req.Status
+	}
+	if len(req.Match) > 0 {
+		merged.Match, merged.match = req.Match, req.match
+	}
+	if len(req.NotMatch) > 0 {
+		merged.NotMatch, merged.notMatch = req.NotMatch, req.notMatch
+	}
+	if len(req.JSONEquals) > 0 {
+		merged.JSONEquals, merged.equals = req.JSONEquals, req.equals
+	}
+	if len(req.JSONExists) > 0 {
+		merged.JSONExists, merged.exists = req.JSONExists, req.exists
+	}
+	if req.MinSize > 0 {
+		merged.MinSize = req.MinSize
+	}
+	if req.MaxSize > 0 {
+		merged.MaxSize = req.MaxSize
+	}
+	if req.MaxLatencyMs > 0 {
+		merged.MaxLatencyMs = req.MaxLatencyMs
+	}
+	return &merged
+}
+
+// ForClient returns the expectations checked for the client type, TCP responses have no status.
+func (ex *Expect) ForClient(cltType conf.ClientType) *Expect {
+	if ex == nil || cltType != conf.TCP || len(ex.Status) == 0 {
+		return ex
+	}
+
+	tcp := *ex
+	tcp.Status = nil
+	return &tcp
+}
+
+// Check verifies the response and the latency meet the expectations, returns *AssertError on failure.
+func (ex *Expect) Check(res *net.Response, latency time.Duration) (err error) {
+	if ex == nil || res == nil {
+		return nil
+	}
+
+	if len(ex.Status) > 0 {
+		var found bool
+		for _, status := range ex.Status {
+			if status == res.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return assertf("unexpected response status %d, expected %v", res.Status, ex.Status)
+		}
+	}
+
+	size := uint(len(res.Body))
+	if size < ex.MinSize || (ex.MaxSize > 0 && size > ex.MaxSize) {
+		return assertf("response size %d out of range %d..%d", size, ex.MinSize, ex.MaxSize)
+	}
+	if ex.MaxLatencyMs > 0 && latency > time.Duration(ex.MaxLatencyMs)*time.Millisecond {
+		return assertf("response latency %v exceeds %dms", latency, ex.MaxLatencyMs)
+	}
+
+	for _, re := range ex.match {
+		if !re.Match(res.Body) {
+			return assertf("response does not match %q", re)
+		}
+	}
+	for _, re := range ex.notMatch {
+		if re.Match(res.Body) {
+			return assertf("response matches forbidden %q", re)
+		}
+	}
+
+	if len(ex.equals) == 0 && len(ex.exists) == 0 {
+		return nil
+	}
+	doc, errJ := util.DecodeJSON(res.Body)
+	if errJ != nil {
+		return assertf("response is not JSON: %v", errJ)
+	}
+	for _, p := range ex.exists {
+		if len(p.Get(doc)) == 0 {
+			return assertf("response has no %s", p)
+		}
+	}
+	for _, eq := range ex.equals {
+		values := eq.path.Get(doc)
+		if len(values) == 0 {
+			return assertf("response has no %s", eq.path)
+		}
+		for _, value := range values {
+			if len(util.DiffJSON(eq.value, value)) > 0 {
+				actual, _ := json.Marshal(value)
+				return assertf("response %s is %s, expected %v", eq.path, actual, eq.value)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/net"
+)
+
+var _ = Describe("Expect", func() {
+	res := &net.Response{Status: 200, Body: []byte(`{"status": "ok", "count": 3, "items": [{"id": 7}]}`)}
+
+	check := func(expect *emul.Expect, latency time.Duration) error {
+		Expect(expect.Compile()).Should(Succeed())
+		return expect.Check(res, latency)
+	}
+
+	It("forbidden patterns.", func() {
+		defer GinkgoRecover()
+		Expect(check(&emul.Expect{NotMatch: []string{`"error"`}}, 0)).Should(Succeed())
+		err := check(&emul.Expect{NotMatch: []string{`"status":\s*"ok"`}}, 0)
+		Expect(err).To(BeAssignableToTypeOf(&emul.AssertError{}))
+		Expect(err.Error()).To(ContainSubstring("forbidden"))
+	})
+
+	It("JSON path equals and exists.", func() {
+		defer GinkgoRecover()
+		Expect(check(&emul.Expect{JSONEquals: []string{"$.status=ok", `$.status="ok"`, "$.count=3.0", "$.items[*].id=7"}, JSONExists: []string{"$.items[0].id"}}, 0)).Should(Succeed())
+		Expect(check(&emul.Expect{JSONEquals: []string{"$.count=4"}}, 0).Error()).To(Equal("response $.count is 3, expected 4"))
+		Expect(check(&emul.Expect{JSONExists: []string{"$.items[1]"}}, 0).Error()).To(Equal("response has no $.items[1]"))
+		Expect((&emul.Expect{JSONEquals: []string{"$.status"}}).Compile()).ShouldNot(Succeed())
+	})
+
+	It("size range and latency.", func() {
+		defer GinkgoRecover()
+		Expect(check(&emul.Expect{MinSize: 10, MaxSize: 100, MaxLatencyMs: 50}, 10*time.Millisecond)).Should(Succeed())
+		Expect(check(&emul.Expect{MaxSize: 10}, 0)).ShouldNot(Succeed())
+		Expect(check(&emul.Expect{MinSize: 100}, 0)).ShouldNot(Succeed())
+		Expect(check(&emul.Expect{MaxLatencyMs: 50}, 60*time.Millisecond)).ShouldNot(Succeed())
+		Expect((&emul.Expect{MinSize: 10, MaxSize: 5}).Compile()).ShouldNot(Succeed())
+	})
+
+	It("request expectations override global ones.", func() {
+		defer GinkgoRecover()
+		global := &emul.Expect{Status: []int{200}, MaxLatencyMs: 50}
+		req := &emul.Expect{Status: []int{404}}
+		Expect(global.Compile()).Should(Succeed())
+		Expect(req.Compile()).Should(Succeed())
+		merged := global.Merge(req)
+		Expect(merged.Status).To(Equal([]int{404}))
+		Expect(merged.MaxLatencyMs).To(Equal(uint(50)))
+		Expect(global.Status).To(Equal([]int{200}))
+		Expect(global.Merge(nil)).To(BeIdenticalTo(global))
+		Expect((*emul.Expect)(nil).Merge(req)).To(BeIdenticalTo(req))
+	})
+
+	It("TCP responses are not checked for status.", func() {
+		defer GinkgoRecover()
+		expect := &emul.Expect{Status: []int{200}, MaxSize: 4}
+		Expect(expect.Compile()).Should(Succeed())
+		tcp := &net.Response{Body: []byte("ok")}
+		Expect(expect.Check(tcp, 0)).ShouldNot(Succeed())
+		Expect(expect.ForClient(conf.TCP).Check(tcp, 0)).Should(Succeed())
+		Expect(expect.ForClient(conf.TCP).Check(&net.Response{Body: []byte("large")}, 0)).ShouldNot(Succeed())
+		Expect(expect.ForClient(conf.HTTPPost)).To(BeIdenticalTo(expect))
+		Expect(expect.Status).To(Equal([]int{200}))
+	})
+})
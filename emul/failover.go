@@ -0,0 +1,200 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/net"
+)
+
+// FailoverEvent active endpoint switch or failed endpoint recovery.
+type FailoverEvent struct {
+	At     time.Time
+	Action string
+	From   string
+	To     string
+	Reason string
+}
+
+// Failover switches to the next configured endpoint when the active one accumulates
+// connection errors and probes the failed endpoints for recovery. The recovered endpoint
+// preceding the active one in the configuration becomes active again.
+type Failover struct {
+	Endpoints []conf.Endpoint
+	Errors    uint
+	Interval  time.Duration
+	Prober    func(ept conf.Endpoint) error
+	OnEvent   func(event FailoverEvent)
+	events    []FailoverEvent
+	active    int
+	failures  uint
+	down      []bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+}
+
+// NewFailover creates failover starting at the active endpoint, probing the failed
+// endpoints every interval.
+func NewFailover(endpoints []conf.Endpoint, active int, threshold uint, interval time.Duration) (failover *Failover, err error) {
+	if len(endpoints) < 2 {
+		return nil, fmt.Errorf("failover needs at least two endpoints, %d configured", len(endpoints))
+	}
+	if active < 0 || active >= len(endpoints) {
+		return nil, fmt.Errorf("invalid active endpoint index %d", active)
+	}
+	if threshold == 0 {
+		threshold = 1
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	failover = &Failover{Endpoints: endpoints, Errors: threshold, Interval: interval, active: active,
+		down: make([]bool, len(endpoints)), done: make(chan struct{})}
+	failover.Prober = func(ept conf.Endpoint) error { return net.Probe(ept, interval) }
+	return failover, nil
+}
+
+// EndpointName names the endpoint in the failover events.
+func EndpointName(ept conf.Endpoint) string {
+	if ept.Name != "" {
+		return ept.Name
+	}
+	return ept.HostPort()
+}
+
+// Active returns the active endpoint and its index.
+func (f *Failover) Active() (ept conf.Endpoint, index int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Endpoints[f.active], f.active
+}
+
+// Observe records the request outcome at the endpoint, the connection errors at the active
+// endpoint switch to the next endpoint which is not down.
+func (f *Failover) Observe(index int, err error) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	if index != f.active {
+		// Sent before the switch.
+		f.mu.Unlock()
+		return
+	}
+	if _, ok := ClassifyError(err); !ok {
+		f.failures = 0
+		f.mu.Unlock()
+		return
+	}
+	if f.failures++; f.failures < f.Errors {
+		f.mu.Unlock()
+		return
+	}
+
+	// Probe the failed endpoint and switch to the next one, stay if all are down.
+	f.failures = 0
+	if !f.down[index] {
+		f.down[index] = true
+		f.wg.Add(1)
+		go f.probe(index)
+	}
+	next := -1
+	for i := 1; i < len(f.Endpoints); i++ {
+		if j := (index + i) % len(f.Endpoints); !f.down[j] {
+			next = j
+			break
+		}
+	}
+	if next < 0 {
+		f.mu.Unlock()
+		return
+	}
+	f.active = next
+	event := f.record("failover", index, next, fmt.Sprintf("%d connection errors, %v", f.Errors, err))
+	f.mu.Unlock()
+
+	f.notify(event)
+}
+
+// probe probes the failed endpoint until it recovers or the failover is closed.
+func (f *Failover) probe(index int) {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+		}
+		if err := f.Prober(f.Endpoints[index]); err != nil {
+			continue
+		}
+
+		f.mu.Lock()
+		f.down[index] = false
+		var event FailoverEvent
+		if index < f.active || f.down[f.active] {
+			event = f.record("failback", f.active, index, "endpoint recovered")
+			f.active, f.failures = index, 0
+		} else {
+			event = f.record("recovered", index, index, "endpoint recovered")
+		}
+		f.mu.Unlock()
+
+		f.notify(event)
+		return
+	}
+}
+
+// record records the event, the caller holds the lock.
+func (f *Failover) record(action string, from, to int, reason string) FailoverEvent {
+	event := FailoverEvent{At: time.Now(), Action: action, From: EndpointName(f.Endpoints[from]),
+		To: EndpointName(f.Endpoints[to]), Reason: reason}
+	f.events = append(f.events, event)
+	return event
+}
+
+// notify reports the event to the event handler.
+func (f *Failover) notify(event FailoverEvent) {
+	if f.OnEvent != nil {
+		f.OnEvent(event)
+	}
+}
+
+// Events returns the failover events.
+func (f *Failover) Events() []FailoverEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FailoverEvent(nil), f.events...)
+}
+
+// Close stops probing the failed endpoints.
+func (f *Failover) Close() {
+	if f == nil {
+		return
+	}
+
+	close(f.done)
+	f.wg.Wait()
+}
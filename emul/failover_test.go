@@ -0,0 +1,102 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("Failover", func() {
+	endpoints := []conf.Endpoint{
+		{Name: "primary", Address: "http://127.0.0.1:9001/TR", Type: conf.HTTPPost},
+		{Name: "dr", Address: "http://127.0.0.1:9002/TR", Type: conf.HTTPPost},
+		{Address: "http://127.0.0.1:9003/TR", Type: conf.HTTPPost},
+	}
+
+	Describe("NewFailover", func() {
+		It("needs two endpoints.", func() {
+			defer GinkgoRecover()
+			_, err := emul.NewFailover(endpoints[:1], 0, 3, time.Second)
+			Expect(err).ShouldNot(BeNil())
+			_, err = emul.NewFailover(endpoints, 3, 3, time.Second)
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("Observe", func() {
+		It("switches on connection errors and fails back when recovered.", func() {
+			defer GinkgoRecover()
+			failover, err := emul.NewFailover(endpoints, 0, 2, 10*time.Millisecond)
+			Expect(err).Should(BeNil())
+			var up int32
+			failover.Prober = func(ept conf.Endpoint) error {
+				if atomic.LoadInt32(&up) == 0 {
+					return syscall.ECONNREFUSED
+				}
+				return nil
+			}
+			events := make(chan emul.FailoverEvent, 10)
+			failover.OnEvent = func(event emul.FailoverEvent) { events <- event }
+			defer failover.Close()
+
+			// Other errors and successes do not count.
+			failover.Observe(0, syscall.ECONNREFUSED)
+			failover.Observe(0, errors.New("assertion failed"))
+			failover.Observe(0, syscall.ECONNREFUSED)
+			_, index := failover.Active()
+			Expect(index).To(Equal(0))
+
+			failover.Observe(0, syscall.ECONNREFUSED)
+			ept, index := failover.Active()
+			Expect(index).To(Equal(1))
+			Expect(ept.Name).To(Equal("dr"))
+			event := <-events
+			Expect([]string{event.Action, event.From, event.To}).To(Equal([]string{"failover", "primary", "dr"}))
+
+			// Requests sent to the failed endpoint before the switch are ignored.
+			failover.Observe(0, syscall.ECONNREFUSED)
+			failover.Observe(0, syscall.ECONNREFUSED)
+			_, index = failover.Active()
+			Expect(index).To(Equal(1))
+
+			atomic.StoreInt32(&up, 1)
+			Eventually(events).Should(Receive(&event))
+			Expect([]string{event.Action, event.From, event.To}).To(Equal([]string{"failback", "dr", "primary"}))
+			_, index = failover.Active()
+			Expect(index).To(Equal(0))
+			Expect(failover.Events()).To(HaveLen(2))
+		})
+		It("stays when all endpoints are down.", func() {
+			defer GinkgoRecover()
+			failover, _ := emul.NewFailover(endpoints[1:], 0, 1, time.Hour)
+			defer failover.Close()
+			failover.Observe(0, syscall.ECONNRESET)
+			failover.Observe(1, syscall.ECONNRESET)
+			_, index := failover.Active()
+			Expect(index).To(Equal(1))
+			Expect(failover.Events()).To(HaveLen(1))
+			Expect(failover.Events()[0].To).To(Equal("127.0.0.1:9003"))
+		})
+	})
+})
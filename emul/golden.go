@@ -0,0 +1,262 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alexstov/sling/util"
+)
+
+// ResIndex saved responses index file name, maps the response file to the request file.
+const ResIndex = "index.csv"
+
+// masked replaces the masked values.
+const masked = "<masked>"
+
+// Mask masks volatile response fields by JSON path, e.g. $.meta.ts, or by regex, e.g. re:[0-9a-f-]{36}.
+type Mask struct {
+	Path   util.JSONPath
+	Regexp *regexp.Regexp
+}
+
+// ParseMask parses JSON path starting with $ or regex optionally prefixed with re:.
+func ParseMask(str string) (mask Mask, err error) {
+	if strings.HasPrefix(str, "$") {
+		mask.Path, err = util.ParseJSONPath(str)
+		return
+	}
+	if mask.Regexp, err = regexp.Compile(strings.TrimPrefix(str, "re:")); err != nil {
+		err = fmt.Errorf("invalid mask %q: %v", str, err)
+	}
+	return
+}
+
+// GoldenDiff golden and actual response difference.
+type GoldenDiff struct {
+	ReqID    uint64
+	FilePath string
+	Diff     string
+}
+
+// Golden verifies the responses against the golden responses of the same request files.
+type Golden struct {
+	Dir      string
+	Masks    []Mask
+	Diffs    []GoldenDiff
+	Verified int
+	Missing  int
+	files    map[string]string
+	mu       sync.Mutex
+}
+
+// NewGolden creates golden verifier with the golden responses in dir and the response masks.
+func NewGolden(dir string, masks []string) (golden *Golden, err error) {
+	golden = &Golden{Dir: dir, files: make(map[string]string)}
+	for _, str := range masks {
+		var mask Mask
+		if mask, err = ParseMask(str); err != nil {
+			return nil, err
+		}
+		golden.Masks = append(golden.Masks, mask)
+	}
+
+	return golden, nil
+}
+
+// GoldenKey returns the request file path relative to the first source directory containing it,
+// prefixed with the source directory name when there are several, or the file name for the files
+// outside the source directories.
+func GoldenKey(srcDirs []string, filePath string) string {
+	for _, dir := range srcDirs {
+		if rel, err := filepath.Rel(dir, filePath); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			if len(srcDirs) > 1 {
+				rel = filepath.Join(filepath.Base(dir), rel)
+			}
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(filePath)
+}
+
+// GoldenPath returns the golden response path of the request file key, the golden directory mirrors the source directories.
+func GoldenPath(dir string, key string) string {
+	return filepath.Join(dir, filepath.FromSlash(key)+".res")
+}
+
+// Read reads the golden response of the key from the golden directory. The golden responses are read
+// directly, they do not take the request cache memory budget from the request bodies.
+func (g *Golden) Read(key string) (path string, golden []byte, err error) {
+	path = GoldenPath(g.Dir, key)
+	golden, err = ioutil.ReadFile(path)
+	return
+}
+
+// Claim assigns the golden response key to the request file, fails when another request file has the key.
+func (g *Golden) Claim(key string, filePath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if other, ok := g.files[key]; ok && other != filePath {
+		return fmt.Errorf("request files %s and %s share the golden response %s", other, filePath, key)
+	}
+	g.files[key] = filePath
+	return nil
+}
+
+// Normalize masks the volatile fields. JSON responses are formatted with sorted keys, one value per line.
+func (g *Golden) Normalize(body []byte) string {
+	if doc, err := util.DecodeJSON(body); err == nil {
+		for _, mask := range g.Masks {
+			if mask.Path != nil {
+				mask.Path.Set(doc, masked)
+			}
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(doc); err == nil {
+			body = buf.Bytes()
+		}
+	}
+
+	text := string(body)
+	for _, mask := range g.Masks {
+		if mask.Regexp != nil {
+			text = mask.Regexp.ReplaceAllString(text, masked)
+		}
+	}
+	return text
+}
+
+// Compare compares the normalized golden and actual responses, nil if they match.
+func (g *Golden) Compare(req Request, golden, actual []byte) *GoldenDiff {
+	want, got := g.Normalize(golden), g.Normalize(actual)
+	if want == got {
+		return nil
+	}
+
+	name := filepath.Base(req.FilePath)
+	diff := util.UnifiedDiff("golden/"+name, "actual/"+name, util.SplitLines(want), util.SplitLines(got), 3)
+	return &GoldenDiff{ReqID: req.ReqID, FilePath: req.FilePath, Diff: diff}
+}
+
+// Record records the verification outcome, diff is nil for matching responses.
+func (g *Golden) Record(diff *GoldenDiff, missing bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if missing {
+		g.Missing++
+		return
+	}
+	g.Verified++
+	if diff != nil {
+		g.Diffs = append(g.Diffs, *diff)
+	}
+}
+
+// resIndexMu serializes the saved responses index appends.
+var resIndexMu sync.Mutex
+
+// appendResIndex appends the response file, the request file and its golden key to the saved responses index.
+func appendResIndex(resDir string, resName string, filePath string, key string) (err error) {
+	resIndexMu.Lock()
+	defer resIndexMu.Unlock()
+
+	var f *os.File
+	if f, err = os.OpenFile(filepath.Join(resDir, ResIndex), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		return
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{resName, filePath, key})
+	w.Flush()
+	return w.Error()
+}
+
+// ApproveGoldens promotes the responses saved in resDir to the golden responses in goldenDir.
+// The latest response of the request file repeated in the session wins, the indexed responses
+// not saved, e.g. when sling exited before saving, are skipped. Fails when different request
+// files share the golden response.
+func ApproveGoldens(resDir string, goldenDir string) (count int, skipped int, err error) {
+	var f *os.File
+	if f, err = os.Open(filepath.Join(resDir, ResIndex)); err != nil {
+		return
+	}
+	defer f.Close()
+
+	if err = os.MkdirAll(goldenDir, 0755); err != nil {
+		return
+	}
+
+	approved := make(map[string]string)
+	files := make(map[string]string)
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	for {
+		var record []string
+		if record, err = r.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, 0, err
+		}
+		if len(record) < 2 {
+			return 0, 0, fmt.Errorf("invalid saved responses index record %v", record)
+		}
+
+		// The index of the older sessions has no key, the golden response is the file name.
+		key := filepath.Base(record[1])
+		if len(record) > 2 {
+			key = record[2]
+		}
+		if other, ok := files[key]; ok && other != record[1] {
+			return 0, 0, fmt.Errorf("request files %s and %s share the golden response %s", other, record[1], key)
+		}
+		files[key] = record[1]
+		approved[GoldenPath(goldenDir, key)] = filepath.Join(resDir, record[0])
+	}
+
+	for golden, res := range approved {
+		var buf []byte
+		if buf, err = ioutil.ReadFile(res); os.IsNotExist(err) {
+			skipped++
+			continue
+		} else if err != nil {
+			return count, skipped, err
+		}
+		if err = os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			return count, skipped, err
+		}
+		if err = ioutil.WriteFile(golden, buf, 0644); err != nil {
+			return count, skipped, err
+		}
+		count++
+	}
+
+	return count, skipped, nil
+}
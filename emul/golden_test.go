@@ -0,0 +1,111 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("Golden", func() {
+	Describe("Compare", func() {
+		It("masked fields match.", func() {
+			defer GinkgoRecover()
+			golden, err := emul.NewGolden("", []string{"$.ts", `re:id-[0-9]+`})
+			Expect(err).Should(BeNil())
+			req := emul.Request{ReqID: 3, FilePath: "/tmp/data/req.dat"}
+			Expect(golden.Compare(req, []byte(`{"ts": 1, "ref": "id-12", "v": 1}`), []byte(`{"v":1,"ref":"id-99","ts":2}`))).To(BeNil())
+			diff := golden.Compare(req, []byte(`{"ts": 1, "v": 1}`), []byte(`{"ts": 2, "v": 2}`))
+			Expect(diff.ReqID).To(Equal(uint64(3)))
+			Expect(diff.Diff).To(ContainSubstring("--- golden/req.dat\n+++ actual/req.dat\n"))
+			Expect(diff.Diff).To(ContainSubstring("-  \"v\": 1\n+  \"v\": 2\n"))
+			Expect(diff.Diff).To(ContainSubstring(" \"ts\": \"<masked>\","))
+		})
+		It("invalid masks.", func() {
+			defer GinkgoRecover()
+			_, err := emul.NewGolden("", []string{"$.a[", "re:("})
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("GoldenKey", func() {
+		It("mirrors the source directories.", func() {
+			defer GinkgoRecover()
+			Expect(emul.GoldenKey([]string{"/data/a"}, "/data/a/sub/x.dat")).To(Equal("sub/x.dat"))
+			dirs := []string{"/data/a", "/data/b"}
+			Expect(emul.GoldenKey(dirs, "/data/a/x.dat")).To(Equal("a/x.dat"))
+			Expect(emul.GoldenKey(dirs, "/data/b/sub/x.dat")).To(Equal("b/sub/x.dat"))
+			Expect(emul.GoldenKey(dirs, "/other/x.dat")).To(Equal("x.dat"))
+			Expect(emul.GoldenPath("/golden", "sub/x.dat")).To(Equal(filepath.Join("/golden", "sub", "x.dat.res")))
+
+			golden, _ := emul.NewGolden("/golden", nil)
+			Expect(golden.Claim("x.dat", "/data/a/x.dat")).Should(Succeed())
+			Expect(golden.Claim("x.dat", "/data/a/x.dat")).Should(Succeed())
+			Expect(golden.Claim("x.dat", "/other/x.dat")).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("ApproveGoldens", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "golden")
+			Expect(err).Should(BeNil())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("promotes the latest session responses.", func() {
+			defer GinkgoRecover()
+			resDir, goldenDir := filepath.Join(dir, "res"), filepath.Join(dir, "golden")
+			Expect(os.Mkdir(resDir, 0755)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(resDir, "001.res"), []byte("first"), 0644)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(resDir, "002.res"), []byte("second"), 0644)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(resDir, "003.res"), []byte("other"), 0644)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(resDir, emul.ResIndex), []byte("001.res,/data/a.dat,a.dat\n002.res,/data/a.dat,a.dat\n003.res,/data/x/b.dat,x/b.dat\n004.res,/data/c.dat,c.dat\n"), 0644)).Should(Succeed())
+
+			count, skipped, err := emul.ApproveGoldens(resDir, goldenDir)
+			Expect(err).Should(BeNil())
+			Expect(count).To(Equal(2))
+			Expect(skipped).To(Equal(1))
+			Expect(ioutil.ReadFile(emul.GoldenPath(goldenDir, "a.dat"))).To(Equal([]byte("second")))
+			Expect(ioutil.ReadFile(filepath.Join(goldenDir, "x", "b.dat.res"))).To(Equal([]byte("other")))
+		})
+		It("request files sharing the golden response.", func() {
+			defer GinkgoRecover()
+			resDir := filepath.Join(dir, "res")
+			Expect(os.Mkdir(resDir, 0755)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(resDir, emul.ResIndex), []byte("001.res,/a/x.dat\n002.res,/b/x.dat\n"), 0644)).Should(Succeed())
+
+			_, _, err := emul.ApproveGoldens(resDir, filepath.Join(dir, "golden"))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("share the golden response x.dat"))
+		})
+		It("no saved responses index.", func() {
+			defer GinkgoRecover()
+			_, _, err := emul.ApproveGoldens(dir, filepath.Join(dir, "golden"))
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,83 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/throt"
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+)
+
+// EndpointLimit endpoint limiter and the time the requests spent waiting in its queue.
+type EndpointLimit struct {
+	Name    string
+	Limiter *throt.EndpointLimiter
+	Wait    metrics.Histogram
+}
+
+// EndpointLimits endpoint limits by the endpoint address and port.
+type EndpointLimits map[string]*EndpointLimit
+
+// NewEndpointLimits creates the limits of the endpoints with the rate or concurrency
+// limit, nil if none is limited.
+func NewEndpointLimits(endpoints []conf.Endpoint) (limits EndpointLimits, err error) {
+	for _, ept := range endpoints {
+		var windows []throt.RateWindow
+		if windows, err = throt.ParseRate(ept.Rate); err != nil {
+			return nil, errors.Wrapf(err, "endpoint %s", EndpointName(ept))
+		}
+		limiter := throt.NewEndpointLimiter(throt.EndpointLimitArgs{RateSec: ept.RateSec, RateMin: ept.RateMin,
+			Windows: windows, Burst: ept.Burst, MaxConn: ept.MaxConn})
+		if limiter == nil {
+			continue
+		}
+		if limits == nil {
+			limits = EndpointLimits{}
+		}
+		limits[limitKey(ept.Address, ept.Port)] = &EndpointLimit{Name: EndpointName(ept), Limiter: limiter,
+			Wait: metrics.NewHistogram(metrics.NewUniformSample(1028))}
+	}
+
+	return limits, nil
+}
+
+// limitKey keys the endpoint limit by the address and port the request is sent to.
+func limitKey(address string, port uint) string {
+	return fmt.Sprintf("%s|%d", address, port)
+}
+
+// Get returns the endpoint limit, nil if the endpoint is not limited.
+func (l EndpointLimits) Get(address string, port uint) *EndpointLimit {
+	return l[limitKey(address, port)]
+}
+
+// Acquire waits for the endpoint limiter, release frees the concurrency slot.
+func (l *EndpointLimit) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	return l.Limiter.Acquire(ctx)
+}
+
+// Observe records the time the request waited in the endpoint queue in milliseconds.
+func (l *EndpointLimit) Observe(wait int64) {
+	if l != nil {
+		l.Wait.Update(wait)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("EndpointLimits", func() {
+	It("limits by the endpoint address.", func() {
+		defer GinkgoRecover()
+		limits, err := emul.NewEndpointLimits([]conf.Endpoint{
+			{Name: "prod", Address: "http://127.0.0.1:9001/TR", Type: conf.HTTPPost},
+			{Name: "staging", Address: "http://127.0.0.1:9002/TR", Type: conf.HTTPPost, Rate: "10/s,300/m", MaxConn: 2},
+			{Address: "localhost", Port: 8634, Type: conf.TCP, MaxConn: 1},
+		})
+		Expect(err).Should(BeNil())
+		Expect(limits).To(HaveLen(2))
+		Expect(limits.Get("http://127.0.0.1:9001/TR", 0)).To(BeNil())
+		Expect(limits.Get("http://127.0.0.1:9002/TR", 0).Name).To(Equal("staging"))
+		Expect(limits.Get("localhost", 8634).Name).To(Equal("localhost:8634"))
+
+		limit := limits.Get("localhost", 8634)
+		release, err := limit.Acquire(context.Background())
+		Expect(err).Should(BeNil())
+		release()
+		limit.Observe(5)
+		Expect(limit.Wait.Count()).To(Equal(int64(1)))
+	})
+	It("no endpoint limits.", func() {
+		defer GinkgoRecover()
+		limits, err := emul.NewEndpointLimits([]conf.Endpoint{{Address: "localhost", Port: 8634, Type: conf.TCP, Rate: "0/s"}})
+		Expect(err).Should(BeNil())
+		Expect(limits).To(BeNil())
+		limit := limits.Get("localhost", 8634)
+		release, err := limit.Acquire(context.Background())
+		Expect(err).Should(BeNil())
+		release()
+		limit.Observe(5)
+	})
+	It("invalid endpoint rate.", func() {
+		defer GinkgoRecover()
+		_, err := emul.NewEndpointLimits([]conf.Endpoint{{Name: "staging", Address: "localhost", Port: 8634, Type: conf.TCP, Rate: "10/fortnight"}})
+		Expect(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,118 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ManifestEntry request manifest record.
+type ManifestEntry struct {
+	Endpoint string            `yaml:"endpoint" json:"endpoint"`
+	Method   string            `yaml:"method" json:"method"`
+	Headers  map[string]string `yaml:"headers" json:"headers"`
+	File     string            `yaml:"file" json:"file"`
+	Body     string            `yaml:"body" json:"body"`
+	Weight   uint              `yaml:"weight" json:"weight"`
+	DelayMs  uint              `yaml:"delayMs" json:"delayMs"`
+	Expect   *Expect           `yaml:"expect" json:"expect"`
+	Tags     []string          `yaml:"tags" json:"tags"`
+}
+
+// ReadManifest reads YAML or JSONL manifest of requests.
+// Each request has either the body file or the inline body. Relative file paths are resolved
+// against the manifest file directory, zero weight defaults to one.
+func ReadManifest(path string) (requests []Request, err error) {
+	var content []byte
+	if content, err = ioutil.ReadFile(path); err != nil {
+		err = errors.Wrap(err, "ioutil.ReadFile")
+		return
+	}
+
+	var entries []ManifestEntry
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+		for n := 1; scanner.Scan(); n++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+
+			var entry ManifestEntry
+			if err = json.Unmarshal([]byte(text), &entry); err != nil {
+				err = errors.Wrapf(err, "manifest line %d", n)
+				return
+			}
+			entries = append(entries, entry)
+		}
+		if err = scanner.Err(); err != nil {
+			err = errors.Wrap(err, "scanner.Scan")
+			return
+		}
+	} else if err = yaml.Unmarshal(content, &entries); err != nil {
+		err = errors.Wrap(err, "yaml.Unmarshal")
+		return
+	}
+
+	dir := filepath.Dir(path)
+	for i, entry := range entries {
+		if entry.File == "" && entry.Body == "" {
+			err = fmt.Errorf("manifest request %d: missing file or body", i+1)
+			return
+		}
+		if err = entry.Expect.Compile(); err != nil {
+			err = errors.Wrapf(err, "manifest request %d", i+1)
+			return
+		}
+
+		req := Request{Endpoint: entry.Endpoint,
+			Method:  strings.ToUpper(entry.Method),
+			Headers: entry.Headers,
+			Weight:  entry.Weight,
+			DelayMs: entry.DelayMs,
+			Expect:  entry.Expect,
+			Tags:    entry.Tags}
+
+		if entry.File != "" {
+			req.FilePath = entry.File
+			if !filepath.IsAbs(entry.File) {
+				req.FilePath = filepath.Join(dir, entry.File)
+			}
+		} else {
+			req.Body = []byte(entry.Body)
+		}
+
+		if req.Weight == 0 {
+			req.Weight = 1
+		}
+
+		requests = append(requests, req)
+	}
+
+	if len(requests) == 0 {
+		err = errors.New("empty manifest")
+	}
+
+	return
+}
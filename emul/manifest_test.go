@@ -0,0 +1,131 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/net"
+)
+
+var _ = Describe("Manifest", func() {
+	var (
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "manifest")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	writeManifest := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).Should(Succeed())
+		return path
+	}
+
+	Describe("ReadManifest", func() {
+		Context("YAML file", func() {
+			It("requests with optional fields.", func() {
+				defer GinkgoRecover()
+				path := writeManifest("manifest.yml", `
+- file: balance.dat
+  endpoint: prod
+  method: put
+  headers:
+    X-Test: "1"
+  weight: 70
+  delayMs: 5
+  expect:
+    status: [200]
+  tags: [balance]
+- body: "inline request"
+`)
+
+				requests, err := emul.ReadManifest(path)
+
+				Expect(err).Should(BeNil())
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[0].FilePath).To(Equal(filepath.Join(dir, "balance.dat")))
+				Expect(requests[0].Endpoint).To(Equal("prod"))
+				Expect(requests[0].Method).To(Equal("PUT"))
+				Expect(requests[0].Headers).To(HaveKeyWithValue("X-Test", "1"))
+				Expect(requests[0].Weight).To(Equal(uint(70)))
+				Expect(requests[0].DelayMs).To(Equal(uint(5)))
+				Expect(requests[0].Expect.Status).To(Equal([]int{200}))
+				Expect(requests[0].Tags).To(Equal([]string{"balance"}))
+				Expect(requests[1].Body).To(Equal([]byte("inline request")))
+				Expect(requests[1].Weight).To(Equal(uint(1)))
+			})
+		})
+
+		Context("JSONL file", func() {
+			It("requests read line by line.", func() {
+				defer GinkgoRecover()
+				path := writeManifest("manifest.jsonl",
+					`{"file": "/tmp/transfer.dat", "weight": 5, "tags": ["transfer"]}`+"\n\n"+
+						`{"body": "ping", "endpoint": "1"}`+"\n")
+
+				requests, err := emul.ReadManifest(path)
+
+				Expect(err).Should(BeNil())
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[0].FilePath).To(Equal("/tmp/transfer.dat"))
+				Expect(requests[0].Weight).To(Equal(uint(5)))
+				Expect(requests[1].Endpoint).To(Equal("1"))
+			})
+
+			It("request without file or body is an error.", func() {
+				defer GinkgoRecover()
+				path := writeManifest("manifest.jsonl", `{"tags": ["empty"]}`+"\n")
+
+				_, err := emul.ReadManifest(path)
+
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+	})
+
+	Describe("Expect", func() {
+		Context("Check", func() {
+			It("status and body patterns.", func() {
+				defer GinkgoRecover()
+				expect := &emul.Expect{Status: []int{200, 201}, Match: []string{`"ok":\s*true`}}
+				Expect(expect.Compile()).Should(Succeed())
+
+				Expect(expect.Check(&net.Response{Status: 201, Body: []byte(`{"ok": true}`)}, 0)).Should(Succeed())
+				Expect(expect.Check(&net.Response{Status: 500, Body: []byte(`{"ok": true}`)}, 0)).ShouldNot(Succeed())
+				Expect(expect.Check(&net.Response{Status: 200, Body: []byte(`{"ok": false}`)}, 0)).ShouldNot(Succeed())
+			})
+
+			It("no expectations.", func() {
+				defer GinkgoRecover()
+				var expect *emul.Expect
+				Expect(expect.Check(&net.Response{Status: 500}, 0)).Should(Succeed())
+			})
+		})
+	})
+})
@@ -0,0 +1,167 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MixMode request selection mode.
+type MixMode int
+
+const (
+	// SeqMix cycles through requests in order.
+	SeqMix MixMode = iota
+	// WeightedMix selects requests at random by weight.
+	WeightedMix
+)
+
+func (m MixMode) String() string {
+	return [...]string{"seq", "weighted"}[m]
+}
+
+// ParseMixMode parses string to MixMode.
+func ParseMixMode(str string) (mode MixMode, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "", "seq":
+		return SeqMix, nil
+	case "weighted":
+		return WeightedMix, nil
+	}
+
+	return SeqMix, fmt.Errorf("unknown mix mode %q", str)
+}
+
+// Mix selects requests at random by weight and counts the achieved mix.
+type Mix struct {
+	names   []string
+	weights []float64
+	cum     []float64
+	counts  []uint64
+	total   uint64
+	rng     *rand.Rand
+	mu      sync.Mutex
+}
+
+// MixStat target and achieved share of the request.
+type MixStat struct {
+	Name     string
+	Count    uint64
+	Target   float64
+	Achieved float64
+}
+
+// NewMix creates new weighted mix of named requests using seeded random generator.
+func NewMix(names []string, weights []float64, seed int64) (mix *Mix, err error) {
+	if len(names) != len(weights) {
+		return nil, errors.New("mix names and weights do not match")
+	}
+
+	mix = &Mix{names: names, weights: weights, counts: make([]uint64, len(names)), rng: rand.New(rand.NewSource(seed))}
+	var sum float64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("negative mix weight %v", w)
+		}
+		sum += w
+		mix.cum = append(mix.cum, sum)
+	}
+	if sum == 0 {
+		return nil, errors.New("mix weights sum to zero")
+	}
+
+	return mix, nil
+}
+
+// Next returns the index of the next request.
+func (mix *Mix) Next() int {
+	mix.mu.Lock()
+	defer mix.mu.Unlock()
+
+	r := mix.rng.Float64() * mix.cum[len(mix.cum)-1]
+	i := sort.Search(len(mix.cum), func(i int) bool { return mix.cum[i] > r })
+	if i == len(mix.cum) {
+		i--
+	}
+	mix.counts[i]++
+	mix.total++
+
+	return i
+}
+
+// Report returns target and achieved mix in percent for each request.
+func (mix *Mix) Report() (stats []MixStat) {
+	mix.mu.Lock()
+	defer mix.mu.Unlock()
+
+	sum := mix.cum[len(mix.cum)-1]
+	for i, name := range mix.names {
+		stat := MixStat{Name: name, Count: mix.counts[i], Target: 100 * mix.weights[i] / sum}
+		if mix.total > 0 {
+			stat.Achieved = 100 * float64(mix.counts[i]) / float64(mix.total)
+		}
+		stats = append(stats, stat)
+	}
+
+	return
+}
+
+// ReadWeights reads weights sidecar file mapping file name patterns to weights.
+func ReadWeights(path string) (weights yaml.MapSlice, err error) {
+	var content []byte
+	if content, err = ioutil.ReadFile(path); err != nil {
+		err = errors.Wrap(err, "ioutil.ReadFile")
+		return
+	}
+	if err = yaml.Unmarshal(content, &weights); err != nil {
+		err = errors.Wrap(err, "yaml.Unmarshal")
+	}
+
+	return
+}
+
+// FileWeight returns the weight of the first pattern matching the file name, zero if none matches.
+func FileWeight(weights yaml.MapSlice, filePath string) (weight float64, err error) {
+	name := filepath.Base(filePath)
+	for _, item := range weights {
+		pattern := fmt.Sprint(item.Key)
+		var matched bool
+		if matched, err = filepath.Match(pattern, name); err != nil {
+			err = errors.Wrap(err, "filepath.Match")
+			return
+		}
+		if matched || pattern == filePath {
+			switch w := item.Value.(type) {
+			case int:
+				return float64(w), nil
+			case float64:
+				return w, nil
+			default:
+				return 0, fmt.Errorf("invalid weight %v for %q", item.Value, pattern)
+			}
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("Mix", func() {
+	Describe("ParseMixMode", func() {
+		It("known modes.", func() {
+			defer GinkgoRecover()
+			Expect(emul.ParseMixMode("")).To(Equal(emul.SeqMix))
+			Expect(emul.ParseMixMode("Weighted")).To(Equal(emul.WeightedMix))
+			_, err := emul.ParseMixMode("random")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("NewMix", func() {
+		Context("weighted selection", func() {
+			It("achieves the target mix.", func() {
+				defer GinkgoRecover()
+				mix, err := emul.NewMix([]string{"balance", "history", "transfer"}, []float64{70, 25, 5}, 42)
+				Expect(err).Should(BeNil())
+				for i := 0; i < 10000; i++ {
+					mix.Next()
+				}
+				stats := mix.Report()
+				Expect(stats).To(HaveLen(3))
+				Expect(stats[0].Target).To(BeNumerically("~", 70, 0.001))
+				Expect(stats[0].Achieved).To(BeNumerically("~", 70, 2))
+				Expect(stats[2].Achieved).To(BeNumerically("~", 5, 1))
+			})
+			It("is reproducible with the same seed.", func() {
+				defer GinkgoRecover()
+				a, _ := emul.NewMix([]string{"a", "b"}, []float64{1, 1}, 7)
+				b, _ := emul.NewMix([]string{"a", "b"}, []float64{1, 1}, 7)
+				for i := 0; i < 100; i++ {
+					Expect(a.Next()).To(Equal(b.Next()))
+				}
+			})
+			It("never selects zero weight.", func() {
+				defer GinkgoRecover()
+				mix, _ := emul.NewMix([]string{"a", "b"}, []float64{0, 1}, 1)
+				for i := 0; i < 100; i++ {
+					Expect(mix.Next()).To(Equal(1))
+				}
+			})
+		})
+		Context("invalid weights", func() {
+			It("returns an error.", func() {
+				defer GinkgoRecover()
+				_, err := emul.NewMix([]string{"a"}, []float64{0}, 1)
+				Expect(err).ShouldNot(BeNil())
+				_, err = emul.NewMix([]string{"a"}, []float64{1, 2}, 1)
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+	})
+
+	Describe("ReadWeights", func() {
+		It("first matching pattern wins.", func() {
+			defer GinkgoRecover()
+			dir, err := ioutil.TempDir("", "weights")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "weights.yml")
+			Expect(ioutil.WriteFile(path, []byte("balance*.dat: 70\ntransfer.dat: 5\n\"*.dat\": 0.5\n"), 0644)).Should(Succeed())
+			weights, err := emul.ReadWeights(path)
+			Expect(err).Should(BeNil())
+
+			Expect(emul.FileWeight(weights, "/data/balance01.dat")).To(Equal(70.0))
+			Expect(emul.FileWeight(weights, "/data/transfer.dat")).To(Equal(5.0))
+			Expect(emul.FileWeight(weights, "/data/other.dat")).To(Equal(0.5))
+			Expect(emul.FileWeight(weights, "/data/other.txt")).To(Equal(0.0))
+		})
+	})
+})
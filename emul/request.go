@@ -14,9 +14,22 @@
 
 package emul
 
+import "time"
+
 // Request - emul request implementation.
 type Request struct {
 	SesID    string
 	ReqID    uint64
 	FilePath string
+	Due      time.Time
+	Endpoint string
+	Method   string
+	Path     string
+	Headers  map[string]string
+	Body     []byte
+	Weight   uint
+	DelayMs  uint
+	Expect   *Expect
+	Tags     []string
+	Done     func(err error)
 }
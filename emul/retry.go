@@ -0,0 +1,192 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/alexstov/sling/net"
+	"github.com/pkg/errors"
+)
+
+// RetryClass retryable transport error class.
+type RetryClass int
+
+const (
+	// TimeoutRetry connection or response timeout.
+	TimeoutRetry RetryClass = iota
+	// RefusedRetry connection refused.
+	RefusedRetry
+	// ResetRetry connection reset or broken pipe.
+	ResetRetry
+	// EOFRetry connection closed before the response.
+	EOFRetry
+)
+
+func (c RetryClass) String() string {
+	return [...]string{"timeout", "refused", "reset", "eof"}[c]
+}
+
+// Retry failed request retry policy and retry statistics.
+type Retry struct {
+	Attempts  uint
+	Base      time.Duration
+	Cap       time.Duration
+	Jitter    float64
+	Classes   []RetryClass
+	Statuses  []int
+	FirstTry  int64
+	Recovered int64
+	Failed    int64
+	Retries   int64
+	mu        sync.Mutex
+	rng       *rand.Rand
+}
+
+// NewRetry creates retry policy retrying on the error classes and status codes, nil if
+// the requests are not retried.
+func NewRetry(attempts uint, base, cap time.Duration, jitter float64, on []string, seed int64) (retry *Retry, err error) {
+	if attempts <= 1 {
+		return nil, nil
+	}
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("invalid retry jitter %v, expected fraction 0..1", jitter)
+	}
+	if cap < base {
+		cap = base
+	}
+
+	retry = &Retry{Attempts: attempts, Base: base, Cap: cap, Jitter: jitter, rng: rand.New(rand.NewSource(seed))}
+	for _, item := range on {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch item {
+		case "":
+		case "timeout":
+			retry.Classes = append(retry.Classes, TimeoutRetry)
+		case "refused":
+			retry.Classes = append(retry.Classes, RefusedRetry)
+		case "reset":
+			retry.Classes = append(retry.Classes, ResetRetry)
+		case "eof":
+			retry.Classes = append(retry.Classes, EOFRetry)
+		default:
+			var status int
+			if status, err = strconv.Atoi(item); err != nil || status < 100 || status > 599 {
+				return nil, fmt.Errorf("invalid retry condition %q, expected timeout, refused, reset, eof or status code", item)
+			}
+			retry.Statuses = append(retry.Statuses, status)
+		}
+	}
+
+	return retry, nil
+}
+
+// ClassifyError returns the transport error class, false if the error is not classified.
+func ClassifyError(err error) (RetryClass, bool) {
+	var timeout interface{ Timeout() bool }
+	switch {
+	case err == nil:
+		return 0, false
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return RefusedRetry, true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ResetRetry, true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return EOFRetry, true
+	case errors.As(err, &timeout) && timeout.Timeout():
+		return TimeoutRetry, true
+	}
+
+	return 0, false
+}
+
+// Retryable reports whether the request attempt with the response or error is retried.
+func (r *Retry) Retryable(res *net.Response, err error) bool {
+	if r == nil {
+		return false
+	}
+
+	if err != nil {
+		class, ok := ClassifyError(err)
+		if !ok {
+			return false
+		}
+		for _, c := range r.Classes {
+			if c == class {
+				return true
+			}
+		}
+		return false
+	}
+	if res != nil {
+		for _, status := range r.Statuses {
+			if status == res.Status {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Next returns the delay before the next attempt following the failed attempt, false
+// if the attempts are exhausted. The backoff doubles from the base every attempt up to
+// the cap and is reduced by up to the jitter fraction.
+func (r *Retry) Next(attempt uint) (delay time.Duration, ok bool) {
+	if r == nil || attempt >= r.Attempts {
+		return 0, false
+	}
+
+	delay = r.Base
+	for i := uint(1); i < attempt && delay < r.Cap; i++ {
+		delay *= 2
+	}
+	if delay > r.Cap {
+		delay = r.Cap
+	}
+	if r.Jitter > 0 {
+		r.mu.Lock()
+		delay -= time.Duration(float64(delay) * r.Jitter * r.rng.Float64())
+		r.mu.Unlock()
+	}
+	atomic.AddInt64(&r.Retries, 1)
+
+	return delay, true
+}
+
+// Record records the request outcome after the attempts, first-try success is counted
+// separately from success after retries and from failure.
+func (r *Retry) Record(attempts uint, ok bool) {
+	if r == nil {
+		return
+	}
+
+	switch {
+	case ok && attempts <= 1:
+		atomic.AddInt64(&r.FirstTry, 1)
+	case ok:
+		atomic.AddInt64(&r.Recovered, 1)
+	default:
+		atomic.AddInt64(&r.Failed, 1)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"io"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/net"
+)
+
+// timeoutError timed out transport error.
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+var _ = Describe("Retry", func() {
+	Describe("NewRetry", func() {
+		It("error classes and status codes.", func() {
+			defer GinkgoRecover()
+			retry, err := emul.NewRetry(3, 100*time.Millisecond, time.Second, 0.2, []string{"timeout", " Reset", "503"}, 1)
+			Expect(err).Should(BeNil())
+			Expect(retry.Classes).To(Equal([]emul.RetryClass{emul.TimeoutRetry, emul.ResetRetry}))
+			Expect(retry.Statuses).To(Equal([]int{503}))
+		})
+		It("single attempt does not retry.", func() {
+			defer GinkgoRecover()
+			retry, err := emul.NewRetry(1, time.Millisecond, time.Second, 0, []string{"timeout"}, 1)
+			Expect(err).Should(BeNil())
+			Expect(retry).To(BeNil())
+			Expect(retry.Retryable(nil, timeoutError{})).To(BeFalse())
+			_, ok := retry.Next(1)
+			Expect(ok).To(BeFalse())
+		})
+		It("invalid policy.", func() {
+			defer GinkgoRecover()
+			_, err := emul.NewRetry(3, time.Millisecond, time.Second, 0, []string{"sometimes"}, 1)
+			Expect(err).ShouldNot(BeNil())
+			_, err = emul.NewRetry(3, time.Millisecond, time.Second, 0, []string{"700"}, 1)
+			Expect(err).ShouldNot(BeNil())
+			_, err = emul.NewRetry(3, time.Millisecond, time.Second, 1.5, nil, 1)
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("ClassifyError", func() {
+		It("wrapped transport errors.", func() {
+			defer GinkgoRecover()
+			for err, class := range map[error]emul.RetryClass{
+				errors.Wrap(syscall.ECONNREFUSED, "dial"):  emul.RefusedRetry,
+				errors.Wrap(syscall.ECONNRESET, "read"):    emul.ResetRetry,
+				errors.Wrap(io.ErrUnexpectedEOF, "read"):   emul.EOFRetry,
+				errors.Wrap(timeoutError{}, "http.Client"): emul.TimeoutRetry,
+			} {
+				c, ok := emul.ClassifyError(err)
+				Expect(ok).To(BeTrue())
+				Expect(c).To(Equal(class))
+			}
+			_, ok := emul.ClassifyError(errors.New("bad request file"))
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Retryable", func() {
+		It("matches error classes and statuses.", func() {
+			defer GinkgoRecover()
+			retry, _ := emul.NewRetry(3, time.Millisecond, time.Second, 0, []string{"refused", "503"}, 1)
+			Expect(retry.Retryable(&net.Response{}, syscall.ECONNREFUSED)).To(BeTrue())
+			Expect(retry.Retryable(&net.Response{}, syscall.ECONNRESET)).To(BeFalse())
+			Expect(retry.Retryable(&net.Response{Status: 503}, nil)).To(BeTrue())
+			Expect(retry.Retryable(&net.Response{Status: 500}, nil)).To(BeFalse())
+		})
+	})
+
+	Describe("Next", func() {
+		It("exponential backoff capped.", func() {
+			defer GinkgoRecover()
+			retry, _ := emul.NewRetry(6, 100*time.Millisecond, 500*time.Millisecond, 0, nil, 1)
+			var delays []time.Duration
+			for attempt := uint(1); ; attempt++ {
+				delay, ok := retry.Next(attempt)
+				if !ok {
+					break
+				}
+				delays = append(delays, delay)
+			}
+			Expect(delays).To(Equal([]time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+				500 * time.Millisecond, 500 * time.Millisecond}))
+			Expect(retry.Retries).To(Equal(int64(5)))
+		})
+		It("jitter reduces the backoff.", func() {
+			defer GinkgoRecover()
+			retry, _ := emul.NewRetry(2, 100*time.Millisecond, time.Second, 0.5, nil, 1)
+			for i := 0; i < 100; i++ {
+				delay, ok := retry.Next(1)
+				Expect(ok).To(BeTrue())
+				Expect(delay).To(BeNumerically(">", 50*time.Millisecond))
+				Expect(delay).To(BeNumerically("<=", 100*time.Millisecond))
+			}
+		})
+	})
+
+	Describe("Record", func() {
+		It("first-try and eventual success.", func() {
+			defer GinkgoRecover()
+			retry, _ := emul.NewRetry(3, time.Millisecond, time.Second, 0, nil, 1)
+			retry.Record(1, true)
+			retry.Record(1, true)
+			retry.Record(3, true)
+			retry.Record(3, false)
+			Expect([]int64{retry.FirstTry, retry.Recovered, retry.Failed}).To(Equal([]int64{2, 1, 1}))
+		})
+	})
+})
@@ -0,0 +1,252 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexstov/sling/throt"
+	"github.com/rcrowley/go-metrics"
+	"golang.org/x/time/rate"
+)
+
+// SearchMode capacity search mode.
+type SearchMode int
+
+const (
+	// NoSearch capacity search is off.
+	NoSearch SearchMode = iota
+	// StepSearch steps the rate up until the SLO is violated.
+	StepSearch
+	// BisectSearch bisects the rate range.
+	BisectSearch
+)
+
+func (m SearchMode) String() string {
+	return [...]string{"", "step", "bisect"}[m]
+}
+
+// ParseSearchMode parses string to SearchMode.
+func ParseSearchMode(str string) (mode SearchMode, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "":
+		return NoSearch, nil
+	case "step":
+		return StepSearch, nil
+	case "bisect":
+		return BisectSearch, nil
+	}
+
+	return NoSearch, fmt.Errorf("unknown search mode %q", str)
+}
+
+// SLO service level objective, latency percentiles and error rate.
+type SLO struct {
+	Percentiles []float64
+	Latencies   []time.Duration
+	MaxErrRate  float64
+}
+
+var sloExpr = regexp.MustCompile(`^(p[0-9.]+|err)\s*<\s*(.+)$`)
+
+// ParseSLO parses SLO expression, e.g. p99<200ms,p95<100ms,err<1%.
+func ParseSLO(expr string) (slo *SLO, err error) {
+	slo = &SLO{MaxErrRate: 1}
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		match := sloExpr.FindStringSubmatch(item)
+		if match == nil {
+			return nil, fmt.Errorf("invalid SLO %q", item)
+		}
+
+		if match[1] == "err" {
+			pct := strings.HasSuffix(match[2], "%")
+			if slo.MaxErrRate, err = strconv.ParseFloat(strings.TrimSuffix(match[2], "%"), 64); err != nil {
+				return nil, fmt.Errorf("invalid SLO error rate %q", item)
+			}
+			if pct {
+				slo.MaxErrRate /= 100
+			}
+			continue
+		}
+
+		var p float64
+		var lat time.Duration
+		if p, err = strconv.ParseFloat(match[1][1:], 64); err != nil || p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("invalid SLO percentile %q", item)
+		}
+		if lat, err = time.ParseDuration(match[2]); err != nil {
+			return nil, fmt.Errorf("invalid SLO latency %q", item)
+		}
+		slo.Percentiles = append(slo.Percentiles, p/100)
+		slo.Latencies = append(slo.Latencies, lat)
+	}
+	if len(slo.Percentiles) == 0 && slo.MaxErrRate == 1 {
+		return nil, fmt.Errorf("empty SLO %q", expr)
+	}
+
+	return slo, nil
+}
+
+// SearchStep capacity search step evidence.
+type SearchStep struct {
+	Rate     uint
+	Achieved float64
+	Count    int64
+	Errors   int64
+	ErrRate  float64
+	Latency  []time.Duration
+	Pass     bool
+	Reason   string
+}
+
+// Search finds the highest rate meeting the SLO.
+type Search struct {
+	Mode  SearchMode
+	Min   uint
+	Max   uint
+	Step  uint
+	Hold  time.Duration
+	SLO   *SLO
+	Steps []SearchStep
+	histo metrics.Histogram
+	errs  int64
+	mu    sync.Mutex
+}
+
+// Observe records the request latency in milliseconds and the outcome for the current step.
+func (s *Search) Observe(elapsed int64, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.histo == nil {
+		return
+	}
+	s.histo.Update(elapsed)
+	if err != nil {
+		s.errs++
+	}
+}
+
+// Run searches the rate adjusting the limiter and returns the highest sustainable rate, zero if none.
+func (s *Search) Run(ctx context.Context, limiter throt.Limiter) (best uint, err error) {
+	if s.Step == 0 {
+		s.Step = 1
+	}
+
+	switch s.Mode {
+	case StepSearch:
+		for r := s.Min; r <= s.Max; r += s.Step {
+			var step SearchStep
+			if step, err = s.try(ctx, limiter, r); err != nil || !step.Pass {
+				return
+			}
+			best = r
+		}
+	case BisectSearch:
+		lo, hi := s.Min, s.Max
+		var step SearchStep
+		if step, err = s.try(ctx, limiter, lo); err != nil || !step.Pass {
+			return
+		}
+		best = lo
+		if step, err = s.try(ctx, limiter, hi); err != nil || step.Pass {
+			if err == nil {
+				best = hi
+			}
+			return
+		}
+		for hi-lo > s.Step {
+			mid := lo + (hi-lo)/2
+			if step, err = s.try(ctx, limiter, mid); err != nil {
+				return
+			}
+			if step.Pass {
+				lo, best = mid, mid
+			} else {
+				hi = mid
+			}
+		}
+	}
+
+	return
+}
+
+// try holds the rate and evaluates the step against the SLO.
+func (s *Search) try(ctx context.Context, limiter throt.Limiter, r uint) (step SearchStep, err error) {
+	s.mu.Lock()
+	s.histo = metrics.NewHistogram(metrics.NewUniformSample(1028))
+	s.errs = 0
+	s.mu.Unlock()
+
+	limiter.SetLimit(rate.Limit(r))
+
+	select {
+	case <-time.After(s.Hold):
+	case <-ctx.Done():
+		return step, ctx.Err()
+	}
+
+	s.mu.Lock()
+	histo, errs := s.histo, s.errs
+	s.histo = nil
+	s.mu.Unlock()
+
+	step = s.evaluate(r, histo, errs)
+	s.Steps = append(s.Steps, step)
+	return step, nil
+}
+
+// evaluate checks the step statistics against the SLO.
+func (s *Search) evaluate(r uint, histo metrics.Histogram, errs int64) (step SearchStep) {
+	step = SearchStep{Rate: r, Count: histo.Count(), Errors: errs, Pass: true}
+	step.Achieved = float64(step.Count) / s.Hold.Seconds()
+	if step.Count == 0 {
+		step.Pass, step.Reason = false, "no requests completed"
+		return
+	}
+
+	step.ErrRate = float64(errs) / float64(step.Count)
+	for i, ms := range histo.Percentiles(s.SLO.Percentiles) {
+		lat := time.Duration(ms * float64(time.Millisecond))
+		step.Latency = append(step.Latency, lat)
+		if step.Pass && lat > s.SLO.Latencies[i] {
+			step.Pass = false
+			step.Reason = fmt.Sprintf("p%v %v > %v", s.SLO.Percentiles[i]*100, lat, s.SLO.Latencies[i])
+		}
+	}
+	if step.Pass && step.ErrRate > s.SLO.MaxErrRate {
+		step.Pass = false
+		step.Reason = fmt.Sprintf("error rate %.2f%% > %.2f%%", step.ErrRate*100, s.SLO.MaxErrRate*100)
+	}
+	if step.Pass && step.Achieved < 0.9*float64(r) {
+		step.Pass = false
+		step.Reason = fmt.Sprintf("achieved %.1f/s < 90%% of target", step.Achieved)
+	}
+
+	return
+}
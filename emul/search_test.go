@@ -0,0 +1,127 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/mock"
+)
+
+var _ = Describe("Search", func() {
+	var (
+		t           testing.T
+		mockCtrl    *gomock.Controller
+		mockLimiter *mock.MockLimiter
+	)
+
+	// newSearch creates search with the latency in milliseconds equal to the rate.
+	newSearch := func(mode emul.SearchMode, step uint) *emul.Search {
+		slo, err := emul.ParseSLO("p99<35ms,err<1%")
+		Expect(err).Should(BeNil())
+		search := &emul.Search{Mode: mode, Min: 10, Max: 100, Step: step, Hold: 50 * time.Millisecond, SLO: slo}
+		mockLimiter.EXPECT().SetLimit(gomock.Any()).Do(func(limit rate.Limit) {
+			for i := 0; i <= int(limit)/20; i++ {
+				search.Observe(int64(limit), nil)
+			}
+		}).AnyTimes()
+		return search
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(&t)
+		mockLimiter = mock.NewMockLimiter(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("ParseSLO", func() {
+		It("percentile latencies and error rate.", func() {
+			defer GinkgoRecover()
+			slo, err := emul.ParseSLO("p99<200ms, p95.5<100ms,err<1%")
+			Expect(err).Should(BeNil())
+			Expect(slo.Percentiles).To(Equal([]float64{0.99, 0.955}))
+			Expect(slo.Latencies).To(Equal([]time.Duration{200 * time.Millisecond, 100 * time.Millisecond}))
+			Expect(slo.MaxErrRate).To(BeNumerically("~", 0.01))
+		})
+		It("invalid SLO.", func() {
+			defer GinkgoRecover()
+			for _, expr := range []string{"", "p99>200ms", "p100<1s", "p99<fast", "err<some"} {
+				_, err := emul.ParseSLO(expr)
+				Expect(err).ShouldNot(BeNil(), expr)
+			}
+		})
+	})
+
+	Describe("ParseSearchMode", func() {
+		It("search modes.", func() {
+			defer GinkgoRecover()
+			Expect(emul.ParseSearchMode("")).To(Equal(emul.NoSearch))
+			Expect(emul.ParseSearchMode("Step")).To(Equal(emul.StepSearch))
+			Expect(emul.ParseSearchMode("bisect")).To(Equal(emul.BisectSearch))
+			_, err := emul.ParseSearchMode("binary")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("Run", func() {
+		It("steps the rate until the SLO is violated.", func() {
+			defer GinkgoRecover()
+			search := newSearch(emul.StepSearch, 10)
+			best, err := search.Run(context.Background(), mockLimiter)
+			Expect(err).Should(BeNil())
+			Expect(best).To(Equal(uint(30)))
+			Expect(search.Steps).To(HaveLen(4))
+			Expect(search.Steps[3].Pass).To(BeFalse())
+			Expect(search.Steps[3].Reason).To(ContainSubstring("p99"))
+		})
+		It("bisects the rate range.", func() {
+			defer GinkgoRecover()
+			search := newSearch(emul.BisectSearch, 5)
+			best, err := search.Run(context.Background(), mockLimiter)
+			Expect(err).Should(BeNil())
+			Expect(best).To(Equal(uint(32)))
+			Expect(len(search.Steps)).To(BeNumerically("<", 10))
+		})
+		It("fails the step without requests.", func() {
+			defer GinkgoRecover()
+			slo, _ := emul.ParseSLO("err<1%")
+			search := &emul.Search{Mode: emul.StepSearch, Min: 10, Max: 20, Step: 10, Hold: 10 * time.Millisecond, SLO: slo}
+			mockLimiter.EXPECT().SetLimit(rate.Limit(10))
+			best, err := search.Run(context.Background(), mockLimiter)
+			Expect(err).Should(BeNil())
+			Expect(best).To(BeZero())
+			Expect(search.Steps[0].Reason).To(Equal("no requests completed"))
+		})
+		It("stops on cancel.", func() {
+			defer GinkgoRecover()
+			search := newSearch(emul.StepSearch, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := search.Run(ctx, mockLimiter)
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+})
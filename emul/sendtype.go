@@ -26,8 +26,16 @@ const (
 	RepeatReq
 	// MultiReq multiple requests.
 	MultiReq
+	// ReplayReq requests replayed from the recorded timeline.
+	ReplayReq
+	// ManifestReq requests listed in the manifest.
+	ManifestReq
+	// WatchReq requests spooled to the watched directories.
+	WatchReq
+	// InputReq requests read from stdin or a named pipe.
+	InputReq
 )
 
 func (s SendType) String() string {
-	return [...]string{"UnknownReq", "SingleReq", "RepeatReq", "MultiReq"}[s]
+	return [...]string{"UnknownReq", "SingleReq", "RepeatReq", "MultiReq", "ReplayReq", "ManifestReq", "WatchReq", "InputReq"}[s]
 }
@@ -0,0 +1,204 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/net"
+	"github.com/alexstov/sling/util"
+	"github.com/rcrowley/go-metrics"
+)
+
+// CompareMode shadow response comparison mode.
+type CompareMode int
+
+const (
+	// ByteCompare compares the responses byte by byte.
+	ByteCompare CompareMode = iota
+	// JSONCompare compares the normalized JSON responses.
+	JSONCompare
+	// IgnoreCompare compares the normalized JSON responses without the ignored fields.
+	IgnoreCompare
+)
+
+func (m CompareMode) String() string {
+	return [...]string{"bytes", "json", "ignore"}[m]
+}
+
+// ParseCompareMode parses string to CompareMode.
+func ParseCompareMode(str string) (mode CompareMode, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "bytes", "":
+		return ByteCompare, nil
+	case "json":
+		return JSONCompare, nil
+	case "ignore":
+		return IgnoreCompare, nil
+	}
+
+	return ByteCompare, fmt.Errorf("unknown compare mode %q", str)
+}
+
+// ShadowDiff primary and secondary response difference.
+type ShadowDiff struct {
+	ReqID    uint64
+	FilePath string
+	Paths    []string
+	Detail   string
+}
+
+// ShadowStat mismatch count by the response path.
+type ShadowStat struct {
+	Path  string
+	Count int
+}
+
+// ShadowMaxDiffs number of the first mismatches kept with their details, the later ones are only counted.
+const ShadowMaxDiffs = 100
+
+// Shadow sends every request to the secondary endpoint and compares the responses.
+type Shadow struct {
+	Endpoint   conf.Endpoint
+	Client     net.Client
+	Compare    CompareMode
+	Ignore     []util.JSONPath
+	Histogram  metrics.Histogram
+	Diffs      []ShadowDiff
+	Mismatched int
+	Compared   int
+	Failed     int
+	paths      map[string]int
+	mu         sync.Mutex
+}
+
+// NewShadow creates shadow comparing the responses in the mode, ignore lists JSON paths of the ignored fields.
+func NewShadow(ept conf.Endpoint, compare CompareMode, ignore []string) (shadow *Shadow, err error) {
+	shadow = &Shadow{Endpoint: ept, Compare: compare, paths: make(map[string]int)}
+	for _, path := range ignore {
+		var p util.JSONPath
+		if p, err = util.ParseJSONPath(path); err != nil {
+			return nil, err
+		}
+		shadow.Ignore = append(shadow.Ignore, p)
+	}
+
+	return shadow, nil
+}
+
+// Diff compares the primary and secondary responses, nil if they match.
+func (s *Shadow) Diff(primary, secondary *net.Response) (diff *ShadowDiff) {
+	diff = &ShadowDiff{}
+	if primary.Status != secondary.Status {
+		diff.Paths = append(diff.Paths, "status")
+		diff.Detail = fmt.Sprintf("status %d != %d", primary.Status, secondary.Status)
+	}
+
+	if s.Compare == ByteCompare {
+		if !bytes.Equal(primary.Body, secondary.Body) {
+			diff.Paths = append(diff.Paths, "body")
+			diff.Detail = joinDetail(diff.Detail, byteDetail(primary.Body, secondary.Body))
+		}
+	} else {
+		a, errA := util.DecodeJSON(primary.Body)
+		b, errB := util.DecodeJSON(secondary.Body)
+		switch {
+		case errA != nil || errB != nil:
+			// Not JSON, fall back to byte comparison.
+			if !bytes.Equal(primary.Body, secondary.Body) {
+				diff.Paths = append(diff.Paths, "body")
+				diff.Detail = joinDetail(diff.Detail, "invalid JSON, "+byteDetail(primary.Body, secondary.Body))
+			}
+		default:
+			if s.Compare == IgnoreCompare {
+				for _, p := range s.Ignore {
+					p.Delete(a)
+					p.Delete(b)
+				}
+			}
+			if paths := util.DiffJSON(a, b); len(paths) > 0 {
+				diff.Paths = append(diff.Paths, paths...)
+				diff.Detail = joinDetail(diff.Detail, "fields "+strings.Join(paths, ","))
+			}
+		}
+	}
+
+	if len(diff.Paths) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// Record records the comparison outcome, diff is nil for matching responses.
+// The first ShadowMaxDiffs mismatches are kept, all are counted by the response path.
+func (s *Shadow) Record(diff *ShadowDiff, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.Failed++
+		return
+	}
+	s.Compared++
+	if diff == nil {
+		return
+	}
+	s.Mismatched++
+	for _, path := range diff.Paths {
+		s.paths[path]++
+	}
+	if len(s.Diffs) < ShadowMaxDiffs {
+		s.Diffs = append(s.Diffs, *diff)
+	}
+}
+
+// Summary returns the mismatch count by the response path, most frequent first.
+func (s *Shadow) Summary() (stats []ShadowStat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for path, count := range s.paths {
+		stats = append(stats, ShadowStat{Path: path, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Path < stats[j].Path
+	})
+
+	return stats
+}
+
+// byteDetail describes the first difference of the bodies.
+func byteDetail(a, b []byte) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return fmt.Sprintf("bodies differ at byte %d, length %d != %d", i, len(a), len(b))
+}
+
+func joinDetail(detail, more string) string {
+	if detail == "" {
+		return more
+	}
+	return detail + "; " + more
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/net"
+)
+
+var _ = Describe("Shadow", func() {
+	primary := &net.Response{Status: 200, Body: []byte(`{"id": 1, "ts": "10:00", "items": [1, 2]}`)}
+
+	newShadow := func(mode string, ignore ...string) *emul.Shadow {
+		compare, err := emul.ParseCompareMode(mode)
+		Expect(err).Should(BeNil())
+		shadow, err := emul.NewShadow(conf.Endpoint{Name: "new"}, compare, ignore)
+		Expect(err).Should(BeNil())
+		return shadow
+	}
+
+	Describe("Diff", func() {
+		It("byte-exact comparison.", func() {
+			defer GinkgoRecover()
+			shadow := newShadow("bytes")
+			Expect(shadow.Diff(primary, primary)).To(BeNil())
+			diff := shadow.Diff(primary, &net.Response{Status: 200, Body: []byte(`{"id":1,"ts":"10:00","items":[1,2]}`)})
+			Expect(diff.Paths).To(Equal([]string{"body"}))
+			Expect(diff.Detail).To(ContainSubstring("byte 6"))
+		})
+		It("normalized JSON comparison.", func() {
+			defer GinkgoRecover()
+			shadow := newShadow("json")
+			Expect(shadow.Diff(primary, &net.Response{Status: 200, Body: []byte(`{"items":[1,2],"ts":"10:00","id":1}`)})).To(BeNil())
+			diff := shadow.Diff(primary, &net.Response{Status: 500, Body: []byte(`{"items":[1,3],"ts":"10:01","id":1}`)})
+			Expect(diff.Paths).To(Equal([]string{"status", "$.items[1]", "$.ts"}))
+		})
+		It("ignored fields comparison.", func() {
+			defer GinkgoRecover()
+			shadow := newShadow("ignore", "$.ts")
+			Expect(shadow.Diff(primary, &net.Response{Status: 200, Body: []byte(`{"items":[1,2],"ts":"10:01","id":1}`)})).To(BeNil())
+			diff := shadow.Diff(primary, &net.Response{Status: 200, Body: []byte(`not json`)})
+			Expect(diff.Detail).To(ContainSubstring("invalid JSON"))
+		})
+	})
+
+	Describe("Summary", func() {
+		It("mismatch count by the response path.", func() {
+			defer GinkgoRecover()
+			shadow := newShadow("json")
+			shadow.Record(&emul.ShadowDiff{Paths: []string{"$.ts", "status"}}, nil)
+			shadow.Record(&emul.ShadowDiff{Paths: []string{"$.ts"}}, nil)
+			shadow.Record(nil, nil)
+			shadow.Record(nil, errors.New("refused"))
+			Expect(shadow.Compared).To(Equal(3))
+			Expect(shadow.Failed).To(Equal(1))
+			Expect(shadow.Summary()).To(Equal([]emul.ShadowStat{{Path: "$.ts", Count: 2}, {Path: "status", Count: 1}}))
+		})
+
+		It("keeps the first mismatches and counts all.", func() {
+			defer GinkgoRecover()
+			shadow := newShadow("json")
+			for i := 0; i < emul.ShadowMaxDiffs+5; i++ {
+				shadow.Record(&emul.ShadowDiff{ReqID: uint64(i + 1), Paths: []string{"$.ts"}}, nil)
+			}
+			Expect(shadow.Diffs).To(HaveLen(emul.ShadowMaxDiffs))
+			Expect(shadow.Diffs[emul.ShadowMaxDiffs-1].ReqID).To(Equal(uint64(emul.ShadowMaxDiffs)))
+			Expect(shadow.Mismatched).To(Equal(emul.ShadowMaxDiffs + 5))
+			Expect(shadow.Summary()).To(Equal([]emul.ShadowStat{{Path: "$.ts", Count: emul.ShadowMaxDiffs + 5}}))
+		})
+	})
+
+	Describe("ParseCompareMode", func() {
+		It("unknown mode.", func() {
+			defer GinkgoRecover()
+			_, err := emul.ParseCompareMode("fuzzy")
+			Expect(err).ShouldNot(BeNil())
+			_, err = emul.NewShadow(conf.Endpoint{}, emul.IgnoreCompare, []string{"$.a[x]"})
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,219 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TimelineEntry recorded request file and its offset from the start of the recording.
+// The HTTP requests recorded by sling record have the method, request URI and headers.
+type TimelineEntry struct {
+	Offset   time.Duration
+	FilePath string
+	Method   string
+	Path     string
+	Headers  map[string]string
+}
+
+// timelineLine JSONL timeline record.
+type timelineLine struct {
+	Offset    interface{}       `json:"offset"`
+	Timestamp string            `json:"timestamp"`
+	File      string            `json:"file"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers"`
+}
+
+// Request returns the request replaying the timeline entry.
+func (entry TimelineEntry) Request() Request {
+	return Request{FilePath: entry.FilePath, Method: entry.Method, Path: entry.Path, Headers: entry.Headers}
+}
+
+// ReadTimeline reads the CSV or JSONL timeline file sorted by offset.
+// Each record has the offset or timestamp and the request file path, JSONL records may have
+// the HTTP method, path and headers. The offset is either a number of seconds or a duration
+// such as 1.5s or 250ms. Timestamps in RFC3339 format are converted to offsets from the
+// earliest timestamp. Relative file paths are resolved against the timeline file directory.
+func ReadTimeline(path string) (entries []TimelineEntry, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		err = errors.Wrap(err, "os.Open")
+		return
+	}
+	defer f.Close()
+
+	var stamps []string
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") || strings.EqualFold(filepath.Ext(path), ".json") {
+		stamps, entries, err = readTimelineJSONL(f)
+	} else {
+		stamps, entries, err = readTimelineCSV(f)
+	}
+	if err != nil {
+		return
+	}
+
+	var offsets []time.Duration
+	if offsets, err = parseOffsets(stamps); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	for i := range entries {
+		entries[i].Offset = offsets[i]
+		if !filepath.IsAbs(entries[i].FilePath) {
+			entries[i].FilePath = filepath.Join(dir, entries[i].FilePath)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Offset < entries[j].Offset
+	})
+
+	return entries, nil
+}
+
+func readTimelineCSV(r io.Reader) (stamps []string, entries []TimelineEntry, err error) {
+	rd := csv.NewReader(r)
+	rd.Comment = '#'
+	rd.TrimLeadingSpace = true
+	rd.FieldsPerRecord = -1
+
+	var records [][]string
+	if records, err = rd.ReadAll(); err != nil {
+		err = errors.Wrap(err, "csv.ReadAll")
+		return
+	}
+
+	for i, rec := range records {
+		if len(rec) < 2 {
+			err = fmt.Errorf("timeline line %d: expected offset and file", i+1)
+			return
+		}
+		if i == 0 && (strings.EqualFold(rec[0], "offset") || strings.EqualFold(rec[0], "timestamp")) {
+			// Skip the header.
+			continue
+		}
+		stamps = append(stamps, strings.TrimSpace(rec[0]))
+		entries = append(entries, TimelineEntry{FilePath: strings.TrimSpace(rec[1])})
+	}
+
+	return
+}
+
+func readTimelineJSONL(r io.Reader) (stamps []string, entries []TimelineEntry, err error) {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var line timelineLine
+		if err = json.Unmarshal([]byte(text), &line); err != nil {
+			err = errors.Wrapf(err, "timeline line %d", n)
+			return
+		}
+		if line.File == "" {
+			err = fmt.Errorf("timeline line %d: missing file", n)
+			return
+		}
+
+		switch {
+		case line.Timestamp != "":
+			stamps = append(stamps, line.Timestamp)
+		case line.Offset != nil:
+			stamps = append(stamps, fmt.Sprint(line.Offset))
+		default:
+			err = fmt.Errorf("timeline line %d: missing offset or timestamp", n)
+			return
+		}
+		entries = append(entries, TimelineEntry{FilePath: line.File, Method: line.Method, Path: line.Path, Headers: line.Headers})
+	}
+
+	if err = scanner.Err(); err != nil {
+		err = errors.Wrap(err, "scanner.Scan")
+	}
+
+	return
+}
+
+// parseOffsets converts offsets or timestamps to durations.
+func parseOffsets(stamps []string) (offsets []time.Duration, err error) {
+	var times []time.Time
+	offsets = make([]time.Duration, len(stamps))
+
+	for i, s := range stamps {
+		if sec, errF := strconv.ParseFloat(s, 64); errF == nil {
+			offsets[i] = time.Duration(sec * float64(time.Second))
+		} else if d, errD := time.ParseDuration(s); errD == nil {
+			offsets[i] = d
+		} else if t, errT := time.Parse(time.RFC3339Nano, s); errT == nil {
+			times = append(times, t)
+		} else {
+			return nil, fmt.Errorf("invalid timeline offset %q", s)
+		}
+	}
+
+	if len(times) == 0 {
+		return
+	}
+	if len(times) != len(stamps) {
+		return nil, errors.New("timeline mixes timestamps and offsets")
+	}
+
+	// Timestamps are relative to the earliest one.
+	first := times[0]
+	for _, t := range times {
+		if t.Before(first) {
+			first = t
+		}
+	}
+	for i, t := range times {
+		offsets[i] = t.Sub(first)
+	}
+
+	return
+}
+
+// ParseSpeed parses replay speed multiplier such as 0.5x, 2x or 10.
+func ParseSpeed(s string) (speed float64, err error) {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "x")
+	if s == "" {
+		return 1, nil
+	}
+	if speed, err = strconv.ParseFloat(s, 64); err != nil {
+		err = errors.Wrap(err, "strconv.ParseFloat")
+		return
+	}
+	if speed <= 0 {
+		err = fmt.Errorf("invalid replay speed %v", speed)
+	}
+
+	return
+}
@@ -0,0 +1,111 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("Timeline", func() {
+	var (
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "timeline")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	writeTimeline := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).Should(Succeed())
+		return path
+	}
+
+	Describe("ReadTimeline", func() {
+		Context("CSV file", func() {
+			It("offsets sorted, relative paths resolved.", func() {
+				defer GinkgoRecover()
+				path := writeTimeline("timeline.csv", "offset,file\n1.5,b.dat\n0,a.dat\n250ms,/tmp/c.dat\n")
+
+				entries, err := emul.ReadTimeline(path)
+
+				Expect(err).Should(BeNil())
+				Expect(entries).To(HaveLen(3))
+				Expect(entries[0]).To(Equal(emul.TimelineEntry{Offset: 0, FilePath: filepath.Join(dir, "a.dat")}))
+				Expect(entries[1]).To(Equal(emul.TimelineEntry{Offset: 250 * time.Millisecond, FilePath: "/tmp/c.dat"}))
+				Expect(entries[2]).To(Equal(emul.TimelineEntry{Offset: 1500 * time.Millisecond, FilePath: filepath.Join(dir, "b.dat")}))
+			})
+		})
+
+		Context("JSONL file", func() {
+			It("timestamps converted to offsets.", func() {
+				defer GinkgoRecover()
+				path := writeTimeline("timeline.jsonl",
+					`{"timestamp": "2019-07-15T09:01:45.5Z", "file": "b.dat"}`+"\n"+
+						`{"timestamp": "2019-07-15T09:01:45Z", "file": "a.dat"}`+"\n")
+
+				entries, err := emul.ReadTimeline(path)
+
+				Expect(err).Should(BeNil())
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Offset).To(Equal(time.Duration(0)))
+				Expect(entries[1].Offset).To(Equal(500 * time.Millisecond))
+				Expect(entries[1].FilePath).To(Equal(filepath.Join(dir, "b.dat")))
+			})
+
+			It("missing file is an error.", func() {
+				defer GinkgoRecover()
+				path := writeTimeline("timeline.jsonl", `{"offset": 1}`+"\n")
+
+				_, err := emul.ReadTimeline(path)
+
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+	})
+
+	Describe("ParseSpeed", func() {
+		Context("multiplier", func() {
+			It("with and without x suffix.", func() {
+				defer GinkgoRecover()
+				for in, expected := range map[string]float64{"0.5x": 0.5, "2X": 2, "10": 10, "": 1} {
+					speed, err := emul.ParseSpeed(in)
+					Expect(err).Should(BeNil())
+					Expect(speed).To(Equal(expected))
+				}
+			})
+
+			It("zero is invalid.", func() {
+				defer GinkgoRecover()
+				_, err := emul.ParseSpeed("0x")
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+	})
+})
@@ -0,0 +1,80 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Warmup warm-up phase by duration and/or request count. Warm-up requests are sent
+// but reported separately from the main statistics.
+type Warmup struct {
+	Duration time.Duration
+	Count    uint64
+	start    time.Time
+	sent     uint64
+	started  sync.Once
+	done     sync.Once
+	OnDone   func()
+}
+
+// ParseWarmup parses warm-up duration, e.g. 30s, or request count.
+func ParseWarmup(expr string) (dur time.Duration, count uint64, err error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return 0, 0, nil
+	}
+	if count, err = strconv.ParseUint(expr, 10, 64); err == nil {
+		return 0, count, nil
+	}
+	if dur, err = time.ParseDuration(expr); err != nil || dur < 0 {
+		return 0, 0, fmt.Errorf("invalid warm-up %q, expected duration or request count", expr)
+	}
+
+	return dur, 0, nil
+}
+
+// NewWarmup creates warm-up phase, nil if there is no warm-up.
+func NewWarmup(dur time.Duration, count uint64) *Warmup {
+	if dur == 0 && count == 0 {
+		return nil
+	}
+	return &Warmup{Duration: dur, Count: count}
+}
+
+// Next reports whether the request being sent belongs to the warm-up phase, the phase starts with the first request.
+func (w *Warmup) Next() bool {
+	if w == nil {
+		return false
+	}
+
+	w.started.Do(func() { w.start = time.Now() })
+	n := atomic.AddUint64(&w.sent, 1)
+	if (w.Count > 0 && n <= w.Count) || (w.Duration > 0 && time.Since(w.start) < w.Duration) {
+		return true
+	}
+
+	w.done.Do(func() {
+		if w.OnDone != nil {
+			w.OnDone()
+		}
+	})
+	return false
+}
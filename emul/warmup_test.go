@@ -0,0 +1,68 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emul_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/emul"
+)
+
+var _ = Describe("Warmup", func() {
+	Describe("ParseWarmup", func() {
+		It("duration or request count.", func() {
+			defer GinkgoRecover()
+			Expect(emul.ParseWarmup("")).To(Equal(time.Duration(0)))
+			dur, count, err := emul.ParseWarmup("30s")
+			Expect(err).Should(BeNil())
+			Expect(dur).To(Equal(30 * time.Second))
+			Expect(count).To(BeZero())
+			dur, count, err = emul.ParseWarmup("500")
+			Expect(err).Should(BeNil())
+			Expect(dur).To(BeZero())
+			Expect(count).To(Equal(uint64(500)))
+			_, _, err = emul.ParseWarmup("soon")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("Next", func() {
+		It("counts warm-up requests.", func() {
+			defer GinkgoRecover()
+			var done int
+			warmup := emul.NewWarmup(0, 3)
+			warmup.OnDone = func() { done++ }
+			Expect([]bool{warmup.Next(), warmup.Next(), warmup.Next(), warmup.Next(), warmup.Next()}).To(Equal([]bool{true, true, true, false, false}))
+			Expect(done).To(Equal(1))
+		})
+		It("times warm-up requests.", func() {
+			defer GinkgoRecover()
+			warmup := emul.NewWarmup(50*time.Millisecond, 0)
+			time.Sleep(60 * time.Millisecond)
+			Expect(warmup.Next()).To(BeTrue())
+			time.Sleep(60 * time.Millisecond)
+			Expect(warmup.Next()).To(BeFalse())
+		})
+		It("no warm-up.", func() {
+			defer GinkgoRecover()
+			var warmup *emul.Warmup = emul.NewWarmup(0, 0)
+			Expect(warmup).To(BeNil())
+			Expect(warmup.Next()).To(BeFalse())
+		})
+	})
+})
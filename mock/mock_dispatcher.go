@@ -79,3 +79,17 @@ func (mr *MockDispatcherMockRecorder) SendReq(arg0, arg1, arg2 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendReq", reflect.TypeOf((*MockDispatcher)(nil).SendReq), arg0, arg1, arg2)
 }
+
+// SendRequest mocks base method
+func (m *MockDispatcher) SendRequest(arg0 context.Context, arg1 emul.Request, arg2 *emul.SendArgs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendRequest indicates an expected call of SendRequest
+func (mr *MockDispatcherMockRecorder) SendRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendRequest", reflect.TypeOf((*MockDispatcher)(nil).SendRequest), arg0, arg1, arg2)
+}
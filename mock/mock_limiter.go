@@ -48,6 +48,18 @@ func (mr *MockLimiterMockRecorder) Limit() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limit", reflect.TypeOf((*MockLimiter)(nil).Limit))
 }
 
+// SetLimit mocks base method
+func (m *MockLimiter) SetLimit(arg0 rate.Limit) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLimit", arg0)
+}
+
+// SetLimit indicates an expected call of SetLimit
+func (mr *MockLimiterMockRecorder) SetLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLimit", reflect.TypeOf((*MockLimiter)(nil).SetLimit), arg0)
+}
+
 // Wait mocks base method
 func (m *MockLimiter) Wait(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,208 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexstov/sling/util"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Direction chaos rule traffic direction.
+type Direction int
+
+const (
+	// BothDirections client to upstream and upstream to client traffic.
+	BothDirections Direction = iota
+	// UpDirection client to upstream traffic.
+	UpDirection
+	// DownDirection upstream to client traffic.
+	DownDirection
+)
+
+func (d Direction) String() string {
+	return [...]string{"both", "up", "down"}[d]
+}
+
+// chaosAction fault injected into the forwarded chunk.
+type chaosAction int
+
+const (
+	forwardChunk chaosAction = iota
+	resetConn
+	halfCloseConn
+	truncateChunk
+)
+
+// ChaosRule fault injection rule. Latency and jitter are added to each forwarded chunk, the
+// bandwidth caps each connection direction, the fault rates are per chunk and the corruption
+// rate is per byte. The rates are fractions, e.g. 0.01, or percentages, e.g. 1%.
+type ChaosRule struct {
+	Name      string `yaml:"name"`
+	Enabled   *bool  `yaml:"enabled"`
+	Direction string `yaml:"direction"`
+	LatencyMs uint   `yaml:"latencyMs"`
+	JitterMs  uint   `yaml:"jitterMs"`
+	Bandwidth string `yaml:"bandwidth"`
+	Reset     string `yaml:"reset"`
+	HalfClose string `yaml:"halfClose"`
+	Truncate  string `yaml:"truncate"`
+	Corrupt   string `yaml:"corrupt"`
+
+	dir       Direction
+	bps       float64
+	reset     float64
+	halfClose float64
+	truncate  float64
+	corrupt   float64
+}
+
+// ChaosRules fault injection rules file.
+type ChaosRules struct {
+	Rules []*ChaosRule `yaml:"rules"`
+}
+
+// ReadChaosRules reads the YAML rules file.
+func ReadChaosRules(path string) (rules *ChaosRules, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, errors.Wrap(err, "ioutil.ReadFile")
+	}
+
+	return ParseChaosRules(data)
+}
+
+// ParseChaosRules parses the YAML rules.
+func ParseChaosRules(data []byte) (rules *ChaosRules, err error) {
+	rules = &ChaosRules{}
+	if err = yaml.UnmarshalStrict(data, rules); err != nil {
+		return nil, errors.Wrap(err, "yaml.UnmarshalStrict")
+	}
+
+	for i, rule := range rules.Rules {
+		if err = rule.compile(); err != nil {
+			return nil, errors.Wrapf(err, "chaos rule %d", i+1)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *ChaosRule) compile() (err error) {
+	switch strings.ToLower(r.Direction) {
+	case "", "both":
+		r.dir = BothDirections
+	case "up":
+		r.dir = UpDirection
+	case "down":
+		r.dir = DownDirection
+	default:
+		return fmt.Errorf("unknown direction %q", r.Direction)
+	}
+
+	if r.bps, err = ParseBandwidth(r.Bandwidth); err != nil {
+		return
+	}
+	for _, rate := range []struct {
+		s string
+		f *float64
+	}{{r.Reset, &r.reset}, {r.HalfClose, &r.halfClose}, {r.Truncate, &r.truncate}, {r.Corrupt, &r.corrupt}} {
+		if *rate.f, err = util.ParseFraction(rate.s); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// Active returns true if the rule is enabled, rules are enabled by default.
+func (r *ChaosRule) Active() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// ParseBandwidth parses bandwidth in bytes per second, e.g. 512, 64kb or 1mb, zero is unlimited.
+func ParseBandwidth(expr string) (bps float64, err error) {
+	s := strings.ToLower(strings.TrimSpace(expr))
+	if s == "" {
+		return 0, nil
+	}
+
+	unit := 1.0
+	for _, suffix := range []struct {
+		name string
+		mul  float64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"b", 1}} {
+		if strings.HasSuffix(s, suffix.name) {
+			s, unit = strings.TrimSuffix(s, suffix.name), suffix.mul
+			break
+		}
+	}
+
+	if bps, err = strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil || bps < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q", expr)
+	}
+
+	return bps * unit, nil
+}
+
+// apply applies the active rules of the direction to the chunk. It corrupts the chunk in place and
+// returns the delay before forwarding, the fault to inject and the number of corrupted bytes.
+func (rs *ChaosRules) apply(dir Direction, chunk []byte, rng *rand.Rand) (delay time.Duration, action chaosAction, corrupted int) {
+	for _, r := range rs.Rules {
+		if !r.Active() || (r.dir != BothDirections && r.dir != dir) {
+			continue
+		}
+
+		ms := float64(r.LatencyMs)
+		if r.JitterMs > 0 {
+			ms += (rng.Float64()*2 - 1) * float64(r.JitterMs)
+		}
+		if ms > 0 {
+			delay += time.Duration(ms * float64(time.Millisecond))
+		}
+		if r.bps > 0 {
+			delay += time.Duration(float64(len(chunk)) / r.bps * float64(time.Second))
+		}
+
+		if action == forwardChunk {
+			switch p := rng.Float64(); {
+			case p < r.reset:
+				action = resetConn
+			case p < r.reset+r.truncate:
+				action = truncateChunk
+			case p < r.reset+r.truncate+r.halfClose:
+				action = halfCloseConn
+			}
+		}
+
+		if r.corrupt > 0 {
+			for i := range chunk {
+				if rng.Float64() < r.corrupt {
+					chunk[i] ^= 1 << uint(rng.Intn(8))
+					corrupted++
+				}
+			}
+		}
+	}
+
+	return
+}
@@ -14,7 +14,12 @@
 
 package net
 
-import "github.com/alexstov/sling/conf"
+import (
+	"net/http"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/throt"
+)
 
 // WriteArgs write method arguments.
 type WriteArgs struct {
@@ -35,6 +40,18 @@ type WriteArgs struct {
 	ReqID           uint64
 	CltType         conf.ClientType
 	SaveResCallback SaveToFileFunc
+	Method          string
+	Path            string
+	Headers         map[string]string
+	Response        *Response
+	Feedback        throt.Feedback
+}
+
+// Response received from the endpoint.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
 }
 
 // Client sends requests to the endpoint.
@@ -20,6 +20,9 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alexstov/sling/conf"
@@ -71,8 +74,39 @@ func (clt HTTPClient) Write(msg []byte, args *WriteArgs) (err error) {
 
 	var resp *http.Response
 	if args.CltType == conf.HTTPPost {
-		// Post request
-		resp, err = httpClt.Post(args.IPAddress, "application/x-www-form-urlencoded", bytes.NewBuffer(msg))
+		method := args.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+
+		// The request URI, e.g. recorded, replaces the endpoint path and query.
+		target := args.IPAddress
+		if args.Path != "" {
+			var addr, uri *url.URL
+			if addr, err = url.Parse(target); err != nil {
+				err = errors.Wrap(err, "url.Parse")
+				return
+			}
+			if uri, err = url.Parse(args.Path); err != nil {
+				err = errors.Wrap(err, "url.Parse")
+				return
+			}
+			addr.Path, addr.RawPath, addr.RawQuery = uri.Path, uri.RawPath, uri.RawQuery
+			target = addr.String()
+		}
+
+		var req *http.Request
+		if req, err = http.NewRequest(method, target, bytes.NewBuffer(msg)); err != nil {
+			err = errors.Wrap(err, "http.NewRequest")
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for name, value := range args.Headers {
+			req.Header.Set(name, value)
+		}
+
+		// Send request
+		resp, err = httpClt.Do(req)
 		if err != nil {
 			clt.logger.Out(logrus.ErrorLevel, logrus.Fields{"error": err}, "HTTP client failed to send the request.")
 			return
@@ -81,8 +115,20 @@ func (clt HTTPClient) Write(msg []byte, args *WriteArgs) (err error) {
 
 	defer resp.Body.Close()
 
+	// Report the status and Retry-After to adapt the rate.
+	if args.Feedback != nil {
+		args.Feedback.Observe(resp.StatusCode, ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+	}
+
 	body, err := ioutil.ReadAll(resp.Body)
-	clt.logger.Out(logrus.InfoLevel, logrus.Fields{"numBytes": len(body)}, "Successfully received msg reply.")
+	clt.logger.Out(logrus.InfoLevel, logrus.Fields{"numBytes": len(body), "status": resp.StatusCode}, "Successfully received msg reply.")
+
+	// Capture the response for the caller.
+	if args.Response != nil {
+		args.Response.Status = resp.StatusCode
+		args.Response.Header = resp.Header
+		args.Response.Body = body
+	}
 
 	// Save response to a file.
 	if args.SaveRes {
@@ -98,6 +144,25 @@ func (clt HTTPClient) Write(msg []byte, args *WriteArgs) (err error) {
 	return
 }
 
+// ParseRetryAfter parses Retry-After delay in seconds or HTTP date, zero if absent or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(value); err == nil {
+		if sec < 0 {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
 // Set connection timeouts.
 func timeoutDialer(cTimeout uint, rwTimeout uint) func(net, addr string) (c net.Conn, err error) {
 	return func(netw, addr string) (net.Conn, error) {
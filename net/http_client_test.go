@@ -0,0 +1,35 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"time"
+
+	"github.com/alexstov/sling/net"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseRetryAfter", func() {
+	It("seconds and HTTP dates.", func() {
+		defer GinkgoRecover()
+		now := time.Date(2019, 7, 20, 10, 39, 5, 0, time.UTC)
+		Expect(net.ParseRetryAfter("120", now)).To(Equal(2 * time.Minute))
+		Expect(net.ParseRetryAfter("Sat, 20 Jul 2019 10:39:35 GMT", now)).To(Equal(30 * time.Second))
+		Expect(net.ParseRetryAfter("Sat, 20 Jul 2019 10:38:35 GMT", now)).To(Equal(time.Duration(0)))
+		Expect(net.ParseRetryAfter("", now)).To(Equal(time.Duration(0)))
+		Expect(net.ParseRetryAfter("soon", now)).To(Equal(time.Duration(0)))
+	})
+})
@@ -0,0 +1,46 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ReadMessage reads the TCP request until the message terminator or EOF.
+// The returned message does not include the terminator.
+func ReadMessage(r io.Reader) (msg []byte, err error) {
+	buf := make([]byte, 0, BufferSize)
+	tmp := make([]byte, BufferSize)
+	var n int
+	for {
+		n, err = r.Read(tmp)
+		buf = append(buf, tmp[:n]...)
+		if bytes.HasSuffix(buf, []byte(MsgEndSequence)) {
+			return buf[:len(buf)-len(MsgEndSequence)], nil
+		}
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, errors.Wrap(err, "r.Read")
+		}
+		if len(buf) > MaxMsgSize {
+			return buf, errors.New("message exceeds the maximum size")
+		}
+	}
+}
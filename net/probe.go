@@ -0,0 +1,33 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"net"
+	"time"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/pkg/errors"
+)
+
+// Probe dials the endpoint to check it accepts connections.
+func Probe(ept conf.Endpoint, timeout time.Duration) (err error) {
+	var conn net.Conn
+	if conn, err = net.DialTimeout("tcp", ept.HostPort(), timeout); err != nil {
+		return errors.Wrap(err, "net.DialTimeout")
+	}
+
+	return conn.Close()
+}
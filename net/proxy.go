@@ -0,0 +1,273 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexstov/sling/slog"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ProxyArgs proxy command arguments.
+type ProxyArgs struct {
+	Listen   string
+	Upstream string
+	Rules    string
+	TmoCxn   uint
+	Seed     int64
+}
+
+// ProxyStat chaos proxy counts.
+type ProxyStat struct {
+	Connections uint64
+	BytesUp     uint64
+	BytesDown   uint64
+	Resets      uint64
+	HalfCloses  uint64
+	Truncations uint64
+	Corrupted   uint64
+}
+
+// Proxy forwards TCP between the listen address and the upstream injecting the faults of the
+// chaos rules. The rules are switched at runtime with SetRules or Reload and apply to the
+// chunks forwarded after the switch, including the open connections.
+type Proxy struct {
+	Args     ProxyArgs
+	Stat     ProxyStat
+	logger   slog.Logger
+	rules    atomic.Value
+	modTime  time.Time
+	listener net.Listener
+	rng      *rand.Rand
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewProxy creates new Proxy instance without rules.
+func NewProxy(slog slog.Logger, args ProxyArgs) *Proxy {
+	proxy := &Proxy{Args: args, logger: slog, rng: rand.New(rand.NewSource(args.Seed)), conns: make(map[net.Conn]struct{})}
+	proxy.rules.Store(&ChaosRules{})
+	return proxy
+}
+
+// Rules returns the current chaos rules.
+func (p *Proxy) Rules() *ChaosRules {
+	return p.rules.Load().(*ChaosRules)
+}
+
+// Stats returns a snapshot of the proxy counts.
+func (p *Proxy) Stats() ProxyStat {
+	return ProxyStat{
+		Connections: atomic.LoadUint64(&p.Stat.Connections),
+		BytesUp:     atomic.LoadUint64(&p.Stat.BytesUp),
+		BytesDown:   atomic.LoadUint64(&p.Stat.BytesDown),
+		Resets:      atomic.LoadUint64(&p.Stat.Resets),
+		HalfCloses:  atomic.LoadUint64(&p.Stat.HalfCloses),
+		Truncations: atomic.LoadUint64(&p.Stat.Truncations),
+		Corrupted:   atomic.LoadUint64(&p.Stat.Corrupted),
+	}
+}
+
+// SetRules switches the chaos rules.
+func (p *Proxy) SetRules(rules *ChaosRules) {
+	p.rules.Store(rules)
+}
+
+// Reload reads the rules file if it has changed since the last reload or if forced.
+func (p *Proxy) Reload(force bool) (reloaded bool, err error) {
+	if p.Args.Rules == "" {
+		return false, nil
+	}
+
+	var fi os.FileInfo
+	if fi, err = os.Stat(p.Args.Rules); err != nil {
+		return false, errors.Wrap(err, "os.Stat")
+	}
+	if !force && fi.ModTime().Equal(p.modTime) {
+		return false, nil
+	}
+	p.modTime = fi.ModTime()
+
+	var rules *ChaosRules
+	if rules, err = ReadChaosRules(p.Args.Rules); err != nil {
+		return false, err
+	}
+	p.SetRules(rules)
+
+	return true, nil
+}
+
+// Start starts listening, returns the listening address.
+func (p *Proxy) Start() (addr net.Addr, err error) {
+	if p.Args.Upstream == "" {
+		return nil, errors.New("no upstream address")
+	}
+	if p.listener, err = net.Listen("tcp", p.Args.Listen); err != nil {
+		return nil, errors.Wrap(err, "net.Listen")
+	}
+
+	p.wg.Add(1)
+	go p.serve()
+
+	p.logger.Out(logrus.InfoLevel, logrus.Fields{"addr": p.listener.Addr(), "upstream": p.Args.Upstream}, "Proxy started.")
+	return p.listener.Addr(), nil
+}
+
+// Stop stops listening and closes the open connections.
+func (p *Proxy) Stop() (err error) {
+	err = p.listener.Close()
+
+	p.mu.Lock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	p.logger.Out(logrus.InfoLevel, logrus.Fields{"connections": atomic.LoadUint64(&p.Stat.Connections)}, "Proxy stopped.")
+	return
+}
+
+func (p *Proxy) serve() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			// Listener is closed.
+			return
+		}
+
+		atomic.AddUint64(&p.Stat.Connections, 1)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.handle(conn)
+		}()
+	}
+}
+
+// track adds the connection to the open connections or removes it.
+func (p *Proxy) track(conn net.Conn, open bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if open {
+		p.conns[conn] = struct{}{}
+	} else {
+		delete(p.conns, conn)
+	}
+}
+
+func (p *Proxy) handle(client net.Conn) {
+	upstream, err := net.DialTimeout("tcp", p.Args.Upstream, time.Duration(p.Args.TmoCxn)*time.Second)
+	if err != nil {
+		p.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "upstream": p.Args.Upstream}, "Cannot connect to the upstream.")
+		client.Close()
+		return
+	}
+
+	p.track(client, true)
+	p.track(upstream, true)
+	defer func() {
+		p.track(client, false)
+		p.track(upstream, false)
+		client.Close()
+		upstream.Close()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go p.pipe(upstream, client, UpDirection, &wg)
+	go p.pipe(client, upstream, DownDirection, &wg)
+	wg.Wait()
+}
+
+// pipe forwards the direction applying the chaos rules to each chunk.
+func (p *Proxy) pipe(dst net.Conn, src net.Conn, dir Direction, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	p.mu.Lock()
+	rng := rand.New(rand.NewSource(p.rng.Int63()))
+	p.mu.Unlock()
+
+	bytes := &p.Stat.BytesUp
+	if dir == DownDirection {
+		bytes = &p.Stat.BytesDown
+	}
+
+	buf := make([]byte, 32*BufferSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			delay, action, corrupted := p.Rules().apply(dir, chunk, rng)
+			atomic.AddUint64(&p.Stat.Corrupted, uint64(corrupted))
+			time.Sleep(delay)
+
+			switch action {
+			case resetConn:
+				// Abort both connections so both peers see the reset.
+				atomic.AddUint64(&p.Stat.Resets, 1)
+				for _, conn := range []net.Conn{src, dst} {
+					if tcpConn, ok := conn.(*net.TCPConn); ok {
+						tcpConn.SetLinger(0)
+					}
+					conn.Close()
+				}
+				return
+			case truncateChunk:
+				// Forward a part of the chunk and close both connections.
+				atomic.AddUint64(&p.Stat.Truncations, 1)
+				n = rng.Intn(n)
+				dst.Write(chunk[:n])
+				atomic.AddUint64(bytes, uint64(n))
+				src.Close()
+				dst.Close()
+				return
+			case halfCloseConn:
+				// Stop forwarding the direction, the peer reads EOF while the other direction continues.
+				atomic.AddUint64(&p.Stat.HalfCloses, 1)
+				closeWrite(dst)
+				return
+			}
+
+			if _, errW := dst.Write(chunk); errW != nil {
+				src.Close()
+				return
+			}
+			atomic.AddUint64(bytes, uint64(n))
+		}
+		if err != nil {
+			// Propagate EOF as the half-close to the destination.
+			closeWrite(dst)
+			return
+		}
+	}
+}
+
+func closeWrite(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.CloseWrite()
+	} else {
+		conn.Close()
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"io/ioutil"
+	gonet "net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/net"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Proxy", func() {
+	var (
+		t          testing.T
+		mockCtrl   *gomock.Controller
+		mockLogger *mock.MockLogger
+		upstream   *net.MockServer
+		proxy      *net.Proxy
+		addr       gonet.Addr
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(&t)
+		mockLogger = mock.NewMockLogger(mockCtrl)
+		mockLogger.EXPECT().Out(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+		// Echo upstream.
+		upstream = net.NewMockServer(mockLogger, net.ServeArgs{TCPListen: "127.0.0.1:0"})
+		upAddr, _, err := upstream.Start()
+		Expect(err).Should(BeNil())
+		proxy = net.NewProxy(mockLogger, net.ProxyArgs{Listen: "127.0.0.1:0", Upstream: upAddr.String(), Seed: 1})
+		addr, err = proxy.Start()
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		proxy.Stop()
+		upstream.Stop()
+		mockCtrl.Finish()
+	})
+
+	send := func(msg string) (string, error) {
+		conn, err := gonet.Dial("tcp", addr.String())
+		Expect(err).Should(BeNil())
+		defer conn.Close()
+		_, err = conn.Write([]byte(msg + net.MsgEndSequence))
+		Expect(err).Should(BeNil())
+		res, err := ioutil.ReadAll(conn)
+		return string(res), err
+	}
+
+	rules := func(yml string) *net.ChaosRules {
+		rules, err := net.ParseChaosRules([]byte(yml))
+		Expect(err).Should(BeNil())
+		return rules
+	}
+
+	It("forwards without rules.", func() {
+		defer GinkgoRecover()
+		Expect(send("ping")).To(Equal("ping"))
+		Expect(proxy.Stats().Connections).To(Equal(uint64(1)))
+	})
+
+	It("switches the rules at runtime.", func() {
+		defer GinkgoRecover()
+		proxy.SetRules(rules("rules:\n- name: slow\n  direction: down\n  latencyMs: 50\n"))
+		start := time.Now()
+		Expect(send("ping")).To(Equal("ping"))
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+
+		proxy.SetRules(rules("rules:\n- name: reset\n  reset: 100%\n"))
+		_, err := send("ping")
+		Expect(err).ShouldNot(BeNil())
+		Expect(proxy.Stats().Resets).To(Equal(uint64(1)))
+
+		proxy.SetRules(rules("rules:\n- name: reset\n  enabled: false\n  reset: 100%\n- name: corrupt\n  direction: down\n  corrupt: 1\n"))
+		res, err := send("ping")
+		Expect(err).Should(BeNil())
+		Expect(res).To(HaveLen(4))
+		Expect(res).NotTo(Equal("ping"))
+		Expect(proxy.Stats().Corrupted).To(Equal(uint64(4)))
+	})
+
+	It("truncates and half-closes.", func() {
+		defer GinkgoRecover()
+		proxy.SetRules(rules("rules:\n- name: truncate\n  direction: down\n  truncate: 1\n"))
+		res, err := send("ping")
+		Expect(err).Should(BeNil())
+		Expect(len(res)).To(BeNumerically("<", 4))
+		Expect(proxy.Stats().Truncations).To(Equal(uint64(1)))
+
+		proxy.SetRules(rules("rules:\n- name: halfclose\n  direction: down\n  halfClose: 1\n"))
+		Expect(send("ping")).To(Equal(""))
+		Expect(proxy.Stats().HalfCloses).To(Equal(uint64(1)))
+	})
+
+	It("reloads the changed rules file.", func() {
+		defer GinkgoRecover()
+		dir, err := ioutil.TempDir("", "chaos")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "chaos.yml")
+		Expect(ioutil.WriteFile(path, []byte("rules:\n- name: a\n  latencyMs: 1\n"), 0644)).Should(Succeed())
+
+		proxy.Args.Rules = path
+		Expect(proxy.Reload(false)).To(BeTrue())
+		Expect(proxy.Reload(false)).To(BeFalse())
+		Expect(proxy.Reload(true)).To(BeTrue())
+
+		Expect(ioutil.WriteFile(path, []byte("rules:\n- name: b\n  latencyMs: 1\n"), 0644)).Should(Succeed())
+		Expect(os.Chtimes(path, time.Now(), time.Now().Add(time.Second))).Should(Succeed())
+		Expect(proxy.Reload(false)).To(BeTrue())
+		Expect(proxy.Rules().Rules[0].Name).To(Equal("b"))
+
+		Expect(ioutil.WriteFile(path, []byte("rules:\n- name: c\n  reset: 200%\n"), 0644)).Should(Succeed())
+		_, err = proxy.Reload(true)
+		Expect(err).ShouldNot(BeNil())
+		Expect(proxy.Rules().Rules[0].Name).To(Equal("b"))
+	})
+})
+
+var _ = Describe("ChaosRules", func() {
+	It("invalid rules.", func() {
+		defer GinkgoRecover()
+		for _, yml := range []string{"rules:\n- direction: sideways\n", "rules:\n- bandwidth: fast\n", "rules:\n- corrupt: 2\n", "rules:\n- latency: 1\n"} {
+			_, err := net.ParseChaosRules([]byte(yml))
+			Expect(err).ShouldNot(BeNil())
+		}
+	})
+	It("bandwidth.", func() {
+		defer GinkgoRecover()
+		Expect(net.ParseBandwidth("512")).To(Equal(512.0))
+		Expect(net.ParseBandwidth("64kb")).To(Equal(65536.0))
+		Expect(net.ParseBandwidth("1MB")).To(Equal(1048576.0))
+		Expect(net.ParseBandwidth("")).To(Equal(0.0))
+	})
+})
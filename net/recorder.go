@@ -0,0 +1,311 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/sio"
+	"github.com/alexstov/sling/slog"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RecordTimeline recorded session timeline file name, replayed with request send --replay.
+const RecordTimeline = "timeline.jsonl"
+
+// RecordArgs record command arguments.
+type RecordArgs struct {
+	CltType  conf.ClientType
+	Listen   string
+	Upstream string // TCP host:port or HTTP URL, records without forwarding if empty.
+	Dir      string
+	TmoSec   uint
+}
+
+// RecordEntry recorded request timeline record.
+type RecordEntry struct {
+	Offset    string            `json:"offset"`
+	File      string            `json:"file"`
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Response  string            `json:"response,omitempty"`
+	Status    int               `json:"status,omitempty"`
+	LatencyMs int64             `json:"latencyMs,omitempty"`
+}
+
+// Recorder records the received requests as request files with the timeline of their offsets.
+// In proxy mode the requests are forwarded to the upstream and the responses are recorded too.
+type Recorder struct {
+	Args     RecordArgs
+	Count    uint64
+	logger   slog.Logger
+	filer    sio.Filer
+	listener net.Listener
+	server   *http.Server
+	timeline *os.File
+	start    time.Time
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// NewRecorder creates new Recorder instance.
+func NewRecorder(slog slog.Logger, filer sio.Filer, args RecordArgs) *Recorder {
+	return &Recorder{Args: args, logger: slog, filer: filer}
+}
+
+// Start creates the record directory and starts listening, returns the listening address.
+func (rec *Recorder) Start() (addr net.Addr, err error) {
+	if rec.Args.CltType != conf.TCP && rec.Args.CltType != conf.HTTPPost {
+		return nil, fmt.Errorf("unsupported record type %s", rec.Args.CltType)
+	}
+
+	if err = rec.filer.MkdirAll(rec.Args.Dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "filer.MkdirAll")
+	}
+	if rec.timeline, err = rec.filer.CreateFile(filepath.Join(rec.Args.Dir, RecordTimeline)); err != nil {
+		return nil, errors.Wrap(err, "filer.CreateFile")
+	}
+	if rec.listener, err = net.Listen("tcp", rec.Args.Listen); err != nil {
+		rec.filer.CloseFile(rec.timeline)
+		return nil, errors.Wrap(err, "net.Listen")
+	}
+	rec.start = time.Now()
+
+	rec.wg.Add(1)
+	if rec.Args.CltType == conf.TCP {
+		go rec.serveTCP()
+	} else {
+		rec.server = &http.Server{Handler: http.HandlerFunc(rec.serveHTTP)}
+		go func() {
+			defer rec.wg.Done()
+			rec.server.Serve(rec.listener)
+		}()
+	}
+
+	rec.logger.Out(logrus.InfoLevel, logrus.Fields{"addr": rec.listener.Addr(), "upstream": rec.Args.Upstream}, "Recording started.")
+	return rec.listener.Addr(), nil
+}
+
+// Stop stops listening, waits for the requests in progress and closes the timeline.
+func (rec *Recorder) Stop() (err error) {
+	if rec.server != nil {
+		err = rec.server.Shutdown(context.Background())
+	} else {
+		err = rec.listener.Close()
+	}
+	rec.wg.Wait()
+
+	if errC := rec.filer.CloseFile(rec.timeline); errC != nil && err == nil {
+		err = errors.Wrap(errC, "filer.CloseFile")
+	}
+
+	rec.logger.Out(logrus.InfoLevel, logrus.Fields{"count": atomic.LoadUint64(&rec.Count)}, "Recording stopped.")
+	return
+}
+
+func (rec *Recorder) serveTCP() {
+	defer rec.wg.Done()
+	for {
+		conn, err := rec.listener.Accept()
+		if err != nil {
+			// Listener is closed.
+			return
+		}
+
+		rec.wg.Add(1)
+		go func() {
+			defer rec.wg.Done()
+			rec.handleTCP(conn)
+		}()
+	}
+}
+
+func (rec *Recorder) handleTCP(conn net.Conn) {
+	defer conn.Close()
+	received := time.Now()
+	if rec.Args.TmoSec != 0 {
+		conn.SetDeadline(received.Add(time.Duration(rec.Args.TmoSec) * time.Second))
+	}
+
+	msg, err := ReadMessage(conn)
+	if err != nil {
+		rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "remote": conn.RemoteAddr()}, "Cannot read the request.")
+		return
+	}
+
+	var entry RecordEntry
+	var res []byte
+	if rec.Args.Upstream != "" {
+		if res, err = rec.forwardTCP(msg); err != nil {
+			rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "upstream": rec.Args.Upstream}, "Cannot forward the request.")
+		} else {
+			conn.Write(res)
+		}
+		entry.LatencyMs = int64(time.Since(received) / time.Millisecond)
+	}
+
+	rec.record(received, msg, res, entry)
+}
+
+func (rec *Recorder) forwardTCP(msg []byte) (res []byte, err error) {
+	var upstream net.Conn
+	if upstream, err = net.DialTimeout("tcp", rec.Args.Upstream, rec.timeout()); err != nil {
+		return nil, errors.Wrap(err, "net.DialTimeout")
+	}
+	defer upstream.Close()
+	if rec.Args.TmoSec != 0 {
+		upstream.SetDeadline(time.Now().Add(rec.timeout()))
+	}
+
+	if _, err = upstream.Write(append(msg, MsgEndSequence...)); err != nil {
+		return nil, errors.Wrap(err, "upstream.Write")
+	}
+	if res, err = ioutil.ReadAll(upstream); err != nil {
+		return nil, errors.Wrap(err, "ioutil.ReadAll")
+	}
+
+	return res, nil
+}
+
+func (rec *Recorder) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	received := time.Now()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "remote": r.RemoteAddr}, "Cannot read the request.")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry := RecordEntry{Method: r.Method, Path: r.URL.RequestURI(), Headers: make(map[string]string)}
+	for name := range r.Header {
+		entry.Headers[name] = r.Header.Get(name)
+	}
+
+	var res []byte
+	if rec.Args.Upstream != "" {
+		var resp *http.Response
+		if resp, res, err = rec.forwardHTTP(r, body); err != nil {
+			rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "upstream": rec.Args.Upstream}, "Cannot forward the request.")
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		} else {
+			for name, values := range resp.Header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(resp.StatusCode)
+			w.Write(res)
+			entry.Status = resp.StatusCode
+		}
+		entry.LatencyMs = int64(time.Since(received) / time.Millisecond)
+	}
+
+	rec.record(received, body, res, entry)
+}
+
+func (rec *Recorder) forwardHTTP(r *http.Request, body []byte) (resp *http.Response, res []byte, err error) {
+	upstream := rec.Args.Upstream
+	if !strings.Contains(upstream, "://") {
+		upstream = "http://" + upstream
+	}
+
+	var target *url.URL
+	if target, err = url.Parse(upstream); err != nil {
+		return nil, nil, errors.Wrap(err, "url.Parse")
+	}
+	target.Path, target.RawPath, target.RawQuery = r.URL.Path, r.URL.RawPath, r.URL.RawQuery
+
+	var req *http.Request
+	if req, err = http.NewRequest(r.Method, target.String(), bytes.NewReader(body)); err != nil {
+		return nil, nil, errors.Wrap(err, "http.NewRequest")
+	}
+	for name, values := range r.Header {
+		req.Header[name] = values
+	}
+
+	clt := &http.Client{Timeout: rec.timeout()}
+	if resp, err = clt.Do(req); err != nil {
+		return nil, nil, errors.Wrap(err, "clt.Do")
+	}
+	defer resp.Body.Close()
+
+	if res, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, nil, errors.Wrap(err, "ioutil.ReadAll")
+	}
+
+	return resp, res, nil
+}
+
+// record saves the request and the upstream response and appends the timeline record.
+func (rec *Recorder) record(received time.Time, msg []byte, res []byte, entry RecordEntry) {
+	seq := atomic.AddUint64(&rec.Count, 1)
+	entry.Offset = received.Sub(rec.start).String()
+	entry.File = fmt.Sprintf("%06d.dat", seq)
+	if err := rec.save(entry.File, msg); err != nil {
+		rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "file": entry.File}, "Cannot save the recorded request.")
+		return
+	}
+
+	if res != nil {
+		entry.Response = fmt.Sprintf("%06d.res", seq)
+		if err := rec.save(entry.Response, res); err != nil {
+			rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "file": entry.Response}, "Cannot save the recorded response.")
+			entry.Response = ""
+		}
+	}
+
+	line, _ := json.Marshal(entry)
+	rec.mu.Lock()
+	_, err := rec.filer.WriteFile(rec.timeline, append(line, '\n'))
+	rec.mu.Unlock()
+	if err != nil {
+		rec.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err}, "Cannot write the timeline.")
+		return
+	}
+
+	rec.logger.Out(logrus.InfoLevel, logrus.Fields{"file": entry.File, "size": len(msg), "offset": entry.Offset}, "Recorded request.")
+}
+
+func (rec *Recorder) save(name string, b []byte) (err error) {
+	var f *os.File
+	if f, err = rec.filer.CreateFile(filepath.Join(rec.Args.Dir, name)); err != nil {
+		return errors.Wrap(err, "filer.CreateFile")
+	}
+	if _, err = rec.filer.WriteFile(f, b); err != nil {
+		rec.filer.CloseFile(f)
+		return errors.Wrap(err, "filer.WriteFile")
+	}
+
+	return rec.filer.CloseFile(f)
+}
+
+// timeout upstream timeout, zero does not time out.
+func (rec *Recorder) timeout() time.Duration {
+	return time.Duration(rec.Args.TmoSec) * time.Second
+}
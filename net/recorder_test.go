@@ -0,0 +1,204 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	gonet "net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexstov/sling/conf"
+	"github.com/alexstov/sling/emul"
+	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/net"
+	"github.com/alexstov/sling/sio"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rcrowley/go-metrics"
+)
+
+var _ = Describe("Recorder", func() {
+	var (
+		t          testing.T
+		mockCtrl   *gomock.Controller
+		mockLogger *mock.MockLogger
+		filer      sio.Filer
+		dir        string
+	)
+
+	BeforeEach(func() {
+		var err error
+		mockCtrl = gomock.NewController(&t)
+		mockLogger = mock.NewMockLogger(mockCtrl)
+		mockLogger.EXPECT().Out(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		filer, err = sio.NewFiler(mockLogger)
+		Expect(err).Should(BeNil())
+		dir, err = ioutil.TempDir("", "record")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+		os.RemoveAll(dir)
+	})
+
+	sendTCP := func(addr string, msg string) string {
+		conn, err := gonet.Dial("tcp", addr)
+		Expect(err).Should(BeNil())
+		defer conn.Close()
+		_, err = conn.Write([]byte(msg + net.MsgEndSequence))
+		Expect(err).Should(BeNil())
+		res, err := ioutil.ReadAll(conn)
+		Expect(err).Should(BeNil())
+		return string(res)
+	}
+
+	readEntries := func() (entries []net.RecordEntry) {
+		f, err := os.Open(filepath.Join(dir, net.RecordTimeline))
+		Expect(err).Should(BeNil())
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry net.RecordEntry
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).Should(Succeed())
+			entries = append(entries, entry)
+		}
+		return
+	}
+
+	It("records TCP requests replayable from the timeline.", func() {
+		defer GinkgoRecover()
+		rec := net.NewRecorder(mockLogger, filer, net.RecordArgs{CltType: conf.TCP, Listen: "127.0.0.1:0", Dir: dir})
+		addr, err := rec.Start()
+		Expect(err).Should(BeNil())
+		Expect(sendTCP(addr.String(), "first")).To(Equal(""))
+		Expect(sendTCP(addr.String(), "second\r\nline")).To(Equal(""))
+		Expect(rec.Stop()).Should(Succeed())
+
+		Expect(rec.Count).To(Equal(uint64(2)))
+		entries, err := emul.ReadTimeline(filepath.Join(dir, net.RecordTimeline))
+		Expect(err).Should(BeNil())
+		Expect(entries).To(HaveLen(2))
+		Expect(ioutil.ReadFile(entries[0].FilePath)).To(Equal([]byte("first")))
+		Expect(ioutil.ReadFile(entries[1].FilePath)).To(Equal([]byte("second\r\nline")))
+		Expect(entries[1].Offset).To(BeNumerically(">=", entries[0].Offset))
+	})
+
+	It("records TCP requests and upstream responses in proxy mode.", func() {
+		defer GinkgoRecover()
+		upstream, err := gonet.Listen("tcp", "127.0.0.1:0")
+		Expect(err).Should(BeNil())
+		defer upstream.Close()
+		go func() {
+			for {
+				conn, err := upstream.Accept()
+				if err != nil {
+					return
+				}
+				msg, _ := net.ReadMessage(conn)
+				conn.Write([]byte(strings.ToUpper(string(msg))))
+				conn.Close()
+			}
+		}()
+
+		rec := net.NewRecorder(mockLogger, filer, net.RecordArgs{CltType: conf.TCP, Listen: "127.0.0.1:0",
+			Upstream: upstream.Addr().String(), Dir: dir})
+		addr, err := rec.Start()
+		Expect(err).Should(BeNil())
+		Expect(sendTCP(addr.String(), "hello")).To(Equal("HELLO"))
+		Expect(rec.Stop()).Should(Succeed())
+
+		entries := readEntries()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Response).To(Equal("000001.res"))
+		Expect(ioutil.ReadFile(filepath.Join(dir, entries[0].Response))).To(Equal([]byte("HELLO")))
+	})
+
+	It("records HTTP requests and upstream responses in proxy mode.", func() {
+		defer GinkgoRecover()
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("X-Path", r.URL.RequestURI())
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("re:" + string(body)))
+		}))
+		defer upstream.Close()
+
+		rec := net.NewRecorder(mockLogger, filer, net.RecordArgs{CltType: conf.HTTPPost, Listen: "127.0.0.1:0",
+			Upstream: upstream.URL, Dir: dir})
+		addr, err := rec.Start()
+		Expect(err).Should(BeNil())
+		resp, err := http.Post("http://"+addr.String()+"/TR?id=1", "text/plain", strings.NewReader("body"))
+		Expect(err).Should(BeNil())
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(resp.Header.Get("X-Path")).To(Equal("/TR?id=1"))
+		Expect(string(body)).To(Equal("re:body"))
+		Expect(rec.Stop()).Should(Succeed())
+
+		entries := readEntries()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Method).To(Equal(http.MethodPost))
+		Expect(entries[0].Path).To(Equal("/TR?id=1"))
+		Expect(entries[0].Headers["Content-Type"]).To(Equal("text/plain"))
+		Expect(entries[0].Status).To(Equal(http.StatusCreated))
+		Expect(ioutil.ReadFile(filepath.Join(dir, entries[0].File))).To(Equal([]byte("body")))
+		Expect(ioutil.ReadFile(filepath.Join(dir, entries[0].Response))).To(Equal([]byte("re:body")))
+	})
+
+	It("replays recorded HTTP requests with the method, path and headers.", func() {
+		defer GinkgoRecover()
+		rec := net.NewRecorder(mockLogger, filer, net.RecordArgs{CltType: conf.HTTPPost, Listen: "127.0.0.1:0", Dir: dir})
+		addr, err := rec.Start()
+		Expect(err).Should(BeNil())
+		req, _ := http.NewRequest(http.MethodPut, "http://"+addr.String()+"/api/items/7?v=2", strings.NewReader(`{"id":7}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Trace", "abc")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).Should(BeNil())
+		resp.Body.Close()
+		Expect(rec.Stop()).Should(Succeed())
+
+		replayed := make(chan string, 1)
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			replayed <- strings.Join([]string{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get("X-Trace"), string(body)}, " ")
+		}))
+		defer target.Close()
+
+		entries, err := emul.ReadTimeline(filepath.Join(dir, net.RecordTimeline))
+		Expect(err).Should(BeNil())
+		Expect(entries).To(HaveLen(1))
+		clt, _ := net.NewHTTPClient(mockLogger, filer)
+		mockConsoler := mock.NewMockConsoler(mockCtrl)
+		mockConsoler.EXPECT().OutLogAndConsole(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		mockLimiter := mock.NewMockLimiter(mockCtrl)
+		mockLimiter.EXPECT().Wait(gomock.Any()).Return(nil).AnyTimes()
+		em, _ := emul.NewEmul(clt, filer, mockConsoler, mockLimiter, mockLogger, metrics.NewHistogram(metrics.NewUniformSample(10)))
+
+		Expect(em.SendRequest(context.Background(), entries[0].Request(), &emul.SendArgs{Address: target.URL + "/TR", CltType: conf.HTTPPost})).Should(Succeed())
+		Eventually(replayed).Should(Receive(Equal(`PUT /api/items/7?v=2 application/json abc {"id":7}`)))
+	})
+})
@@ -0,0 +1,137 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RespondMode mock server response mode.
+type RespondMode int
+
+const (
+	// EchoRespond responds with the request.
+	EchoRespond RespondMode = iota
+	// FileRespond responds with the file content.
+	FileRespond
+	// MapRespond responds with the file of the first rule matching the request.
+	MapRespond
+)
+
+func (m RespondMode) String() string {
+	return [...]string{"echo", "file", "map"}[m]
+}
+
+// ResponseRule maps the request body pattern to the response file.
+type ResponseRule struct {
+	Match  string `yaml:"match"`
+	File   string `yaml:"file"`
+	Status int    `yaml:"status"`
+	Count  uint64 `yaml:"-"`
+	regex  *regexp.Regexp
+	body   []byte
+}
+
+// Responder builds the mock server responses.
+type Responder struct {
+	Mode  RespondMode
+	Body  []byte
+	Rules []*ResponseRule
+}
+
+// ParseRespond parses the response mode: echo, file:PATH or map:PATH. The map file is a YAML
+// list of rules with the request body regex, the response file and the optional HTTP status.
+// Relative response files are resolved against the map file directory.
+func ParseRespond(expr string) (responder *Responder, err error) {
+	expr = strings.TrimSpace(expr)
+	mode, path := expr, ""
+	if i := strings.Index(expr, ":"); i >= 0 {
+		mode, path = expr[:i], expr[i+1:]
+	}
+
+	switch strings.ToLower(mode) {
+	case "", "echo":
+		return &Responder{Mode: EchoRespond}, nil
+	case "file":
+		responder = &Responder{Mode: FileRespond}
+		if responder.Body, err = ioutil.ReadFile(path); err != nil {
+			return nil, errors.Wrap(err, "ioutil.ReadFile")
+		}
+		return responder, nil
+	case "map":
+		return readResponseMap(path)
+	}
+
+	return nil, fmt.Errorf("unknown response mode %q", expr)
+}
+
+func readResponseMap(path string) (responder *Responder, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, errors.Wrap(err, "ioutil.ReadFile")
+	}
+
+	responder = &Responder{Mode: MapRespond}
+	if err = yaml.Unmarshal(data, &responder.Rules); err != nil {
+		return nil, errors.Wrap(err, "yaml.Unmarshal")
+	}
+
+	for i, rule := range responder.Rules {
+		if rule.regex, err = regexp.Compile(rule.Match); err != nil {
+			return nil, errors.Wrapf(err, "response rule %d", i+1)
+		}
+		file := rule.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		if rule.body, err = ioutil.ReadFile(file); err != nil {
+			return nil, errors.Wrapf(err, "response rule %d", i+1)
+		}
+	}
+
+	return responder, nil
+}
+
+// Respond returns the response body and status for the request, the rule is nil if no rule matches in map mode.
+func (r *Responder) Respond(req []byte) (body []byte, status int, rule *ResponseRule) {
+	switch r.Mode {
+	case EchoRespond:
+		return req, http.StatusOK, nil
+	case FileRespond:
+		return r.Body, http.StatusOK, nil
+	}
+
+	for _, rule = range r.Rules {
+		if rule.regex.Match(req) {
+			atomic.AddUint64(&rule.Count, 1)
+			status = rule.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			return rule.body, status, rule
+		}
+	}
+
+	return nil, http.StatusNotFound, nil
+}
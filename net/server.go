@@ -0,0 +1,251 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexstov/sling/slog"
+	"github.com/alexstov/sling/throt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ServeArgs serve command arguments.
+type ServeArgs struct {
+	TCPListen   string
+	HTTPListen  string
+	Responder   *Responder
+	Latency     *throt.Think
+	ErrorRate   float64
+	DropRate    float64
+	ErrorStatus int
+	Seed        int64
+}
+
+// ServeStat mock server request counts.
+type ServeStat struct {
+	Received  uint64
+	Responded uint64
+	Errors    uint64
+	Drops     uint64
+	Unmatched uint64
+	Bytes     uint64
+}
+
+// MockServer local TCP and HTTP target responding with the mock responses after the
+// latency drawn from the distribution, failing and dropping the connections at random.
+type MockServer struct {
+	Args    ServeArgs
+	TCP     ServeStat
+	HTTP    ServeStat
+	logger  slog.Logger
+	rng     *rand.Rand
+	mu      sync.Mutex
+	tcpLn   net.Listener
+	httpSrv *http.Server
+	wg      sync.WaitGroup
+}
+
+// NewMockServer creates new MockServer instance.
+func NewMockServer(slog slog.Logger, args ServeArgs) *MockServer {
+	if args.Responder == nil {
+		args.Responder = &Responder{Mode: EchoRespond}
+	}
+	if args.ErrorStatus == 0 {
+		args.ErrorStatus = http.StatusInternalServerError
+	}
+
+	return &MockServer{Args: args, logger: slog, rng: rand.New(rand.NewSource(args.Seed))}
+}
+
+// Start starts the TCP and HTTP listeners set in the arguments, returns the listening addresses.
+func (srv *MockServer) Start() (tcpAddr net.Addr, httpAddr net.Addr, err error) {
+	if srv.Args.TCPListen == "" && srv.Args.HTTPListen == "" {
+		return nil, nil, errors.New("no TCP or HTTP listen address")
+	}
+
+	if srv.Args.TCPListen != "" {
+		if srv.tcpLn, err = net.Listen("tcp", srv.Args.TCPListen); err != nil {
+			return nil, nil, errors.Wrap(err, "net.Listen")
+		}
+		tcpAddr = srv.tcpLn.Addr()
+		srv.wg.Add(1)
+		go srv.serveTCP()
+	}
+
+	if srv.Args.HTTPListen != "" {
+		var ln net.Listener
+		if ln, err = net.Listen("tcp", srv.Args.HTTPListen); err != nil {
+			if srv.tcpLn != nil {
+				srv.tcpLn.Close()
+				srv.wg.Wait()
+			}
+			return nil, nil, errors.Wrap(err, "net.Listen")
+		}
+		httpAddr = ln.Addr()
+		srv.httpSrv = &http.Server{Handler: http.HandlerFunc(srv.serveHTTP)}
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			srv.httpSrv.Serve(ln)
+		}()
+	}
+
+	srv.logger.Out(logrus.InfoLevel, logrus.Fields{"tcp": tcpAddr, "http": httpAddr, "respond": srv.Args.Responder.Mode}, "Mock server started.")
+	return tcpAddr, httpAddr, nil
+}
+
+// Stop stops the listeners and waits for the requests in progress.
+func (srv *MockServer) Stop() (err error) {
+	if srv.tcpLn != nil {
+		err = srv.tcpLn.Close()
+	}
+	if srv.httpSrv != nil {
+		if errS := srv.httpSrv.Shutdown(context.Background()); errS != nil && err == nil {
+			err = errS
+		}
+	}
+	srv.wg.Wait()
+
+	srv.logger.Out(logrus.InfoLevel, logrus.Fields{"tcp": srv.TCP.Received, "http": srv.HTTP.Received}, "Mock server stopped.")
+	return
+}
+
+// fault draws the response latency and whether to fail or drop the request.
+func (srv *MockServer) fault() (latency time.Duration, fail bool, drop bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.Args.Latency != nil {
+		latency = srv.Args.Latency.Next(srv.rng)
+	}
+	p := srv.rng.Float64()
+	drop = p < srv.Args.DropRate
+	fail = !drop && p < srv.Args.DropRate+srv.Args.ErrorRate
+
+	return
+}
+
+func (srv *MockServer) serveTCP() {
+	defer srv.wg.Done()
+	for {
+		conn, err := srv.tcpLn.Accept()
+		if err != nil {
+			// Listener is closed.
+			return
+		}
+
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			srv.handleTCP(conn)
+		}()
+	}
+}
+
+func (srv *MockServer) handleTCP(conn net.Conn) {
+	defer conn.Close()
+
+	msg, err := ReadMessage(conn)
+	if err != nil {
+		srv.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "remote": conn.RemoteAddr()}, "Cannot read the request.")
+		return
+	}
+	stat := &srv.TCP
+	atomic.AddUint64(&stat.Received, 1)
+	atomic.AddUint64(&stat.Bytes, uint64(len(msg)))
+
+	latency, fail, drop := srv.fault()
+	time.Sleep(latency)
+
+	if drop {
+		// Reset the connection so the client sees the error.
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetLinger(0)
+		}
+		atomic.AddUint64(&stat.Drops, 1)
+		return
+	}
+
+	// TCP responses have no status, the error response is the error status text.
+	var res []byte
+	if fail {
+		atomic.AddUint64(&stat.Errors, 1)
+		res = []byte(http.StatusText(srv.Args.ErrorStatus))
+	} else {
+		var rule *ResponseRule
+		if res, _, rule = srv.Args.Responder.Respond(msg); rule == nil && srv.Args.Responder.Mode == MapRespond {
+			atomic.AddUint64(&stat.Unmatched, 1)
+		}
+	}
+
+	if _, err = conn.Write(res); err != nil {
+		srv.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "remote": conn.RemoteAddr()}, "Cannot write the response.")
+		return
+	}
+	atomic.AddUint64(&stat.Responded, 1)
+}
+
+func (srv *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		srv.logger.Out(logrus.ErrorLevel, logrus.Fields{"err": err, "remote": r.RemoteAddr}, "Cannot read the request.")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stat := &srv.HTTP
+	atomic.AddUint64(&stat.Received, 1)
+	atomic.AddUint64(&stat.Bytes, uint64(len(body)))
+
+	latency, fail, drop := srv.fault()
+	time.Sleep(latency)
+
+	if drop {
+		atomic.AddUint64(&stat.Drops, 1)
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, errH := hj.Hijack(); errH == nil {
+				if tcpConn, ok := conn.(*net.TCPConn); ok {
+					tcpConn.SetLinger(0)
+				}
+				conn.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	if fail {
+		atomic.AddUint64(&stat.Errors, 1)
+		http.Error(w, http.StatusText(srv.Args.ErrorStatus), srv.Args.ErrorStatus)
+		atomic.AddUint64(&stat.Responded, 1)
+		return
+	}
+
+	res, status, rule := srv.Args.Responder.Respond(body)
+	if rule == nil && srv.Args.Responder.Mode == MapRespond {
+		atomic.AddUint64(&stat.Unmatched, 1)
+	}
+	w.WriteHeader(status)
+	w.Write(res)
+	atomic.AddUint64(&stat.Responded, 1)
+}
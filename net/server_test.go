@@ -0,0 +1,157 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"io/ioutil"
+	gonet "net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/net"
+	"github.com/alexstov/sling/throt"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MockServer", func() {
+	var (
+		t          testing.T
+		mockCtrl   *gomock.Controller
+		mockLogger *mock.MockLogger
+		dir        string
+	)
+
+	BeforeEach(func() {
+		var err error
+		mockCtrl = gomock.NewController(&t)
+		mockLogger = mock.NewMockLogger(mockCtrl)
+		mockLogger.EXPECT().Out(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		dir, err = ioutil.TempDir("", "serve")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+		os.RemoveAll(dir)
+	})
+
+	sendTCP := func(addr gonet.Addr, msg string) (string, error) {
+		conn, err := gonet.Dial("tcp", addr.String())
+		Expect(err).Should(BeNil())
+		defer conn.Close()
+		_, err = conn.Write([]byte(msg + net.MsgEndSequence))
+		Expect(err).Should(BeNil())
+		res, err := ioutil.ReadAll(conn)
+		return string(res), err
+	}
+
+	postHTTP := func(addr gonet.Addr, body string) (int, string, error) {
+		resp, err := http.Post("http://"+addr.String()+"/TR", "text/plain", strings.NewReader(body))
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		res, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(res), nil
+	}
+
+	It("echoes TCP and HTTP requests after the latency.", func() {
+		defer GinkgoRecover()
+		latency, _ := throt.ParseThink("20")
+		srv := net.NewMockServer(mockLogger, net.ServeArgs{TCPListen: "127.0.0.1:0", HTTPListen: "127.0.0.1:0", Latency: latency})
+		tcpAddr, httpAddr, err := srv.Start()
+		Expect(err).Should(BeNil())
+
+		start := time.Now()
+		Expect(sendTCP(tcpAddr, "ping")).To(Equal("ping"))
+		Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+		status, res, err := postHTTP(httpAddr, "hello")
+		Expect(err).Should(BeNil())
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(res).To(Equal("hello"))
+		Expect(srv.Stop()).Should(Succeed())
+
+		Expect(srv.TCP).To(Equal(net.ServeStat{Received: 1, Responded: 1, Bytes: 4}))
+		Expect(srv.HTTP).To(Equal(net.ServeStat{Received: 1, Responded: 1, Bytes: 5}))
+	})
+
+	It("responds with the first matching map rule.", func() {
+		defer GinkgoRecover()
+		Expect(ioutil.WriteFile(filepath.Join(dir, "balance.res"), []byte(`{"balance":10}`), 0644)).Should(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "busy.res"), []byte("busy"), 0644)).Should(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "map.yml"), []byte("- match: '\"op\":\\s*\"balance\"'\n  file: balance.res\n- match: transfer\n  file: busy.res\n  status: 503\n"), 0644)).Should(Succeed())
+		responder, err := net.ParseRespond("map:" + filepath.Join(dir, "map.yml"))
+		Expect(err).Should(BeNil())
+
+		srv := net.NewMockServer(mockLogger, net.ServeArgs{TCPListen: "127.0.0.1:0", HTTPListen: "127.0.0.1:0", Responder: responder})
+		tcpAddr, httpAddr, err := srv.Start()
+		Expect(err).Should(BeNil())
+		Expect(sendTCP(tcpAddr, `{"op": "balance"}`)).To(Equal(`{"balance":10}`))
+		Expect(sendTCP(tcpAddr, "unknown")).To(Equal(""))
+		status, res, err := postHTTP(httpAddr, "transfer")
+		Expect(err).Should(BeNil())
+		Expect(status).To(Equal(http.StatusServiceUnavailable))
+		Expect(res).To(Equal("busy"))
+		status, _, err = postHTTP(httpAddr, "unknown")
+		Expect(err).Should(BeNil())
+		Expect(status).To(Equal(http.StatusNotFound))
+		Expect(srv.Stop()).Should(Succeed())
+
+		Expect(srv.TCP.Unmatched).To(Equal(uint64(1)))
+		Expect(srv.HTTP.Unmatched).To(Equal(uint64(1)))
+		Expect(responder.Rules[0].Count).To(Equal(uint64(1)))
+		Expect(responder.Rules[1].Count).To(Equal(uint64(1)))
+	})
+
+	It("fails and drops the requests at the rates.", func() {
+		defer GinkgoRecover()
+		srv := net.NewMockServer(mockLogger, net.ServeArgs{TCPListen: "127.0.0.1:0", HTTPListen: "127.0.0.1:0", ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable})
+		tcpAddr, httpAddr, err := srv.Start()
+		Expect(err).Should(BeNil())
+		Expect(sendTCP(tcpAddr, "ping")).To(Equal("Service Unavailable"))
+		status, _, err := postHTTP(httpAddr, "hello")
+		Expect(err).Should(BeNil())
+		Expect(status).To(Equal(http.StatusServiceUnavailable))
+		Expect(srv.Stop()).Should(Succeed())
+		Expect(srv.TCP.Errors).To(Equal(uint64(1)))
+		Expect(srv.HTTP.Errors).To(Equal(uint64(1)))
+
+		srv = net.NewMockServer(mockLogger, net.ServeArgs{TCPListen: "127.0.0.1:0", HTTPListen: "127.0.0.1:0", DropRate: 1})
+		tcpAddr, httpAddr, err = srv.Start()
+		Expect(err).Should(BeNil())
+		_, err = sendTCP(tcpAddr, "ping")
+		Expect(err).ShouldNot(BeNil())
+		_, _, err = postHTTP(httpAddr, "hello")
+		Expect(err).ShouldNot(BeNil())
+		Expect(srv.Stop()).Should(Succeed())
+		Expect(srv.TCP.Drops).To(Equal(uint64(1)))
+		Expect(srv.HTTP.Drops).To(Equal(uint64(1)))
+	})
+
+	It("invalid response modes.", func() {
+		defer GinkgoRecover()
+		_, err := net.ParseRespond("proxy")
+		Expect(err).ShouldNot(BeNil())
+		_, err = net.ParseRespond("file:" + filepath.Join(dir, "missing.res"))
+		Expect(err).ShouldNot(BeNil())
+	})
+})
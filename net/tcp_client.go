@@ -109,11 +109,15 @@ func (clt TCPClient) Write(msg []byte, args *WriteArgs) (err error) {
 	}
 
 	if err != nil {
-		clt.logger.Out(logrus.InfoLevel, logrus.Fields{"rdLen": len(buf)}, "Successfully read the response.")
-	} else {
 		err = errors.Wrap(err, "Read response")
 		return
 	}
+	clt.logger.Out(logrus.InfoLevel, logrus.Fields{"rdLen": len(buf)}, "Successfully read the response.")
+
+	// Capture the response for the caller.
+	if args.Response != nil {
+		args.Response.Body = buf
+	}
 
 	// Save response to a file.
 	if args.SaveRes {
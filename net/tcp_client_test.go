@@ -0,0 +1,113 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"bufio"
+	"bytes"
+	gonet "net"
+	"strings"
+	"testing"
+
+	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/net"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCPClient", func() {
+	var (
+		t          testing.T
+		mockCtrl   *gomock.Controller
+		mockLogger *mock.MockLogger
+		mockFiler  *mock.MockFiler
+		ln         gonet.Listener
+		port       uint
+	)
+
+	BeforeEach(func() {
+		var err error
+		mockCtrl = gomock.NewController(&t)
+		mockLogger = mock.NewMockLogger(mockCtrl)
+		mockLogger.EXPECT().Out(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		mockFiler = mock.NewMockFiler(mockCtrl)
+		ln, err = gonet.Listen("tcp", "127.0.0.1:0")
+		Expect(err).Should(BeNil())
+		port = uint(ln.Addr().(*gonet.TCPAddr).Port)
+	})
+
+	AfterEach(func() {
+		ln.Close()
+		mockCtrl.Finish()
+	})
+
+	// respond answers one request read up to the message terminator.
+	respond := func(res string) {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rd := bufio.NewReader(conn)
+		var msg string
+		for !strings.HasSuffix(msg, net.MsgEndSequence) {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			msg += line
+		}
+		conn.Write([]byte(res))
+	}
+
+	It("captures and saves the response.", func() {
+		defer GinkgoRecover()
+		go respond("pong")
+		mockFiler.EXPECT().BuildFilePath("res", "007.res").Return("res/007.res", nil)
+		saved := make(chan string, 1)
+		clt, _ := net.NewTCPClient(mockLogger, mockFiler)
+		args := &net.WriteArgs{IPAddress: "127.0.0.1", Port: port, ReqID: 7, SaveRes: true, SaveResDir: "res",
+			Response: &net.Response{},
+			SaveResCallback: func(filepath string, buff *bytes.Buffer) error {
+				saved <- filepath + ":" + buff.String()
+				return nil
+			}}
+
+		Expect(clt.Write([]byte("ping"), args)).Should(Succeed())
+		Expect(string(args.Response.Body)).To(Equal("pong"))
+		Eventually(saved).Should(Receive(Equal("res/007.res:pong")))
+	})
+
+	It("returns the read error without saving the response.", func() {
+		defer GinkgoRecover()
+		go func() {
+			conn, err := ln.Accept()
+			if err == nil {
+				defer conn.Close()
+				bufio.NewReader(conn).ReadString('x')
+			}
+		}()
+		clt, _ := net.NewTCPClient(mockLogger, mockFiler)
+		args := &net.WriteArgs{IPAddress: "127.0.0.1", Port: port, TmoRdS: 1, SaveRes: true, Response: &net.Response{},
+			SaveResCallback: func(filepath string, buff *bytes.Buffer) error {
+				Fail("response saved")
+				return nil
+			}}
+
+		Expect(clt.Write([]byte("ping"), args)).ShouldNot(Succeed())
+		Expect(args.Response.Body).To(BeEmpty())
+	})
+})
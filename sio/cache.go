@@ -0,0 +1,170 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CacheFiler caches request bodies read through the Filer within the memory budget.
+// The least recently used bodies are evicted first.
+type CacheFiler struct {
+	Filer
+	budget  int64
+	size    int64
+	lru     *list.List
+	entries map[cacheKey]*list.Element
+	types   map[string]ContentType
+	stats   CacheStats
+	mu      sync.Mutex
+}
+
+// CacheStats cache statistics.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+type cacheKey struct {
+	path    string
+	archive bool
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	body []byte
+}
+
+// NewCacheFiler creates new cache decorating the filer with the memory budget in bytes.
+func NewCacheFiler(filer Filer, budget int64) *CacheFiler {
+	return &CacheFiler{Filer: filer, budget: budget, lru: list.New(),
+		entries: make(map[cacheKey]*list.Element), types: make(map[string]ContentType)}
+}
+
+// DetermineContentType returns the cached file content type.
+func (c *CacheFiler) DetermineContentType(filePath string) (contentType ContentType, err error) {
+	c.mu.Lock()
+	contentType, ok := c.types[filePath]
+	c.mu.Unlock()
+	if ok {
+		return contentType, nil
+	}
+
+	if contentType, err = c.Filer.DetermineContentType(filePath); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.types[filePath] = contentType
+	c.mu.Unlock()
+	return contentType, nil
+}
+
+// ReadFile returns the cached file content. The content must not be modified.
+func (c *CacheFiler) ReadFile(filename string) ([]byte, error) {
+	return c.read(cacheKey{path: filename}, c.Filer.ReadFile)
+}
+
+// ReadArchive returns the cached decompressed archive content. The content must not be modified.
+func (c *CacheFiler) ReadArchive(filename string) ([]byte, error) {
+	return c.read(cacheKey{path: filename, archive: true}, c.Filer.ReadArchive)
+}
+
+// Preload reads and decompresses the files into the cache.
+func (c *CacheFiler) Preload(paths []string) (err error) {
+	for _, path := range paths {
+		var contentType ContentType
+		if contentType, err = c.DetermineContentType(path); err != nil {
+			return errors.Wrap(err, path)
+		}
+
+		if contentType == GzipType || contentType == ZipType {
+			_, err = c.ReadArchive(path)
+		} else {
+			_, err = c.ReadFile(path)
+		}
+		if err != nil {
+			return errors.Wrap(err, path)
+		}
+	}
+
+	return nil
+}
+
+// Stats returns the cache statistics.
+func (c *CacheFiler) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.size
+	return stats
+}
+
+// read returns the cached content or reads and caches it.
+func (c *CacheFiler) read(key cacheKey, read func(string) ([]byte, error)) (body []byte, err error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return elem.Value.(*cacheEntry).body, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	if body, err = read(key.path); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Do not cache the content larger than the budget.
+	size := int64(len(body))
+	if size > c.budget {
+		return body, nil
+	}
+	if _, ok := c.entries[key]; ok {
+		return body, nil
+	}
+
+	for c.size+size > c.budget {
+		c.evict()
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, body: body})
+	c.size += size
+
+	return body, nil
+}
+
+// evict removes the least recently used content.
+func (c *CacheFiler) evict() {
+	elem := c.lru.Back()
+	if elem == nil {
+		return
+	}
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.body))
+	c.stats.Evictions++
+}
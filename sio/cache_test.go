@@ -0,0 +1,95 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio_test
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/mock"
+	"github.com/alexstov/sling/sio"
+)
+
+var _ = Describe("CacheFiler", func() {
+	var (
+		t         testing.T
+		mockCtrl  *gomock.Controller
+		mockFiler *mock.MockFiler
+		cache     *sio.CacheFiler
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(&t)
+		mockFiler = mock.NewMockFiler(mockCtrl)
+		cache = sio.NewCacheFiler(mockFiler, 10)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Preload", func() {
+		It("reads and decompresses files once.", func() {
+			defer GinkgoRecover()
+			mockFiler.EXPECT().DetermineContentType("a.dat").Return(sio.UnknownType, nil).Times(1)
+			mockFiler.EXPECT().DetermineContentType("b.gz").Return(sio.GzipType, nil).Times(1)
+			mockFiler.EXPECT().ReadFile("a.dat").Return([]byte("aaa"), nil).Times(1)
+			mockFiler.EXPECT().ReadArchive("b.gz").Return([]byte("bbb"), nil).Times(1)
+
+			Expect(cache.Preload([]string{"a.dat", "b.gz"})).Should(Succeed())
+			for i := 0; i < 3; i++ {
+				Expect(cache.DetermineContentType("a.dat")).To(Equal(sio.UnknownType))
+				Expect(cache.ReadFile("a.dat")).To(Equal([]byte("aaa")))
+				Expect(cache.ReadArchive("b.gz")).To(Equal([]byte("bbb")))
+			}
+
+			stats := cache.Stats()
+			Expect(stats.Hits).To(Equal(uint64(6)))
+			Expect(stats.Misses).To(Equal(uint64(2)))
+			Expect(stats.Bytes).To(Equal(int64(6)))
+		})
+	})
+
+	Describe("ReadFile", func() {
+		It("evicts the least recently used content over budget.", func() {
+			defer GinkgoRecover()
+			mockFiler.EXPECT().ReadFile("a").Return([]byte("aaaa"), nil).Times(1)
+			mockFiler.EXPECT().ReadFile("b").Return([]byte("bbbb"), nil).Times(2)
+			mockFiler.EXPECT().ReadFile("c").Return([]byte("cccc"), nil).Times(1)
+
+			cache.ReadFile("a")
+			cache.ReadFile("b")
+			cache.ReadFile("a")
+			cache.ReadFile("c") // evicts b
+			cache.ReadFile("a")
+			cache.ReadFile("b")
+
+			stats := cache.Stats()
+			Expect(stats.Evictions).To(Equal(uint64(2)))
+			Expect(stats.Bytes).To(BeNumerically("<=", 10))
+		})
+		It("does not cache content over budget.", func() {
+			defer GinkgoRecover()
+			mockFiler.EXPECT().ReadFile("big").Return(make([]byte, 11), nil).Times(2)
+
+			cache.ReadFile("big")
+			cache.ReadFile("big")
+			Expect(cache.Stats().Entries).To(Equal(0))
+		})
+	})
+})
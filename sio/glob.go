@@ -0,0 +1,187 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Order file send order.
+type Order int
+
+const (
+	// NameOrder sorts files by path.
+	NameOrder Order = iota
+	// MtimeOrder sorts files by modification time, oldest first.
+	MtimeOrder
+	// SizeOrder sorts files by size, smallest first.
+	SizeOrder
+	// ShuffleOrder shuffles files using the seed.
+	ShuffleOrder
+)
+
+func (o Order) String() string {
+	return [...]string{"name", "mtime", "size", "shuffle"}[o]
+}
+
+// ParseOrder parses string to Order.
+func ParseOrder(str string) (order Order, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "", "name":
+		return NameOrder, nil
+	case "mtime":
+		return MtimeOrder, nil
+	case "size":
+		return SizeOrder, nil
+	case "shuffle":
+		return ShuffleOrder, nil
+	}
+
+	return NameOrder, fmt.Errorf("unknown send order %q", str)
+}
+
+// GlobFile file found by Glob.
+type GlobFile struct {
+	Path string
+	Info os.FileInfo
+}
+
+// Glob finds the files in the directories matching any of the include globs and none of the exclude globs.
+// The globs are matched against the path relative to the directory, ** matches any number of directories.
+// Exclude globs without a separator are matched against the file or directory name.
+func Glob(dirs []string, includes []string, excludes []string) (files []GlobFile, err error) {
+	deep := recursive(includes)
+
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == dir {
+				return nil
+			}
+
+			var rel string
+			if rel, err = filepath.Rel(dir, path); err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+
+			var matched bool
+			if matched, err = matchAny(excludes, rel, true); err != nil {
+				return err
+			} else if matched {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if !deep {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if matched, err = matchAny(includes, rel, false); err != nil {
+				return err
+			} else if matched && !seen[path] {
+				seen[path] = true
+				files = append(files, GlobFile{Path: path, Info: info})
+			}
+			return nil
+		})
+		if err != nil {
+			err = errors.Wrap(err, "filepath.Walk")
+			return
+		}
+	}
+
+	return
+}
+
+// SortFiles sorts the files in the order, the seed is used to shuffle.
+func SortFiles(files []GlobFile, order Order, seed int64) {
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	switch order {
+	case MtimeOrder:
+		sort.SliceStable(files, func(i, j int) bool { return files[i].Info.ModTime().Before(files[j].Info.ModTime()) })
+	case SizeOrder:
+		sort.SliceStable(files, func(i, j int) bool { return files[i].Info.Size() < files[j].Info.Size() })
+	case ShuffleOrder:
+		rnd := rand.New(rand.NewSource(seed))
+		rnd.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+	}
+}
+
+// MatchGlob reports whether the slash separated path matches the glob, ** matches any number of directories.
+func MatchGlob(pattern string, path string) (matched bool, err error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern []string, path []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try to match the rest of the pattern at every depth.
+			for i := 0; i <= len(path); i++ {
+				if matched, err := matchSegments(pattern[1:], path[i:]); matched || err != nil {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(path) == 0 {
+			return false, nil
+		}
+		if matched, err := filepath.Match(pattern[0], path[0]); !matched || err != nil {
+			return false, err
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+
+	return len(path) == 0, nil
+}
+
+// matchAny reports whether the path matches any of the globs.
+func matchAny(patterns []string, rel string, byName bool) (bool, error) {
+	for _, pattern := range patterns {
+		target := rel
+		if byName && !strings.Contains(pattern, "/") {
+			target = filepath.Base(rel)
+		}
+		if matched, err := MatchGlob(pattern, target); matched || err != nil {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// recursive reports whether any of the globs can match files in subdirectories.
+func recursive(patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "/") || strings.Contains(pattern, "**") {
+			return true
+		}
+	}
+	return false
+}
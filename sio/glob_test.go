@@ -0,0 +1,139 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/sio"
+)
+
+var _ = Describe("Glob", func() {
+	var (
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "glob")
+		Expect(err).Should(BeNil())
+
+		for i, name := range []string{"a.dat", "b.dat", "a.bak.dat", "sub/c.dat", "sub/deep/d.dat", "skip/e.dat", "f.txt"} {
+			path := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).Should(Succeed())
+			Expect(ioutil.WriteFile(path, make([]byte, 10-i), 0644)).Should(Succeed())
+			mtime := time.Now().Add(time.Duration(-i) * time.Hour)
+			Expect(os.Chtimes(path, mtime, mtime)).Should(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	paths := func(files []sio.GlobFile) (rel []string) {
+		for _, file := range files {
+			path, _ := filepath.Rel(dir, file.Path)
+			rel = append(rel, filepath.ToSlash(path))
+		}
+		return
+	}
+
+	Describe("MatchGlob", func() {
+		It("** matches any number of directories.", func() {
+			defer GinkgoRecover()
+			Expect(sio.MatchGlob("**/*.dat", "a.dat")).To(BeTrue())
+			Expect(sio.MatchGlob("**/*.dat", "sub/deep/d.dat")).To(BeTrue())
+			Expect(sio.MatchGlob("sub/**/d.dat", "sub/d.dat")).To(BeTrue())
+			Expect(sio.MatchGlob("*.dat", "sub/c.dat")).To(BeFalse())
+			Expect(sio.MatchGlob("**/*.dat", "f.txt")).To(BeFalse())
+		})
+	})
+
+	Describe("Glob", func() {
+		Context("single wildcard", func() {
+			It("does not descend into subdirectories.", func() {
+				defer GinkgoRecover()
+				files, err := sio.Glob([]string{dir}, []string{"*.dat"}, nil)
+				Expect(err).Should(BeNil())
+				Expect(paths(files)).To(ConsistOf("a.bak.dat", "a.dat", "b.dat"))
+			})
+		})
+		Context("recursive wildcards with excludes", func() {
+			It("skips excluded files and directories.", func() {
+				defer GinkgoRecover()
+				files, err := sio.Glob([]string{dir}, []string{"**/*.dat", "*.txt"}, []string{"*.bak.dat", "skip"})
+				Expect(err).Should(BeNil())
+				Expect(paths(files)).To(ConsistOf("a.dat", "b.dat", "f.txt", "sub/c.dat", "sub/deep/d.dat"))
+			})
+		})
+		Context("multiple directories", func() {
+			It("finds each file once.", func() {
+				defer GinkgoRecover()
+				files, err := sio.Glob([]string{dir, filepath.Join(dir, "sub")}, []string{"**/c.dat"}, nil)
+				Expect(err).Should(BeNil())
+				Expect(files).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("SortFiles", func() {
+		var files []sio.GlobFile
+
+		BeforeEach(func() {
+			var err error
+			files, err = sio.Glob([]string{dir}, []string{"*.dat"}, nil)
+			Expect(err).Should(BeNil())
+		})
+
+		It("name order.", func() {
+			defer GinkgoRecover()
+			sio.SortFiles(files, sio.NameOrder, 0)
+			Expect(paths(files)).To(Equal([]string{"a.bak.dat", "a.dat", "b.dat"}))
+		})
+		It("mtime order.", func() {
+			defer GinkgoRecover()
+			sio.SortFiles(files, sio.MtimeOrder, 0)
+			Expect(paths(files)).To(Equal([]string{"a.bak.dat", "b.dat", "a.dat"}))
+		})
+		It("size order.", func() {
+			defer GinkgoRecover()
+			sio.SortFiles(files, sio.SizeOrder, 0)
+			Expect(paths(files)).To(Equal([]string{"a.bak.dat", "b.dat", "a.dat"}))
+		})
+		It("shuffle is reproducible with the seed.", func() {
+			defer GinkgoRecover()
+			other := append([]sio.GlobFile(nil), files...)
+			sio.SortFiles(files, sio.ShuffleOrder, 42)
+			sio.SortFiles(other, sio.ShuffleOrder, 42)
+			Expect(paths(files)).To(Equal(paths(other)))
+		})
+	})
+
+	Describe("ParseOrder", func() {
+		It("unknown order.", func() {
+			defer GinkgoRecover()
+			_, err := sio.ParseOrder("random")
+			Expect(err).ShouldNot(BeNil())
+			Expect(sio.ParseOrder("MTIME")).To(Equal(sio.MtimeOrder))
+		})
+	})
+})
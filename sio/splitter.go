@@ -0,0 +1,183 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxRecordSize the largest input record.
+const MaxRecordSize = 64 * 1024 * 1024
+
+// SplitMode input split mode.
+type SplitMode int
+
+const (
+	// DelimSplit records end with the delimiter, newline by default.
+	DelimSplit SplitMode = iota
+	// NulSplit records end with the NUL byte.
+	NulSplit
+	// LenSplit records start with the 4-byte big-endian length.
+	LenSplit
+	// JSONLSplit records are JSON lines with base64 body.
+	JSONLSplit
+)
+
+func (m SplitMode) String() string {
+	return [...]string{"delim", "nul", "len", "jsonl"}[m]
+}
+
+// ParseSplitMode parses string to SplitMode.
+func ParseSplitMode(str string) (mode SplitMode, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "", "delim", "line":
+		return DelimSplit, nil
+	case "nul":
+		return NulSplit, nil
+	case "len":
+		return LenSplit, nil
+	case "jsonl":
+		return JSONLSplit, nil
+	}
+
+	return DelimSplit, fmt.Errorf("unknown split mode %q", str)
+}
+
+// ParseDelim parses the delimiter with Go escape sequences, e.g. \n or \r\n.
+// The delimiter that is not a valid escaped string is used as is.
+func ParseDelim(str string) (delim []byte) {
+	if str == "" {
+		return []byte("\n")
+	}
+
+	if unquoted, err := strconv.Unquote(`"` + strings.Replace(str, `"`, `\"`, -1) + `"`); err == nil {
+		return []byte(unquoted)
+	}
+
+	return []byte(str)
+}
+
+// InputRecord JSONL input record.
+type InputRecord struct {
+	Body []byte `json:"body"`
+}
+
+// Splitter splits the input into records.
+type Splitter struct {
+	scanner *bufio.Scanner
+	mode    SplitMode
+}
+
+// NewSplitter creates new input splitter.
+func NewSplitter(r io.Reader, mode SplitMode, delim []byte) (splitter *Splitter, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxRecordSize)
+
+	switch mode {
+	case DelimSplit, JSONLSplit:
+		if mode == JSONLSplit {
+			delim = []byte("\n")
+		}
+		if len(delim) == 0 {
+			return nil, errors.New("empty delimiter")
+		}
+		scanner.Split(splitDelim(delim))
+	case NulSplit:
+		scanner.Split(splitDelim([]byte{0}))
+	case LenSplit:
+		scanner.Split(splitLen)
+	default:
+		return nil, fmt.Errorf("unknown split mode %v", mode)
+	}
+
+	return &Splitter{scanner: scanner, mode: mode}, nil
+}
+
+// Next returns the next record, io.EOF at the end of input. Empty records are skipped.
+func (s *Splitter) Next() (record []byte, err error) {
+	for s.scanner.Scan() {
+		token := s.scanner.Bytes()
+		if len(token) == 0 && s.mode != LenSplit {
+			continue
+		}
+
+		if s.mode == JSONLSplit {
+			var rec InputRecord
+			if err = json.Unmarshal(token, &rec); err != nil {
+				err = errors.Wrap(err, "json.Unmarshal")
+				return
+			}
+			if rec.Body == nil {
+				return nil, errors.New("input record without body")
+			}
+			return rec.Body, nil
+		}
+
+		record = make([]byte, len(token))
+		copy(record, token)
+		return record, nil
+	}
+
+	if err = s.scanner.Err(); err != nil {
+		err = errors.Wrap(err, "bufio.Scanner")
+		return
+	}
+	return nil, io.EOF
+}
+
+// splitDelim splits records ending with the delimiter.
+func splitDelim(delim []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// splitLen splits records starting with the 4-byte big-endian length.
+func splitLen(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) < 4 {
+		if atEOF && len(data) > 0 {
+			return 0, nil, errors.New("truncated record length")
+		}
+		return 0, nil, nil
+	}
+
+	size := int(binary.BigEndian.Uint32(data))
+	if size > MaxRecordSize {
+		return 0, nil, fmt.Errorf("record length %d exceeds %d", size, MaxRecordSize)
+	}
+	if len(data) < 4+size {
+		if atEOF {
+			return 0, nil, errors.New("truncated record")
+		}
+		return 0, nil, nil
+	}
+
+	return 4 + size, data[4 : 4+size], nil
+}
@@ -0,0 +1,106 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/sio"
+)
+
+var _ = Describe("Splitter", func() {
+	records := func(r io.Reader, mode sio.SplitMode, delim []byte) (recs []string, err error) {
+		var splitter *sio.Splitter
+		if splitter, err = sio.NewSplitter(r, mode, delim); err != nil {
+			return
+		}
+		for {
+			var rec []byte
+			if rec, err = splitter.Next(); err == io.EOF {
+				return recs, nil
+			} else if err != nil {
+				return
+			}
+			recs = append(recs, string(rec))
+		}
+	}
+
+	Describe("Next", func() {
+		Context("delimiter", func() {
+			It("splits lines and skips empty ones.", func() {
+				defer GinkgoRecover()
+				recs, err := records(strings.NewReader("one\ntwo\n\nthree"), sio.DelimSplit, []byte("\n"))
+				Expect(err).Should(BeNil())
+				Expect(recs).To(Equal([]string{"one", "two", "three"}))
+			})
+			It("splits by multi-byte delimiter.", func() {
+				defer GinkgoRecover()
+				delim := sio.ParseDelim(`\r\n--\r\n`)
+				recs, err := records(strings.NewReader("a\nb\r\n--\r\nc"), sio.DelimSplit, delim)
+				Expect(err).Should(BeNil())
+				Expect(recs).To(Equal([]string{"a\nb", "c"}))
+			})
+		})
+		Context("NUL", func() {
+			It("splits by NUL byte.", func() {
+				defer GinkgoRecover()
+				recs, err := records(strings.NewReader("a\nb\x00c\x00"), sio.NulSplit, nil)
+				Expect(err).Should(BeNil())
+				Expect(recs).To(Equal([]string{"a\nb", "c"}))
+			})
+		})
+		Context("length prefix", func() {
+			It("splits by big-endian length.", func() {
+				defer GinkgoRecover()
+				input := bytes.NewBuffer([]byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 1, 'd'})
+				recs, err := records(input, sio.LenSplit, nil)
+				Expect(err).Should(BeNil())
+				Expect(recs).To(Equal([]string{"abc", "d"}))
+			})
+			It("fails on truncated record.", func() {
+				defer GinkgoRecover()
+				_, err := records(bytes.NewBuffer([]byte{0, 0, 0, 3, 'a'}), sio.LenSplit, nil)
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+		Context("JSONL", func() {
+			It("decodes base64 bodies.", func() {
+				defer GinkgoRecover()
+				recs, err := records(strings.NewReader(`{"body": "aGVsbG8="}`+"\n"+`{"body": "d29ybGQ="}`+"\n"), sio.JSONLSplit, nil)
+				Expect(err).Should(BeNil())
+				Expect(recs).To(Equal([]string{"hello", "world"}))
+			})
+			It("fails without body.", func() {
+				defer GinkgoRecover()
+				_, err := records(strings.NewReader(`{"other": 1}`), sio.JSONLSplit, nil)
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+	})
+
+	Describe("ParseSplitMode", func() {
+		It("unknown mode.", func() {
+			defer GinkgoRecover()
+			Expect(sio.ParseSplitMode("LEN")).To(Equal(sio.LenSplit))
+			_, err := sio.ParseSplitMode("xml")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+})
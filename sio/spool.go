@@ -0,0 +1,327 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProcessingDir spool subdirectory for the files being sent.
+	ProcessingDir = "processing"
+	// SentDir spool subdirectory for sent files.
+	SentDir = "sent"
+	// FailedDir spool subdirectory for failed files.
+	FailedDir = "failed"
+)
+
+// Spool watches the directories and emits new matching files once they settle.
+// A file is claimed by moving it to the processing subdirectory before it is emitted and moved to sent or failed
+// once sent. The delivery is at-least-once: a file left in processing by a crash is sent again on restart, even when
+// the crash came after the send and before the move, so the receiver must tolerate duplicates.
+type Spool struct {
+	Dirs     []string
+	Includes []string
+	Excludes []string
+	Settle   time.Duration
+	watcher  *fsnotify.Watcher
+	pending  map[string]*time.Timer
+	ready    chan string
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewSpool creates new spool for the directories.
+func NewSpool(dirs []string, includes []string, excludes []string, settle time.Duration) (spool *Spool, err error) {
+	spool = &Spool{Dirs: dirs, Includes: includes, Settle: settle,
+		pending: make(map[string]*time.Timer), ready: make(chan string), done: make(chan struct{})}
+	spool.Excludes = append([]string{ProcessingDir, SentDir, FailedDir}, excludes...)
+
+	if spool.watcher, err = fsnotify.NewWatcher(); err != nil {
+		err = errors.Wrap(err, "fsnotify.NewWatcher")
+		return nil, err
+	}
+
+	return spool, nil
+}
+
+// Run emits the claimed paths of the files already spooled and the new files as they arrive until the context is done.
+// The out channel is closed when Run returns.
+func (spool *Spool) Run(ctx context.Context, out chan<- string) (err error) {
+	defer close(out)
+	defer close(spool.done)
+	defer spool.watcher.Close()
+
+	// Return the files claimed by the interrupted runs to the spool.
+	for _, dir := range spool.Dirs {
+		if err = spool.recover(dir); err != nil {
+			return
+		}
+	}
+
+	// Watch the directories and the subdirectories the globs can match.
+	for _, dir := range spool.Dirs {
+		if err = spool.watch(dir); err != nil {
+			return
+		}
+	}
+
+	// Emit the files left from the previous runs.
+	var files []GlobFile
+	if files, err = Glob(spool.Dirs, spool.Includes, spool.Excludes); err != nil {
+		return
+	}
+	SortFiles(files, NameOrder, 0)
+	for _, file := range files {
+		spool.touch(file.Path)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			spool.mu.Lock()
+			for path, timer := range spool.pending {
+				timer.Stop()
+				delete(spool.pending, path)
+			}
+			spool.mu.Unlock()
+			return nil
+		case path := <-spool.ready:
+			select {
+			case out <- path:
+			case <-ctx.Done():
+				spool.release(path)
+			}
+		case event, ok := <-spool.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			if info, statErr := os.Stat(event.Name); statErr == nil && info.IsDir() {
+				// Watch new subdirectory and pick up the files moved in with it.
+				if spool.watchable(event.Name) {
+					if err = spool.watch(event.Name); err != nil {
+						return
+					}
+					spool.scan(event.Name)
+				}
+			} else if _, _, matched := spool.match(event.Name); matched {
+				spool.touch(event.Name)
+			}
+		case err, ok := <-spool.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return errors.Wrap(err, "fsnotify.Watcher")
+		}
+	}
+}
+
+// Finish moves the claimed file to the sent or failed subdirectory of its spool directory.
+func (spool *Spool) Finish(path string, sendErr error) (err error) {
+	var dir, rel string
+	if dir, rel, err = spool.claimed(path); err != nil {
+		return
+	}
+
+	sub := SentDir
+	if sendErr != nil {
+		sub = FailedDir
+	}
+	return move(path, filepath.Join(dir, sub, filepath.FromSlash(rel)))
+}
+
+// recover moves the files left in the processing subdirectory back to the spool directory.
+func (spool *Spool) recover(dir string) error {
+	processing := filepath.Join(dir, ProcessingDir)
+	return filepath.Walk(processing, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return errors.Wrap(err, "filepath.Walk")
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(processing, path)
+		return move(path, filepath.Join(dir, rel))
+	})
+}
+
+// claim moves the file to the processing subdirectory of its spool directory, returns the claimed path.
+func (spool *Spool) claim(path string) (claimed string, err error) {
+	dir, rel, _ := spool.match(path)
+	if dir == "" {
+		return "", errors.Errorf("file %q is not in the spool", path)
+	}
+
+	claimed = filepath.Join(dir, ProcessingDir, filepath.FromSlash(rel))
+	if err = move(path, claimed); err != nil {
+		return "", err
+	}
+	return claimed, nil
+}
+
+// release moves the claimed file back to the spool directory.
+func (spool *Spool) release(path string) (err error) {
+	var dir, rel string
+	if dir, rel, err = spool.claimed(path); err != nil {
+		return
+	}
+	return move(path, filepath.Join(dir, filepath.FromSlash(rel)))
+}
+
+// claimed returns the spool directory and the original relative path of the claimed file.
+func (spool *Spool) claimed(path string) (dir string, rel string, err error) {
+	dir, rel, _ = spool.match(path)
+	if dir == "" || !strings.HasPrefix(rel, ProcessingDir+"/") {
+		return "", "", errors.Errorf("file %q is not claimed in the spool", path)
+	}
+	return dir, strings.TrimPrefix(rel, ProcessingDir+"/"), nil
+}
+
+// move renames the file creating the destination directory.
+func move(src string, dst string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return errors.Wrap(err, "os.MkdirAll")
+	}
+	if err = os.Rename(src, dst); err != nil {
+		return errors.Wrap(err, "os.Rename")
+	}
+	return nil
+}
+
+// scan emits the matching files in the directory.
+func (spool *Spool) scan(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			if _, _, matched := spool.match(path); matched {
+				spool.touch(path)
+			}
+		}
+		return nil
+	})
+}
+
+// touch claims and emits the file once it has not changed for the settle delay.
+func (spool *Spool) touch(path string) {
+	spool.mu.Lock()
+	defer spool.mu.Unlock()
+
+	if timer, ok := spool.pending[path]; ok {
+		timer.Reset(spool.Settle)
+		return
+	}
+	spool.pending[path] = time.AfterFunc(spool.Settle, func() {
+		spool.mu.Lock()
+		if _, ok := spool.pending[path]; !ok {
+			spool.mu.Unlock()
+			return
+		}
+		delete(spool.pending, path)
+		spool.mu.Unlock()
+
+		// The file is gone when it was claimed already.
+		claimed, err := spool.claim(path)
+		if err != nil {
+			return
+		}
+		select {
+		case spool.ready <- claimed:
+		case <-spool.done:
+			// Not emitted, return the file to the spool.
+			spool.release(claimed)
+		}
+	})
+}
+
+// match returns the spool directory and relative path of the file and whether the file matches the globs.
+func (spool *Spool) match(path string) (dir string, rel string, matched bool) {
+	for _, d := range spool.Dirs {
+		if r, err := filepath.Rel(d, path); err == nil && r != "." && !strings.HasPrefix(r, "..") {
+			dir, rel = d, filepath.ToSlash(r)
+			break
+		}
+	}
+	if dir == "" {
+		return
+	}
+
+	// Skip excluded files and files in excluded directories.
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		if excluded, err := matchAny(spool.Excludes, strings.Join(parts[:i+1], "/"), true); err != nil || excluded {
+			return dir, rel, false
+		}
+	}
+	if len(parts) == 1 || recursive(spool.Includes) {
+		matched, _ = matchAny(spool.Includes, rel, false)
+	}
+	return
+}
+
+// watch adds the directory and, for recursive globs, its subdirectories to the watcher.
+func (spool *Spool) watch(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if !spool.watchable(path) {
+			return filepath.SkipDir
+		}
+		if err = spool.watcher.Add(path); err != nil {
+			return errors.Wrap(err, "fsnotify.Watcher.Add")
+		}
+		return nil
+	})
+}
+
+// watchable reports whether the directory is a spool directory or its subdirectory the globs can match.
+func (spool *Spool) watchable(path string) bool {
+	for _, dir := range spool.Dirs {
+		if path == dir {
+			return true
+		}
+	}
+	if !recursive(spool.Includes) {
+		return false
+	}
+
+	dir, rel, _ := spool.match(path)
+	if dir == "" {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		if excluded, err := matchAny(spool.Excludes, strings.Join(parts[:i+1], "/"), true); err != nil || excluded {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sio_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/sio"
+)
+
+var _ = Describe("Spool", func() {
+	var (
+		dir    string
+		spool  *sio.Spool
+		files  chan string
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "spool")
+		Expect(err).Should(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "old.dat"), []byte("old"), 0644)).Should(Succeed())
+
+		spool, err = sio.NewSpool([]string{dir}, []string{"*.dat"}, nil, 50*time.Millisecond)
+		Expect(err).Should(BeNil())
+
+		files = make(chan string)
+		ctx, cancel = context.WithCancel(context.Background())
+		go spool.Run(ctx, files)
+	})
+
+	AfterEach(func() {
+		cancel()
+		for range files {
+		}
+		os.RemoveAll(dir)
+	})
+
+	Describe("Run", func() {
+		It("emits spooled and new matching files once.", func() {
+			defer GinkgoRecover()
+			Eventually(files, time.Second).Should(Receive(Equal(filepath.Join(dir, sio.ProcessingDir, "old.dat"))))
+			Expect(filepath.Join(dir, "old.dat")).ShouldNot(BeAnExistingFile())
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "new.txt"), []byte("skip"), 0644)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "new.dat"), []byte("new"), 0644)).Should(Succeed())
+			Eventually(files, time.Second).Should(Receive(Equal(filepath.Join(dir, sio.ProcessingDir, "new.dat"))))
+			Consistently(files, 200*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("emits the files claimed by an interrupted run again.", func() {
+			defer GinkgoRecover()
+			Eventually(files, time.Second).Should(Receive(Equal(filepath.Join(dir, sio.ProcessingDir, "old.dat"))))
+			cancel()
+			for range files {
+			}
+
+			var err error
+			spool, err = sio.NewSpool([]string{dir}, []string{"*.dat"}, nil, 50*time.Millisecond)
+			Expect(err).Should(BeNil())
+			files = make(chan string)
+			ctx, cancel = context.WithCancel(context.Background())
+			go spool.Run(ctx, files)
+
+			Eventually(files, time.Second).Should(Receive(Equal(filepath.Join(dir, sio.ProcessingDir, "old.dat"))))
+			Consistently(files, 200*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	Describe("Finish", func() {
+		It("moves files to sent and failed.", func() {
+			defer GinkgoRecover()
+			var path string
+			Eventually(files, time.Second).Should(Receive(&path))
+			Expect(spool.Finish(path, nil)).Should(Succeed())
+			Expect(filepath.Join(dir, sio.SentDir, "old.dat")).Should(BeARegularFile())
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "bad.dat"), []byte("bad"), 0644)).Should(Succeed())
+			Eventually(files, time.Second).Should(Receive(&path))
+			Expect(spool.Finish(path, errors.New("failed"))).Should(Succeed())
+			Expect(filepath.Join(dir, sio.FailedDir, "bad.dat")).Should(BeARegularFile())
+			Consistently(files, 200*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+})
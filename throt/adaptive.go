@@ -0,0 +1,219 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Feedback receives the endpoint response status and the Retry-After delay to adapt the rate.
+type Feedback interface {
+	Observe(status int, retryAfter time.Duration)
+}
+
+// AdaptiveArgs adaptive limiter settings.
+type AdaptiveArgs struct {
+	Decrease float64
+	Step     float64
+	MinRate  float64
+	Statuses []int
+	Interval time.Duration
+}
+
+// RateSample effective rate over the interval ending at the offset from the start.
+type RateSample struct {
+	At       time.Duration
+	Limit    float64
+	Achieved float64
+}
+
+// AdaptiveLimiter decreases the rate multiplicatively when the endpoint throttles and recovers
+// it additively by step every second up to the configured rate (AIMD). Retry-After pauses sending.
+type AdaptiveLimiter struct {
+	Args        AdaptiveArgs
+	Decreases   uint64
+	Pauses      uint64
+	limiter     Limiter
+	mu          sync.Mutex
+	max         rate.Limit
+	limit       rate.Limit
+	ceiling     float64
+	adjusted    time.Time
+	pausedUntil time.Time
+	since       time.Time
+	throttled   time.Duration
+	start       time.Time
+	window      time.Time
+	count       uint64
+	samples     []RateSample
+}
+
+// NewAdaptiveLimiter creates new adaptive limiter around the limiter, its limit is the highest rate.
+func NewAdaptiveLimiter(limiter Limiter, args AdaptiveArgs) *AdaptiveLimiter {
+	if args.Interval <= 0 {
+		args.Interval = time.Second
+	}
+	now := time.Now()
+	return &AdaptiveLimiter{Args: args, limiter: limiter, max: limiter.Limit(), limit: limiter.Limit(), start: now, window: now}
+}
+
+// Wait waits for the rate limit and the Retry-After pause. The requests reserved at the rate before
+// the pause wait for the current rate again after it.
+func (l *AdaptiveLimiter) Wait(ctx context.Context) (err error) {
+	for {
+		if err = l.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		l.mu.Lock()
+		pause := time.Until(l.pausedUntil)
+		l.mu.Unlock()
+		if pause <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(pause)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	l.mu.Lock()
+	l.sample(time.Now())
+	l.count++
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Limit returns the current rate limit.
+func (l *AdaptiveLimiter) Limit() rate.Limit {
+	return l.limiter.Limit()
+}
+
+// SetLimit sets the highest rate and the current rate limit.
+func (l *AdaptiveLimiter) SetLimit(newLimit rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.max = newLimit
+	l.setLimit(newLimit, time.Now())
+}
+
+// Observe decreases the rate on the throttling status and pauses sending for Retry-After.
+// The rate is decreased once a second since the concurrent responses report the same overload.
+// Other responses recover the rate by step once a second, the unlimited rate is restored once
+// the recovery passes the rate achieved before the first decrease.
+func (l *AdaptiveLimiter) Observe(status int, retryAfter time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.throttling(status) {
+		if l.limit < l.max && now.Sub(l.adjusted) >= time.Second {
+			next := float64(l.limit) + l.Args.Step
+			if l.max == rate.Inf && next >= l.ceiling {
+				l.setLimit(rate.Inf, now)
+			} else {
+				l.setLimit(rate.Limit(math.Min(float64(l.max), next)), now)
+			}
+		}
+		return
+	}
+
+	if until := now.Add(retryAfter); retryAfter > 0 && until.After(l.pausedUntil) {
+		l.pausedUntil = until
+		l.Pauses++
+	}
+	if l.limit < l.max && now.Sub(l.adjusted) < time.Second {
+		return
+	}
+
+	// Unlimited rate decreases from the achieved one.
+	current := float64(l.limit)
+	if l.limit == rate.Inf {
+		current = float64(l.count) / now.Sub(l.window).Seconds()
+		l.ceiling = current
+	}
+	l.Decreases++
+	l.setLimit(rate.Limit(math.Max(l.Args.MinRate, current*l.Args.Decrease)), now)
+}
+
+// Throttled returns the time spent below the highest rate.
+func (l *AdaptiveLimiter) Throttled() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.since.IsZero() {
+		return l.throttled
+	}
+	return l.throttled + time.Since(l.since)
+}
+
+// Samples returns the effective rate over time including the current partial interval.
+func (l *AdaptiveLimiter) Samples() (samples []RateSample) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sample(now)
+	samples = append(samples, l.samples...)
+	if elapsed := now.Sub(l.window); l.count > 0 && elapsed > 0 {
+		samples = append(samples, RateSample{At: now.Sub(l.start), Limit: float64(l.limit), Achieved: float64(l.count) / elapsed.Seconds()})
+	}
+
+	return
+}
+
+func (l *AdaptiveLimiter) throttling(status int) bool {
+	for _, s := range l.Args.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// setLimit sets the current limit and tracks the time spent throttled.
+func (l *AdaptiveLimiter) setLimit(limit rate.Limit, now time.Time) {
+	l.limit = limit
+	l.adjusted = now
+	l.limiter.SetLimit(limit)
+
+	if limit < l.max && l.since.IsZero() {
+		l.since = now
+	} else if limit >= l.max && !l.since.IsZero() {
+		l.throttled += now.Sub(l.since)
+		l.since = time.Time{}
+	}
+}
+
+// sample closes the elapsed intervals.
+func (l *AdaptiveLimiter) sample(now time.Time) {
+	for now.Sub(l.window) >= l.Args.Interval {
+		l.window = l.window.Add(l.Args.Interval)
+		l.samples = append(l.samples, RateSample{At: l.window.Sub(l.start), Limit: float64(l.limit),
+			Achieved: float64(l.count) / l.Args.Interval.Seconds()})
+		l.count = 0
+	}
+}
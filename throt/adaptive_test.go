@@ -0,0 +1,126 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+
+	. "github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("AdaptiveLimiter", func() {
+	var (
+		limiter  Limiter
+		adaptive *AdaptiveLimiter
+	)
+
+	BeforeEach(func() {
+		var err error
+		limiter, err = NewMultiLimiter(&MultiLimitArgs{CxnNum: 1, RateSec: 100, RateMin: 6000})
+		Expect(err).Should(BeNil())
+		adaptive = NewAdaptiveLimiter(limiter, AdaptiveArgs{Decrease: 0.5, Step: 30, MinRate: 20, Statuses: []int{429, 503}, Interval: 100 * time.Millisecond})
+	})
+
+	It("decreases the rate once a second down to the lowest rate.", func() {
+		defer GinkgoRecover()
+		adaptive.Observe(200, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(100)))
+		adaptive.Observe(429, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(50)))
+		adaptive.Observe(503, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(50)))
+		Expect(adaptive.Decreases).To(Equal(uint64(1)))
+
+		adaptive.SetLimit(30)
+		adaptive.Observe(429, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(20)))
+	})
+
+	It("recovers the rate by step and reports the time throttled.", func() {
+		defer GinkgoRecover()
+		adaptive.Observe(429, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(50)))
+		time.Sleep(time.Second)
+		adaptive.Observe(200, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(80)))
+		adaptive.Observe(200, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(80)))
+		Expect(adaptive.Throttled()).To(BeNumerically(">=", time.Second))
+
+		time.Sleep(time.Second)
+		adaptive.Observe(200, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Limit(100)))
+		throttled := adaptive.Throttled()
+		Expect(throttled).To(BeNumerically(">=", 2*time.Second))
+		time.Sleep(10 * time.Millisecond)
+		Expect(adaptive.Throttled()).To(Equal(throttled))
+	})
+
+	It("restores the unlimited rate once recovered past the achieved rate.", func() {
+		defer GinkgoRecover()
+		unlimited, err := NewMultiLimiter(&MultiLimitArgs{CxnNum: 1})
+		Expect(err).Should(BeNil())
+		Expect(unlimited.Limit()).To(Equal(rate.Inf))
+		adaptive = NewAdaptiveLimiter(unlimited, AdaptiveArgs{Decrease: 0.1, Step: 1, MinRate: 0.01, Statuses: []int{429}, Interval: time.Minute})
+		for i := 0; i < 10; i++ {
+			Expect(adaptive.Wait(context.Background())).Should(Succeed())
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		adaptive.Observe(429, 0)
+		decreased := adaptive.Limit()
+		Expect(decreased).To(BeNumerically("<=", 10))
+		time.Sleep(time.Second)
+		adaptive.Observe(200, 0)
+		Expect(adaptive.Limit()).To(Equal(decreased + 1))
+
+		adaptive.Args.Step = 1000
+		time.Sleep(time.Second)
+		adaptive.Observe(200, 0)
+		Expect(adaptive.Limit()).To(Equal(rate.Inf))
+		throttled := adaptive.Throttled()
+		Expect(throttled).To(BeNumerically(">=", 2*time.Second))
+		time.Sleep(10 * time.Millisecond)
+		Expect(adaptive.Throttled()).To(Equal(throttled))
+	})
+
+	It("pauses for Retry-After and samples the effective rate.", func() {
+		defer GinkgoRecover()
+		ctx := context.Background()
+		Expect(adaptive.Wait(ctx)).Should(Succeed())
+		adaptive.Observe(429, 150*time.Millisecond)
+		Expect(adaptive.Pauses).To(Equal(uint64(1)))
+
+		start := time.Now()
+		Expect(adaptive.Wait(ctx)).Should(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 150*time.Millisecond))
+
+		samples := adaptive.Samples()
+		Expect(len(samples)).To(BeNumerically(">=", 1))
+		Expect(samples[0].At).To(Equal(100 * time.Millisecond))
+		Expect(samples[0].Achieved).To(Equal(10.0))
+		Expect(samples[len(samples)-1].Limit).To(Equal(50.0))
+
+		cancel, stop := context.WithCancel(ctx)
+		adaptive.Observe(503, time.Minute)
+		stop()
+		Expect(adaptive.Wait(cancel)).ShouldNot(Succeed())
+	})
+})
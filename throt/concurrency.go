@@ -0,0 +1,333 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ConcurrencyAlgo adaptive concurrency algorithm.
+type ConcurrencyAlgo int
+
+const (
+	// NoConcurrency adaptive concurrency is off.
+	NoConcurrency ConcurrencyAlgo = iota
+	// AIMDConcurrency increases the limit additively and decreases it multiplicatively on drops.
+	AIMDConcurrency
+	// GradientConcurrency follows the gradient of the long-term to the recent latency.
+	GradientConcurrency
+)
+
+func (a ConcurrencyAlgo) String() string {
+	return [...]string{"", "aimd", "gradient"}[a]
+}
+
+// ParseConcurrencyAlgo parses string to ConcurrencyAlgo.
+func ParseConcurrencyAlgo(str string) (algo ConcurrencyAlgo, err error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "", "none":
+		return NoConcurrency, nil
+	case "aimd":
+		return AIMDConcurrency, nil
+	case "gradient":
+		return GradientConcurrency, nil
+	}
+	return NoConcurrency, fmt.Errorf("invalid concurrency algorithm %q", str)
+}
+
+// Gradient algorithm constants, the long-term latency average weight, the limit smoothing and the
+// gradient bounds.
+const (
+	longWeight      = 0.05
+	limitSmoothing  = 0.2
+	minGradient     = 0.5
+	maxLatencyDrift = 2
+)
+
+// ConcurrencyArgs adaptive concurrency limiter settings. The requests slower than MaxLatency, failed
+// or answered with 429 or 5xx statuses are drops, zero MaxLatency disables the latency drops.
+type ConcurrencyArgs struct {
+	Algo       ConcurrencyAlgo
+	Initial    float64
+	Min        float64
+	Max        float64
+	Backoff    float64
+	Tolerance  float64
+	MaxLatency time.Duration
+	Interval   time.Duration
+}
+
+// ConcurrencySample concurrency limit over the interval ending at the offset from the start.
+type ConcurrencySample struct {
+	At        time.Duration
+	Limit     float64
+	Peak      int
+	Latency   time.Duration
+	Completed uint64
+	Drops     uint64
+}
+
+// ConcurrencyLimiter limits the requests in flight, the limit adapts to the endpoint latency and drops.
+// It does not limit the rate, Limit and SetLimit report and set the concurrency limit.
+type ConcurrencyLimiter struct {
+	Args     ConcurrencyArgs
+	Drops    uint64
+	Lowest   float64
+	Highest  float64
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	changed  chan struct{}
+	dropped  time.Time
+	long     time.Duration
+	latSum   time.Duration
+	latCount int
+	latDrops int
+	start    time.Time
+	window   time.Time
+	trace    ConcurrencySample
+	traceLat time.Duration
+	samples  []ConcurrencySample
+}
+
+// NewConcurrencyLimiter creates new adaptive concurrency limiter, nil if the algorithm is off.
+func NewConcurrencyLimiter(args ConcurrencyArgs) *ConcurrencyLimiter {
+	if args.Algo == NoConcurrency {
+		return nil
+	}
+	if args.Min < 1 {
+		args.Min = 1
+	}
+	if args.Max < args.Min {
+		args.Max = args.Min
+	}
+	if args.Backoff <= 0 || args.Backoff >= 1 {
+		args.Backoff = 0.9
+	}
+	if args.Tolerance < 1 {
+		args.Tolerance = 1.5
+	}
+	if args.Interval <= 0 {
+		args.Interval = time.Second
+	}
+	limit := math.Max(args.Min, math.Min(args.Max, args.Initial))
+	now := time.Now()
+	return &ConcurrencyLimiter{Args: args, Lowest: limit, Highest: limit, limit: limit, changed: make(chan struct{}),
+		start: now, window: now}
+}
+
+// Wait waits for a slot under the concurrency limit, the slot is released by Done.
+func (l *ConcurrencyLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		if l.inFlight < l.slots() {
+			l.inFlight++
+			l.sample(time.Now())
+			if l.inFlight > l.trace.Peak {
+				l.trace.Peak = l.inFlight
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Done releases the slot and adapts the limit to the request latency and outcome.
+func (l *ConcurrencyLimiter) Done(status int, latency time.Duration, err error) {
+	if l == nil {
+		return
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	dropped := err != nil || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError ||
+		(l.Args.MaxLatency > 0 && latency > l.Args.MaxLatency)
+	l.sample(now)
+	l.trace.Completed++
+	l.traceLat += latency
+	if dropped {
+		l.trace.Drops++
+		l.Drops++
+	}
+
+	switch l.Args.Algo {
+	case AIMDConcurrency:
+		l.aimd(dropped, latency, now)
+	case GradientConcurrency:
+		l.gradient(dropped, latency)
+	}
+	l.notify()
+}
+
+// aimd decreases the limit by backoff on the drop once per latency, the concurrent requests
+// report the same overload, and increases it by one per limit of requests sent with at least
+// half of the limit in flight.
+func (l *ConcurrencyLimiter) aimd(dropped bool, latency time.Duration, now time.Time) {
+	if dropped {
+		if now.Sub(l.dropped) >= latency {
+			l.dropped = now
+			l.setLimit(l.limit * l.Args.Backoff)
+		}
+		return
+	}
+	if 2*(l.inFlight+1) >= l.slots() {
+		l.setLimit(l.limit + 1/l.limit)
+	}
+}
+
+// gradient updates the limit once per limit of requests. The limit grows by its square root queue
+// while the recent latency stays within the tolerance of the long-term latency and shrinks as
+// the recent latency grows, drops decrease it by backoff.
+func (l *ConcurrencyLimiter) gradient(dropped bool, latency time.Duration) {
+	l.latSum += latency
+	l.latCount++
+	if dropped {
+		l.latDrops++
+	}
+	if l.latCount < l.slots() {
+		return
+	}
+
+	short := l.latSum / time.Duration(l.latCount)
+	drops := l.latDrops
+	l.latSum, l.latCount, l.latDrops = 0, 0, 0
+	if drops > 0 {
+		l.setLimit(l.limit * l.Args.Backoff)
+		return
+	}
+	if short <= 0 {
+		short = time.Microsecond
+	}
+	if l.long == 0 {
+		l.long = short
+	} else {
+		l.long = time.Duration(float64(l.long)*(1-longWeight) + float64(short)*longWeight)
+	}
+	// The long-term latency drifts down quickly after the endpoint recovers.
+	if float64(l.long)/float64(short) > maxLatencyDrift {
+		l.long = time.Duration(float64(l.long) * 0.95)
+	}
+
+	gradient := math.Max(minGradient, math.Min(1, l.Args.Tolerance*float64(l.long)/float64(short)))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	l.setLimit(l.limit*(1-limitSmoothing) + newLimit*limitSmoothing)
+}
+
+// Limit returns the current concurrency limit.
+func (l *ConcurrencyLimiter) Limit() rate.Limit {
+	if l == nil {
+		return rate.Inf
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return rate.Limit(l.limit)
+}
+
+// SetLimit sets the concurrency limit within the bounds.
+func (l *ConcurrencyLimiter) SetLimit(newLimit rate.Limit) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setLimit(float64(newLimit))
+	l.notify()
+}
+
+// InFlight returns the number of requests in flight.
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Samples returns the concurrency limit over time including the current partial interval.
+func (l *ConcurrencyLimiter) Samples() (samples []ConcurrencySample) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sample(now)
+	samples = append(samples, l.samples...)
+	if l.trace.Completed > 0 {
+		samples = append(samples, l.closeTrace(now))
+	}
+
+	return
+}
+
+// setLimit sets the limit within the bounds.
+func (l *ConcurrencyLimiter) setLimit(limit float64) {
+	l.limit = math.Max(l.Args.Min, math.Min(l.Args.Max, limit))
+	l.Lowest = math.Min(l.Lowest, l.limit)
+	l.Highest = math.Max(l.Highest, l.limit)
+}
+
+// slots returns the number of requests allowed in flight.
+func (l *ConcurrencyLimiter) slots() int {
+	return int(math.Max(1, math.Floor(l.limit)))
+}
+
+// notify wakes the requests waiting for a slot.
+func (l *ConcurrencyLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// sample closes the elapsed intervals.
+func (l *ConcurrencyLimiter) sample(now time.Time) {
+	for now.Sub(l.window) >= l.Args.Interval {
+		l.window = l.window.Add(l.Args.Interval)
+		l.samples = append(l.samples, l.closeTrace(l.window))
+		l.trace = ConcurrencySample{Peak: l.inFlight}
+		l.traceLat = 0
+	}
+}
+
+// closeTrace returns the interval sample ending at the time.
+func (l *ConcurrencyLimiter) closeTrace(end time.Time) ConcurrencySample {
+	sample := l.trace
+	sample.At = end.Sub(l.start)
+	sample.Limit = l.limit
+	if sample.Completed > 0 {
+		sample.Latency = l.traceLat / time.Duration(sample.Completed)
+	}
+	return sample
+}
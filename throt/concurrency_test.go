@@ -0,0 +1,127 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+
+	. "github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("Concurrency", func() {
+	// send sends the rounds of requests filling the limit with the status and latency.
+	send := func(limiter *ConcurrencyLimiter, rounds int, status int, latency time.Duration) {
+		for i := 0; i < rounds; i++ {
+			n := int(limiter.Limit())
+			for j := 0; j < n; j++ {
+				Expect(limiter.Wait(context.Background())).Should(Succeed())
+			}
+			for j := 0; j < n; j++ {
+				limiter.Done(status, latency, nil)
+			}
+		}
+	}
+
+	It("parses the algorithm.", func() {
+		defer GinkgoRecover()
+		Expect(ParseConcurrencyAlgo("AIMD")).To(Equal(AIMDConcurrency))
+		Expect(ParseConcurrencyAlgo("gradient")).To(Equal(GradientConcurrency))
+		Expect(ParseConcurrencyAlgo("")).To(Equal(NoConcurrency))
+		_, err := ParseConcurrencyAlgo("vegas")
+		Expect(err).ShouldNot(BeNil())
+	})
+	It("off is not limited.", func() {
+		defer GinkgoRecover()
+		limiter := NewConcurrencyLimiter(ConcurrencyArgs{})
+		Expect(limiter).To(BeNil())
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		limiter.Done(200, time.Millisecond, nil)
+		Expect(limiter.Limit()).To(Equal(rate.Inf))
+	})
+	It("limits the requests in flight.", func() {
+		defer GinkgoRecover()
+		limiter := NewConcurrencyLimiter(ConcurrencyArgs{Algo: AIMDConcurrency, Min: 2, Max: 2})
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		Expect(limiter.InFlight()).To(Equal(2))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(limiter.Wait(ctx)).Should(Equal(context.DeadlineExceeded))
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			limiter.Done(200, time.Millisecond, nil)
+		}()
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		Expect(limiter.InFlight()).To(Equal(2))
+	})
+	It("AIMD increases additively and decreases on drops.", func() {
+		defer GinkgoRecover()
+		limiter := NewConcurrencyLimiter(ConcurrencyArgs{Algo: AIMDConcurrency, Initial: 1, Max: 10, Backoff: 0.5})
+		send(limiter, 10, 200, time.Millisecond)
+		Expect(float64(limiter.Limit())).To(BeNumerically(">", 4))
+		Expect(float64(limiter.Limit())).To(BeNumerically("<", 8))
+
+		// The concurrent drops decrease the limit once.
+		before := float64(limiter.Limit())
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		limiter.Done(503, time.Second, nil)
+		limiter.Done(0, time.Second, errors.New("connection reset"))
+		Expect(float64(limiter.Limit())).To(Equal(before * 0.5))
+		Expect(limiter.Drops).To(Equal(uint64(2)))
+		Expect(limiter.Highest).To(Equal(before))
+	})
+	It("AIMD drops the slow requests.", func() {
+		defer GinkgoRecover()
+		limiter := NewConcurrencyLimiter(ConcurrencyArgs{Algo: AIMDConcurrency, Initial: 8, Max: 10, MaxLatency: 100 * time.Millisecond})
+		send(limiter, 1, 200, 200*time.Millisecond)
+		Expect(limiter.Limit()).To(Equal(rate.Limit(8 * 0.9)))
+	})
+	It("gradient follows the latency.", func() {
+		defer GinkgoRecover()
+		limiter := NewConcurrencyLimiter(ConcurrencyArgs{Algo: GradientConcurrency, Initial: 10, Max: 100, Tolerance: 1})
+		send(limiter, 20, 200, 10*time.Millisecond)
+		grown := float64(limiter.Limit())
+		Expect(grown).To(BeNumerically(">", 20))
+
+		send(limiter, 5, 200, 100*time.Millisecond)
+		Expect(float64(limiter.Limit())).To(BeNumerically("<", grown*0.8))
+		Expect(limiter.Lowest).To(Equal(10.0))
+	})
+	It("traces the limit over time.", func() {
+		defer GinkgoRecover()
+		limiter := NewConcurrencyLimiter(ConcurrencyArgs{Algo: AIMDConcurrency, Initial: 2, Max: 4, Interval: 50 * time.Millisecond})
+		send(limiter, 1, 200, 5*time.Millisecond)
+		time.Sleep(60 * time.Millisecond)
+		send(limiter, 1, 200, 15*time.Millisecond)
+
+		samples := limiter.Samples()
+		Expect(samples).To(HaveLen(2))
+		Expect(samples[0].Completed).To(Equal(uint64(2)))
+		Expect(samples[0].Peak).To(Equal(2))
+		Expect(samples[0].Latency).To(Equal(5 * time.Millisecond))
+		Expect(samples[1].Completed).To(Equal(uint64(2)))
+		Expect(samples[1].Latency).To(Equal(15 * time.Millisecond))
+		Expect(samples[1].Limit).To(BeNumerically(">", 2))
+	})
+})
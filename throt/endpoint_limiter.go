@@ -0,0 +1,80 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt
+
+import (
+	"context"
+	"time"
+)
+
+// EndpointLimitArgs used to configure the endpoint limiter, zero disables the limit.
+type EndpointLimitArgs struct {
+	RateSec uint
+	RateMin uint
+	Windows []RateWindow
+	Burst   uint
+	MaxConn uint
+}
+
+// EndpointLimiter limits the rate and the concurrency of a single endpoint.
+type EndpointLimiter struct {
+	Args    EndpointLimitArgs
+	limiter Limiter
+	slots   chan struct{}
+}
+
+// NewEndpointLimiter creates the endpoint limiter, nil if the endpoint is not limited.
+func NewEndpointLimiter(args EndpointLimitArgs) *EndpointLimiter {
+	windows := append([]RateWindow{{Count: float64(args.RateSec), Window: time.Second},
+		{Count: float64(args.RateMin), Window: time.Minute}}, args.Windows...)
+	limiters := newRateLimiters(windows, args.Burst)
+	if len(limiters) == 0 && args.MaxConn == 0 {
+		return nil
+	}
+
+	l := &EndpointLimiter{Args: args}
+	if len(limiters) > 0 {
+		l.limiter = &MultiLimiter{limiters: limiters}
+	}
+	if args.MaxConn > 0 {
+		l.slots = make(chan struct{}, args.MaxConn)
+	}
+	return l
+}
+
+// Acquire waits for the endpoint rate limit and a concurrency slot, release frees the slot
+// once the request completes.
+func (l *EndpointLimiter) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if l == nil {
+		return release, nil
+	}
+
+	if l.limiter != nil {
+		if err = l.limiter.Wait(ctx); err != nil {
+			return release, err
+		}
+	}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return release, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
@@ -0,0 +1,64 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("EndpointLimiter", func() {
+	It("not limited.", func() {
+		defer GinkgoRecover()
+		var limiter *EndpointLimiter = NewEndpointLimiter(EndpointLimitArgs{Burst: 5})
+		Expect(limiter).To(BeNil())
+		release, err := limiter.Acquire(context.Background())
+		Expect(err).Should(BeNil())
+		release()
+	})
+	It("limits the rate.", func() {
+		defer GinkgoRecover()
+		limiter := NewEndpointLimiter(EndpointLimitArgs{RateSec: 20, Burst: 2})
+		start := time.Now()
+		for i := 0; i < 6; i++ {
+			release, err := limiter.Acquire(context.Background())
+			Expect(err).Should(BeNil())
+			release()
+		}
+		// Two requests in the burst, four more at 50ms intervals.
+		Expect(time.Since(start)).To(BeNumerically(">=", 180*time.Millisecond))
+	})
+	It("limits the concurrency.", func() {
+		defer GinkgoRecover()
+		limiter := NewEndpointLimiter(EndpointLimitArgs{MaxConn: 1})
+		release, err := limiter.Acquire(context.Background())
+		Expect(err).Should(BeNil())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = limiter.Acquire(ctx)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		release()
+		release, err = limiter.Acquire(context.Background())
+		Expect(err).Should(BeNil())
+		release()
+	})
+})
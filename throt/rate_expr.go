@@ -0,0 +1,75 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateWindow allows Count requests per Window.
+type RateWindow struct {
+	Count  float64
+	Window time.Duration
+}
+
+// Limit returns the window rate limit, rate.Inf if the window is not limited.
+func (w RateWindow) Limit() rate.Limit {
+	if w.Count <= 0 || w.Window <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(w.Count / w.Window.Seconds())
+}
+
+func (w RateWindow) String() string {
+	return fmt.Sprintf("%g/%v", w.Count, w.Window)
+}
+
+// ParseRate parses comma separated rate expression, e.g. 0.5/s, 100/s,5000/m,200000/h or
+// 1/200ms. The window is a unit, ms, s, m or h, or a duration, a count without the window
+// is per second. Zero count windows are not limited and omitted.
+func ParseRate(expr string) (windows []RateWindow, err error) {
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		count, window := item, "s"
+		if i := strings.Index(item, "/"); i >= 0 {
+			count, window = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+		var w RateWindow
+		if w.Count, err = strconv.ParseFloat(count, 64); err != nil || w.Count < 0 {
+			return nil, fmt.Errorf("invalid rate count in %q", item)
+		}
+		if window != "" && (window[0] < '0' || window[0] > '9') && window[0] != '.' {
+			// Unit without the number is one unit.
+			window = "1" + window
+		}
+		if w.Window, err = time.ParseDuration(window); err != nil || w.Window <= 0 {
+			return nil, fmt.Errorf("invalid rate window in %q", item)
+		}
+		if w.Count > 0 {
+			windows = append(windows, w)
+		}
+	}
+
+	return windows, nil
+}
@@ -0,0 +1,95 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+
+	. "github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("RateExpr", func() {
+	Describe("ParseRate", func() {
+		It("windows and fractional rates.", func() {
+			defer GinkgoRecover()
+			windows, err := ParseRate("100/s, 5000/m,200000/h")
+			Expect(err).Should(BeNil())
+			Expect(windows).To(Equal([]RateWindow{{Count: 100, Window: time.Second}, {Count: 5000, Window: time.Minute},
+				{Count: 200000, Window: time.Hour}}))
+			Expect(ParseRate("0.5/s")).To(Equal([]RateWindow{{Count: 0.5, Window: time.Second}}))
+			Expect(ParseRate("1/200ms")).To(Equal([]RateWindow{{Count: 1, Window: 200 * time.Millisecond}}))
+			Expect(ParseRate("1/3s")).To(Equal([]RateWindow{{Count: 1, Window: 3 * time.Second}}))
+			Expect(ParseRate("20")).To(Equal([]RateWindow{{Count: 20, Window: time.Second}}))
+		})
+		It("zero or absent rate is not limited.", func() {
+			defer GinkgoRecover()
+			Expect(ParseRate("")).To(BeEmpty())
+			Expect(ParseRate("0/s,0")).To(BeEmpty())
+			Expect(RateWindow{}.Limit()).To(Equal(rate.Inf))
+			Expect(Per(0, time.Second)).To(Equal(rate.Inf))
+			Expect(Per(10, 0)).To(Equal(rate.Inf))
+			Expect(Per(30, time.Minute)).To(Equal(rate.Limit(0.5)))
+		})
+		It("invalid expression.", func() {
+			defer GinkgoRecover()
+			for _, expr := range []string{"fast", "-1/s", "10/fortnight", "10/0s", "1/-2s"} {
+				_, err := ParseRate(expr)
+				Expect(err).ShouldNot(BeNil(), expr)
+			}
+		})
+	})
+
+	Describe("NewMultiLimiter", func() {
+		It("enforces every window.", func() {
+			defer GinkgoRecover()
+			windows, _ := ParseRate("100/s,1/50ms")
+			limiter, err := NewMultiLimiter(&MultiLimitArgs{Windows: windows, Burst: 1})
+			Expect(err).Should(BeNil())
+			Expect(limiter.Limit()).To(Equal(rate.Limit(20)))
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				Expect(limiter.Wait(context.Background())).Should(Succeed())
+			}
+			Expect(time.Since(start)).To(BeNumerically(">=", 190*time.Millisecond))
+		})
+		It("sets the rate of the shortest window, the longer windows cap it.", func() {
+			defer GinkgoRecover()
+			windows, _ := ParseRate("10/s,300/m")
+			limiter, err := NewMultiLimiter(&MultiLimitArgs{Windows: windows, Burst: 1})
+			Expect(err).Should(BeNil())
+			Expect(limiter.Limit()).To(Equal(rate.Limit(5)))
+			limiter.SetLimit(2)
+			Expect(limiter.Limit()).To(Equal(rate.Limit(2)))
+			limiter.SetLimit(20)
+			Expect(limiter.Limit()).To(Equal(rate.Limit(5)))
+			limiter.SetLimit(rate.Inf)
+			Expect(limiter.Limit()).To(Equal(rate.Limit(5)))
+		})
+		It("zero rates are not limited.", func() {
+			defer GinkgoRecover()
+			limiter, err := NewMultiLimiter(&MultiLimitArgs{CxnNum: 2})
+			Expect(err).Should(BeNil())
+			Expect(limiter.Limit()).To(Equal(rate.Inf))
+			for i := 0; i < 1000; i++ {
+				Expect(limiter.Wait(context.Background())).Should(Succeed())
+			}
+		})
+	})
+})
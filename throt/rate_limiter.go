@@ -26,31 +26,60 @@ import (
 type Limiter interface {
 	Wait(context.Context) error
 	Limit() rate.Limit
+	SetLimit(newLimit rate.Limit)
 }
 
-// MultiLimitArgs used to configure the limiter.
+// MultiLimitArgs used to configure the limiter. Burst defaults to CxnNum, zero rates are not limited.
 type MultiLimitArgs struct {
 	CxnNum  uint
 	RateSec uint
 	RateMin uint
+	Windows []RateWindow
+	Burst   uint
 }
 
-// NewMultiLimiter limits the rate using multiple limiters.
+// NewMultiLimiter limits the rate using multiple limiters, one per rate window.
 func NewMultiLimiter(args *MultiLimitArgs) (limiter Limiter, err error) {
-	secondLimit := rate.NewLimiter(Per(int(args.RateSec), time.Second), int(args.CxnNum))
-	minuteLimit := rate.NewLimiter(Per(int(args.RateMin), time.Minute), int(args.CxnNum))
-	limiters := []Limiter{secondLimit, minuteLimit}
-
-	byLimit := func(i, j int) bool {
-		return limiters[i].Limit() < limiters[j].Limit()
+	burst := args.Burst
+	if burst == 0 {
+		burst = args.CxnNum
+	}
+	windows := append([]RateWindow{{Count: float64(args.RateSec), Window: time.Second},
+		{Count: float64(args.RateMin), Window: time.Minute}}, args.Windows...)
+	limiters := newRateLimiters(windows, burst)
+	if len(limiters) == 0 {
+		// Not limited.
+		limiters = []Limiter{rate.NewLimiter(rate.Inf, burstSize(burst))}
 	}
-	sort.Slice(limiters, byLimit)
+
 	lim := MultiLimiter{limiters: limiters}
 	lim.limiter = &lim
 	return lim.limiter, nil
 }
 
-// MultiLimiter throttles transactions
+// newRateLimiters creates a limiter per limited rate window, the shortest window first.
+func newRateLimiters(windows []RateWindow, burst uint) (limiters []Limiter) {
+	windows = append([]RateWindow(nil), windows...)
+	sort.SliceStable(windows, func(i, j int) bool {
+		return windows[i].Window < windows[j].Window
+	})
+	for _, w := range windows {
+		if limit := w.Limit(); limit != rate.Inf {
+			limiters = append(limiters, rate.NewLimiter(limit, burstSize(burst)))
+		}
+	}
+	return limiters
+}
+
+// burstSize returns the limiter burst, at least one request.
+func burstSize(burst uint) int {
+	if burst == 0 {
+		return 1
+	}
+	return int(burst)
+}
+
+// MultiLimiter throttles transactions, the shortest window sets the rate and the longer windows cap it.
 type MultiLimiter struct {
 	limiters []Limiter
 	limiter  Limiter
@@ -58,8 +87,8 @@ type MultiLimiter struct {
 
 // Wait blocks to limit the rate by using every encapsulted limiter.
 func (l *MultiLimiter) Wait(ctx context.Context) error {
-	for _, l := range l.limiters {
-		if err := l.Wait(ctx); err != nil {
+	for _, lim := range l.limiters {
+		if err := lim.Wait(ctx); err != nil {
 			return err
 		}
 	}
@@ -67,12 +96,24 @@ func (l *MultiLimiter) Wait(ctx context.Context) error {
 	return nil
 }
 
-// Limit returns the most restrictive rate limiter.
+// Limit returns the most restrictive rate limit.
 func (l *MultiLimiter) Limit() rate.Limit {
-	return l.limiters[0].Limit()
+	limit := l.limiters[0].Limit()
+	for _, lim := range l.limiters[1:] {
+		if lim.Limit() < limit {
+			limit = lim.Limit()
+		}
+	}
+
+	return limit
+}
+
+// SetLimit sets the rate limit of the shortest window, the longer windows keep capping the rate.
+func (l *MultiLimiter) SetLimit(newLimit rate.Limit) {
+	l.limiters[0].SetLimit(newLimit)
 }
 
-// Per sets rate limit. Returns rate.Inf if duration is zero.
+// Per sets rate limit of eventCount events per duration. Returns rate.Inf if either is zero.
 func Per(eventCount int, duration time.Duration) rate.Limit {
-	return rate.Every(duration / time.Duration(eventCount)) // TODO: bug
+	return RateWindow{Count: float64(eventCount), Window: duration}.Limit()
 }
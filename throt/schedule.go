@@ -0,0 +1,319 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// scheduleTick rate schedule limit update interval.
+const scheduleTick = 100 * time.Millisecond
+
+// RatePoint target rate per second at the offset from the schedule start.
+type RatePoint struct {
+	At   time.Duration
+	Rate float64
+}
+
+// RateSchedule target rate curve, the rate is interpolated linearly between the points.
+// Speed compresses the schedule time, e.g. 60 plays an hour of the schedule per minute.
+type RateSchedule struct {
+	Points []RatePoint
+	Speed  float64
+}
+
+// timeLayouts rate schedule timestamp layouts, the layouts without the date are the time of day.
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "15:04:05", "15:04"}
+
+// ReadRateSchedule reads the rate schedule CSV file.
+func ReadRateSchedule(path string, speed float64) (schedule *RateSchedule, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return nil, errors.Wrap(err, "os.Open")
+	}
+	defer f.Close()
+
+	return ParseRateSchedule(f, speed)
+}
+
+// ParseRateSchedule parses the rate schedule CSV rows of timestamps and target rates with an
+// optional header. The timestamp is a date and time, the time of day or an offset in seconds,
+// the rate is per second or a rate expression, e.g. 1200/m.
+func ParseRateSchedule(r io.Reader, speed float64) (schedule *RateSchedule, err error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("invalid rate schedule speed %v", speed)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	schedule = &RateSchedule{Speed: speed}
+	var first, prev time.Time
+	for line := 1; ; line++ {
+		var record []string
+		if record, err = reader.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "reader.Read")
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected timestamp and rate", line)
+		}
+
+		var point RatePoint
+		if point.Rate, err = parseRatePoint(record[1]); err != nil {
+			if line == 1 {
+				// Header.
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		ts := strings.TrimSpace(record[0])
+		if sec, errF := strconv.ParseFloat(ts, 64); errF == nil {
+			point.At = time.Duration(sec * float64(time.Second))
+		} else {
+			var t time.Time
+			if t, err = parseTimestamp(ts); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			// The time of day passes midnight.
+			for t.Year() == 0 && !prev.IsZero() && t.Before(prev) {
+				t = t.Add(24 * time.Hour)
+			}
+			if first.IsZero() {
+				first = t
+			}
+			prev = t
+			point.At = t.Sub(first)
+		}
+
+		if n := len(schedule.Points); n > 0 && point.At < schedule.Points[n-1].At {
+			return nil, fmt.Errorf("line %d: timestamp %q is before the previous one", line, ts)
+		}
+		schedule.Points = append(schedule.Points, point)
+	}
+
+	if len(schedule.Points) == 0 {
+		return nil, fmt.Errorf("empty rate schedule")
+	}
+	// Offsets start at the first point.
+	for i := len(schedule.Points) - 1; i >= 0; i-- {
+		schedule.Points[i].At -= schedule.Points[0].At
+	}
+
+	return schedule, nil
+}
+
+// parseRatePoint parses the target rate per second or the rate expression.
+func parseRatePoint(s string) (float64, error) {
+	windows, err := ParseRate(s)
+	if err != nil || len(windows) > 1 {
+		return 0, fmt.Errorf("invalid target rate %q", s)
+	}
+	if len(windows) == 0 {
+		return 0, nil
+	}
+	return float64(windows[0].Limit()), nil
+}
+
+// parseTimestamp parses the timestamp by the known layouts.
+func parseTimestamp(s string) (t time.Time, err error) {
+	for _, layout := range timeLayouts {
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// Duration returns the run time of the schedule.
+func (s *RateSchedule) Duration() time.Duration {
+	return time.Duration(float64(s.Points[len(s.Points)-1].At) / s.Speed)
+}
+
+// RateAt returns the target rate at the run time since the start.
+func (s *RateSchedule) RateAt(elapsed time.Duration) float64 {
+	at := time.Duration(float64(elapsed) * s.Speed)
+	if at <= s.Points[0].At {
+		return s.Points[0].Rate
+	}
+	for i := 1; i < len(s.Points); i++ {
+		p0, p1 := s.Points[i-1], s.Points[i]
+		if at < p1.At {
+			return p0.Rate + (p1.Rate-p0.Rate)*float64(at-p0.At)/float64(p1.At-p0.At)
+		}
+	}
+	return s.Points[len(s.Points)-1].Rate
+}
+
+// ScheduleLimiter follows the rate schedule, updating the limit continuously while it runs.
+type ScheduleLimiter struct {
+	Schedule  *RateSchedule
+	Interval  time.Duration
+	limiter   Limiter
+	mu        sync.Mutex
+	start     time.Time
+	target    float64
+	window    time.Time
+	count     uint64
+	targetSum float64
+	targets   int
+	samples   []RateSample
+}
+
+// NewScheduleLimiter creates the schedule limiter around the limiter, the target and achieved
+// rates are sampled every interval.
+func NewScheduleLimiter(limiter Limiter, schedule *RateSchedule, interval time.Duration) *ScheduleLimiter {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &ScheduleLimiter{Schedule: schedule, Interval: interval, limiter: limiter}
+}
+
+// Run follows the schedule until it ends or the context is done.
+func (l *ScheduleLimiter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	l.mu.Lock()
+	l.start = time.Now()
+	l.window = l.start
+	l.mu.Unlock()
+	for {
+		now := time.Now()
+		l.update(now)
+		if now.Sub(l.start) >= l.Schedule.Duration() {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// update sets the target rate at the time.
+func (l *ScheduleLimiter) update(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sample(now)
+	l.target = l.Schedule.RateAt(now.Sub(l.start))
+	l.targetSum += l.target
+	l.targets++
+	if l.target > 0 {
+		l.limiter.SetLimit(rate.Limit(l.target))
+	}
+}
+
+// Wait waits for the scheduled rate. The request does not reserve the wait longer than the update
+// interval to follow the rate as it changes, and waits while the target rate is zero.
+func (l *ScheduleLimiter) Wait(ctx context.Context) (err error) {
+	for {
+		l.mu.Lock()
+		target := l.target
+		l.mu.Unlock()
+
+		if target > 0 {
+			tickCtx, cancel := context.WithTimeout(ctx, scheduleTick)
+			err = l.limiter.Wait(tickCtx)
+			cancel()
+			if err == nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		timer := time.NewTimer(scheduleTick)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	l.mu.Lock()
+	l.sample(time.Now())
+	l.count++
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Limit returns the current rate limit.
+func (l *ScheduleLimiter) Limit() rate.Limit {
+	return l.limiter.Limit()
+}
+
+// SetLimit sets the rate limit until the next schedule update.
+func (l *ScheduleLimiter) SetLimit(newLimit rate.Limit) {
+	l.limiter.SetLimit(newLimit)
+}
+
+// Samples returns the target and achieved rate over time including the current partial interval
+// longer than the update tick.
+func (l *ScheduleLimiter) Samples() (samples []RateSample) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.start.IsZero() {
+		return nil
+	}
+	l.sample(now)
+	samples = append(samples, l.samples...)
+	if elapsed := now.Sub(l.window); l.targets > 0 && elapsed >= scheduleTick {
+		samples = append(samples, RateSample{At: now.Sub(l.start), Limit: l.targetSum / float64(l.targets),
+			Achieved: float64(l.count) / elapsed.Seconds()})
+	}
+
+	return
+}
+
+// sample closes the elapsed intervals with the average target rate.
+func (l *ScheduleLimiter) sample(now time.Time) {
+	if l.start.IsZero() {
+		return
+	}
+	for now.Sub(l.window) >= l.Interval {
+		l.window = l.window.Add(l.Interval)
+		target := l.target
+		if l.targets > 0 {
+			target = l.targetSum / float64(l.targets)
+		}
+		l.samples = append(l.samples, RateSample{At: l.window.Sub(l.start), Limit: target,
+			Achieved: float64(l.count) / l.Interval.Seconds()})
+		l.count, l.targetSum, l.targets = 0, 0, 0
+	}
+}
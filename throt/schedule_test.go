@@ -0,0 +1,115 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+
+	. "github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("Schedule", func() {
+	Describe("ParseRateSchedule", func() {
+		It("header, comments and offsets in seconds.", func() {
+			defer GinkgoRecover()
+			schedule, err := ParseRateSchedule(strings.NewReader("offset,rate\n# warm-up\n10,5\n20,1200/m\n40.5,0\n"), 1)
+			Expect(err).Should(BeNil())
+			Expect(schedule.Points).To(Equal([]RatePoint{{At: 0, Rate: 5}, {At: 10 * time.Second, Rate: 20},
+				{At: 30500 * time.Millisecond, Rate: 0}}))
+			Expect(schedule.Duration()).To(Equal(30500 * time.Millisecond))
+		})
+		It("time of day passes midnight.", func() {
+			defer GinkgoRecover()
+			schedule, err := ParseRateSchedule(strings.NewReader("23:00,10\n23:30:30,20\n00:30,30\n"), 60)
+			Expect(err).Should(BeNil())
+			Expect(schedule.Points).To(Equal([]RatePoint{{At: 0, Rate: 10}, {At: 30*time.Minute + 30*time.Second, Rate: 20},
+				{At: 90 * time.Minute, Rate: 30}}))
+			Expect(schedule.Duration()).To(Equal(90 * time.Second))
+		})
+		It("dates and times.", func() {
+			defer GinkgoRecover()
+			schedule, err := ParseRateSchedule(strings.NewReader("2019-07-15T09:00:00Z,10\n2019-07-15 10:00,20\n"), 1)
+			Expect(err).Should(BeNil())
+			Expect(schedule.Points[1].At).To(Equal(time.Hour))
+		})
+		It("invalid schedule.", func() {
+			defer GinkgoRecover()
+			for _, csv := range []string{"", "time,rate\n", "10\n", "10,5\n5,5\n", "10,5\nnoon,5\n", "10,5\n20,fast\n", "0,\"10/s,20/m\"\n"} {
+				_, err := ParseRateSchedule(strings.NewReader(csv), 1)
+				Expect(err).ShouldNot(BeNil(), csv)
+			}
+			_, err := ParseRateSchedule(strings.NewReader("0,10\n"), 0)
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Describe("RateAt", func() {
+		It("interpolates between the points.", func() {
+			defer GinkgoRecover()
+			schedule := &RateSchedule{Points: []RatePoint{{At: 0, Rate: 10}, {At: 10 * time.Second, Rate: 30},
+				{At: 20 * time.Second, Rate: 0}}, Speed: 2}
+			Expect(schedule.RateAt(0)).To(Equal(10.0))
+			Expect(schedule.RateAt(2500 * time.Millisecond)).To(Equal(20.0))
+			Expect(schedule.RateAt(5 * time.Second)).To(Equal(30.0))
+			Expect(schedule.RateAt(7500 * time.Millisecond)).To(Equal(15.0))
+			Expect(schedule.RateAt(time.Minute)).To(Equal(0.0))
+		})
+	})
+
+	Describe("ScheduleLimiter", func() {
+		It("follows the schedule until it ends.", func() {
+			defer GinkgoRecover()
+			inner, err := NewMultiLimiter(&MultiLimitArgs{RateSec: 1, CxnNum: 1})
+			Expect(err).Should(BeNil())
+			schedule := &RateSchedule{Points: []RatePoint{{At: 0, Rate: 50}, {At: time.Second, Rate: 50}}, Speed: 2}
+			limiter := NewScheduleLimiter(inner, schedule, 100*time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- limiter.Run(ctx)
+				cancel()
+			}()
+			count := 0
+			for limiter.Wait(ctx) == nil {
+				count++
+			}
+			Expect(<-done).Should(BeNil())
+			Expect(limiter.Limit()).To(Equal(rate.Limit(50)))
+			Expect(count).To(BeNumerically("~", 25, 8))
+
+			samples := limiter.Samples()
+			Expect(samples).ShouldNot(BeEmpty())
+			Expect(samples[0].Limit).To(Equal(50.0))
+		})
+		It("pauses while the target rate is zero.", func() {
+			defer GinkgoRecover()
+			inner, _ := NewMultiLimiter(&MultiLimitArgs{CxnNum: 1})
+			schedule := &RateSchedule{Points: []RatePoint{{At: 0, Rate: 0}, {At: time.Second, Rate: 0}}, Speed: 1}
+			limiter := NewScheduleLimiter(inner, schedule, time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+			go limiter.Run(ctx)
+			Expect(limiter.Wait(ctx)).Should(Equal(context.DeadlineExceeded))
+		})
+	})
+})
@@ -0,0 +1,161 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThinkKind think time distribution.
+type ThinkKind int
+
+const (
+	// FixedThink constant think time.
+	FixedThink ThinkKind = iota
+	// UniformThink uniform think time in range.
+	UniformThink
+	// NormalThink normal think time with mean and standard deviation.
+	NormalThink
+	// ExpThink exponential think time with mean.
+	ExpThink
+	// LognormalThink log-normal think time with median and log standard deviation.
+	LognormalThink
+)
+
+func (k ThinkKind) String() string {
+	return [...]string{"fixed", "uniform", "normal", "exp", "lognormal"}[k]
+}
+
+// Think think time distribution in milliseconds.
+type Think struct {
+	Kind ThinkKind
+	A    float64
+	B    float64
+}
+
+var thinkExpr = regexp.MustCompile(`^\s*([a-z]+)\s*\(([^)]*)\)\s*$`)
+
+// ParseThink parses think time expression: fixed(ms), uniform(min,max), normal(mean,sd), exp(mean),
+// lognormal(median,sigma), or milliseconds. Times are in milliseconds.
+func ParseThink(expr string) (think *Think, err error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	if expr == "" {
+		return nil, nil
+	}
+	if ms, errP := strconv.ParseFloat(expr, 64); errP == nil {
+		expr = fmt.Sprintf("fixed(%v)", ms)
+	}
+
+	match := thinkExpr.FindStringSubmatch(expr)
+	if match == nil {
+		return nil, fmt.Errorf("invalid think time %q", expr)
+	}
+
+	var params []float64
+	for _, param := range strings.Split(match[2], ",") {
+		var val float64
+		if val, err = strconv.ParseFloat(strings.TrimSpace(param), 64); err != nil || val < 0 {
+			return nil, fmt.Errorf("invalid think time parameter %q in %q", param, expr)
+		}
+		params = append(params, val)
+	}
+
+	kinds := map[string]ThinkKind{"fixed": FixedThink, "uniform": UniformThink, "normal": NormalThink, "exp": ExpThink, "lognormal": LognormalThink}
+	kind, ok := kinds[match[1]]
+	if !ok {
+		return nil, fmt.Errorf("unknown think time distribution %q", match[1])
+	}
+
+	want := 2
+	if kind == FixedThink || kind == ExpThink {
+		want = 1
+	}
+	if len(params) != want {
+		return nil, fmt.Errorf("%s think time takes %d parameters", kind, want)
+	}
+
+	think = &Think{Kind: kind, A: params[0]}
+	if want == 2 {
+		think.B = params[1]
+	}
+	if kind == UniformThink && think.B < think.A {
+		return nil, fmt.Errorf("invalid uniform think time range %q", expr)
+	}
+
+	return think, nil
+}
+
+// Next returns the next think time drawn from the distribution.
+func (t *Think) Next(rng *rand.Rand) time.Duration {
+	var ms float64
+	switch t.Kind {
+	case FixedThink:
+		ms = t.A
+	case UniformThink:
+		ms = t.A + rng.Float64()*(t.B-t.A)
+	case NormalThink:
+		ms = t.A + rng.NormFloat64()*t.B
+	case ExpThink:
+		ms = rng.ExpFloat64() * t.A
+	case LognormalThink:
+		ms = t.A * math.Exp(rng.NormFloat64()*t.B)
+	}
+	if ms < 0 {
+		ms = 0
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// Mean returns the mean think time.
+func (t *Think) Mean() time.Duration {
+	var ms float64
+	switch t.Kind {
+	case FixedThink, NormalThink, ExpThink:
+		ms = t.A
+	case UniformThink:
+		ms = (t.A + t.B) / 2
+	case LognormalThink:
+		ms = t.A * math.Exp(t.B*t.B/2)
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// StartOffset returns a random worker start offset within the mean delay between requests,
+// the think time or, without one, the fixed delay, so the workers do not send in lockstep.
+func StartOffset(think *Think, delay time.Duration, rng *rand.Rand) time.Duration {
+	if think != nil {
+		delay = think.Mean()
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rng.Int63n(int64(delay)))
+}
+
+func (t *Think) String() string {
+	if t.Kind == FixedThink || t.Kind == ExpThink {
+		return fmt.Sprintf("%s(%v)", t.Kind, t.A)
+	}
+	return fmt.Sprintf("%s(%v,%v)", t.Kind, t.A, t.B)
+}
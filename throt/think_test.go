@@ -0,0 +1,97 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throt_test
+
+import (
+	"math/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alexstov/sling/throt"
+)
+
+var _ = Describe("Think", func() {
+	mean := func(think *Think, n int) time.Duration {
+		rng := rand.New(rand.NewSource(1))
+		var sum time.Duration
+		for i := 0; i < n; i++ {
+			next := think.Next(rng)
+			Expect(next).To(BeNumerically(">=", 0))
+			sum += next
+		}
+		return sum / time.Duration(n)
+	}
+
+	Describe("ParseThink", func() {
+		Context("valid expressions", func() {
+			It("parses the distributions.", func() {
+				defer GinkgoRecover()
+				Expect(ParseThink("")).To(BeNil())
+				Expect(ParseThink("25")).To(Equal(&Think{Kind: FixedThink, A: 25}))
+				Expect(ParseThink("uniform(10, 50)")).To(Equal(&Think{Kind: UniformThink, A: 10, B: 50}))
+				Expect(ParseThink("Normal(100,20)")).To(Equal(&Think{Kind: NormalThink, A: 100, B: 20}))
+				Expect(ParseThink("exp(100)")).To(Equal(&Think{Kind: ExpThink, A: 100}))
+				Expect(ParseThink("lognormal(100,0.5)")).To(Equal(&Think{Kind: LognormalThink, A: 100, B: 0.5}))
+			})
+		})
+		Context("invalid expressions", func() {
+			It("returns an error.", func() {
+				defer GinkgoRecover()
+				for _, expr := range []string{"poisson(1)", "uniform(50,10)", "exp(1,2)", "normal(x,1)", "uniform(-1,2)", "fast"} {
+					_, err := ParseThink(expr)
+					Expect(err).ShouldNot(BeNil(), expr)
+				}
+			})
+		})
+	})
+
+	Describe("Next", func() {
+		It("draws think time with the distribution mean.", func() {
+			defer GinkgoRecover()
+			for _, expr := range []string{"fixed(40)", "uniform(10,50)", "normal(100,20)", "exp(100)", "lognormal(100,0.5)"} {
+				think, err := ParseThink(expr)
+				Expect(err).Should(BeNil())
+				Expect(mean(think, 20000)).To(BeNumerically("~", think.Mean(), think.Mean()/20), expr)
+			}
+		})
+		It("stays in the uniform range.", func() {
+			defer GinkgoRecover()
+			think, _ := ParseThink("uniform(10,50)")
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 1000; i++ {
+				Expect(think.Next(rng)).To(And(BeNumerically(">=", 10*time.Millisecond), BeNumerically("<=", 50*time.Millisecond)))
+			}
+		})
+	})
+
+	Describe("StartOffset", func() {
+		It("offsets within the think time or the fixed delay.", func() {
+			defer GinkgoRecover()
+			think, _ := ParseThink("uniform(10,50)")
+			rng := rand.New(rand.NewSource(1))
+			offsets := make(map[time.Duration]bool)
+			for i := 0; i < 100; i++ {
+				offset := StartOffset(think, time.Second, rng)
+				Expect(offset).To(And(BeNumerically(">=", 0), BeNumerically("<", 30*time.Millisecond)))
+				offsets[offset] = true
+				Expect(StartOffset(nil, 20*time.Millisecond, rng)).To(And(BeNumerically(">=", 0), BeNumerically("<", 20*time.Millisecond)))
+			}
+			Expect(len(offsets)).To(BeNumerically(">", 1))
+			Expect(StartOffset(nil, 0, rng)).To(BeZero())
+		})
+	})
+})
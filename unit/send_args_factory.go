@@ -20,8 +20,8 @@ import "github.com/alexstov/sling/emul"
 func NewSendArgs() emul.SendArgs {
 	var sendArgs = emul.SendArgs{
 		Data:       "string",
-		SrcDir:     "string",
-		Wildcard:   "string",
+		SrcDirs:    []string{"string"},
+		Wildcards:  []string{"string"},
 		SaveReqDir: "string",
 		SaveResDir: "string",
 		Repeat:     2,
@@ -0,0 +1,178 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp edit script operation, ' ' keeps, '-' deletes a[i], '+' inserts b[j].
+type diffOp struct {
+	kind byte
+	i, j int
+}
+
+// SplitLines splits the text into lines without the line terminators.
+func SplitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// UnifiedDiff returns unified diff of the lines with the number of context lines, empty if the lines are equal.
+func UnifiedDiff(fromName, toName string, a, b []string, context int) string {
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while the changes are within twice the context.
+		end, kept := start, 0
+		for i := start; i < len(ops) && kept <= 2*context; i++ {
+			if ops[i].kind == ' ' {
+				kept++
+			} else {
+				end, kept = i+1, 0
+			}
+		}
+		from, to := start-context, end+context
+		if from < 0 {
+			from = 0
+		}
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&sb, a, b, ops[from:to])
+		start = to
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes the hunk header and lines.
+func writeHunk(sb *strings.Builder, a, b []string, ops []diffOp) {
+	var aLen, bLen int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(ops[0].i, aLen), hunkRange(ops[0].j, bLen))
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			sb.WriteString("+" + b[op.j] + "\n")
+		case '-':
+			sb.WriteString("-" + a[op.i] + "\n")
+		default:
+			sb.WriteString(" " + a[op.i] + "\n")
+		}
+	}
+}
+
+// hunkRange formats the hunk line range, the empty range starts at the preceding line.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// diffLines returns the shortest edit script turning a into b, Myers' algorithm.
+// Every operation records the positions in both a and b.
+func diffLines(a, b []string) (ops []diffOp) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	d := 0
+search:
+	for ; d <= n+m; d++ {
+		// Keep the diagonals reachable at the previous step to backtrack.
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Backtrack from the end collecting the operations in reverse.
+	x, y := n, m
+	for ; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{kind: ' ', i: x, j: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: '+', i: x, j: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: '-', i: x, j: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		ops = append(ops, diffOp{kind: ' ', i: x, j: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
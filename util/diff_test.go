@@ -0,0 +1,72 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/util"
+)
+
+var _ = Describe("UnifiedDiff", func() {
+	lines := func(n int) []string {
+		var l []string
+		for i := 1; i <= n; i++ {
+			l = append(l, string(rune('a'+i-1)))
+		}
+		return l
+	}
+
+	It("equal lines.", func() {
+		defer GinkgoRecover()
+		Expect(util.UnifiedDiff("a", "b", lines(5), lines(5), 3)).To(BeEmpty())
+		Expect(util.SplitLines("x\ny\n")).To(Equal([]string{"x", "y"}))
+		Expect(util.SplitLines("")).To(BeEmpty())
+	})
+
+	It("changed, deleted and inserted lines with context.", func() {
+		defer GinkgoRecover()
+		a := lines(12)
+		b := append([]string{"new"}, lines(12)...)
+		b[3] = "C"
+		b = append(b[:10], b[11:]...)
+		Expect(util.UnifiedDiff("golden/x", "actual/x", a, b, 2)).To(Equal(strings.Join([]string{
+			"--- golden/x",
+			"+++ actual/x",
+			"@@ -1,5 +1,6 @@",
+			"+new",
+			" a",
+			" b",
+			"-c",
+			"+C",
+			" d",
+			" e",
+			"@@ -8,5 +9,4 @@",
+			" h",
+			" i",
+			"-j",
+			" k",
+			" l",
+			""}, "\n")))
+	})
+
+	It("insertion into empty text.", func() {
+		defer GinkgoRecover()
+		Expect(util.UnifiedDiff("a", "b", nil, []string{"x"}, 3)).To(Equal("--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n"))
+	})
+})
@@ -0,0 +1,255 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathElem JSON path element, object key, array index or wildcard.
+type pathElem struct {
+	key   string
+	index int
+	array bool
+	wild  bool
+}
+
+// JSONPath simple JSON path, e.g. $.items[0].id, $.meta.*, $.items[*].ts.
+type JSONPath []pathElem
+
+// ParseJSONPath parses dot separated JSON path with array indexes and wildcards, the leading $ is optional.
+func ParseJSONPath(path string) (p JSONPath, err error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in JSON path %q", path)
+			}
+			key := rest[:end]
+			p = append(p, pathElem{key: key, wild: key == "*"})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed index in JSON path %q", path)
+			}
+			elem := pathElem{array: true, wild: rest[1:end] == "*"}
+			if !elem.wild {
+				if elem.index, err = strconv.Atoi(rest[1:end]); err != nil || elem.index < 0 {
+					return nil, fmt.Errorf("invalid index in JSON path %q", path)
+				}
+			}
+			p = append(p, elem)
+			rest = rest[end+1:]
+		default:
+			// Path without the leading $.
+			if len(p) > 0 {
+				return nil, fmt.Errorf("invalid JSON path %q", path)
+			}
+			rest = "." + rest
+		}
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("empty JSON path %q", path)
+	}
+
+	return p, nil
+}
+
+func (p JSONPath) String() string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, elem := range p {
+		switch {
+		case elem.array && elem.wild:
+			sb.WriteString("[*]")
+		case elem.array:
+			fmt.Fprintf(&sb, "[%d]", elem.index)
+		default:
+			sb.WriteString("." + elem.key)
+		}
+	}
+	return sb.String()
+}
+
+// Get returns the values matching the path.
+func (p JSONPath) Get(doc interface{}) (values []interface{}) {
+	p.walk(doc, func(parent interface{}, key interface{}) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			values = append(values, node[key.(string)])
+		case []interface{}:
+			values = append(values, node[key.(int)])
+		}
+	})
+	return
+}
+
+// Set replaces the values matching the path.
+func (p JSONPath) Set(doc interface{}, value interface{}) {
+	p.walk(doc, func(parent interface{}, key interface{}) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[key.(string)] = value
+		case []interface{}:
+			node[key.(int)] = value
+		}
+	})
+}
+
+// Delete removes the object keys matching the path, the matching array elements are set to null.
+func (p JSONPath) Delete(doc interface{}) {
+	p.walk(doc, func(parent interface{}, key interface{}) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			delete(node, key.(string))
+		case []interface{}:
+			node[key.(int)] = nil
+		}
+	})
+}
+
+// walk calls fn with the parent container and the key of every value matching the path.
+func (p JSONPath) walk(node interface{}, fn func(parent interface{}, key interface{})) {
+	if len(p) == 0 {
+		return
+	}
+
+	elem, last := p[0], len(p) == 1
+	visit := func(parent interface{}, key interface{}, child interface{}) {
+		if last {
+			fn(parent, key)
+		} else {
+			p[1:].walk(child, fn)
+		}
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if elem.array {
+			return
+		}
+		if elem.wild {
+			keys := make([]string, 0, len(n))
+			for key := range n {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				visit(n, key, n[key])
+			}
+		} else if child, ok := n[elem.key]; ok {
+			visit(n, elem.key, child)
+		}
+	case []interface{}:
+		if !elem.array {
+			return
+		}
+		if elem.wild {
+			for i := range n {
+				visit(n, i, n[i])
+			}
+		} else if elem.index < len(n) {
+			visit(n, elem.index, n[elem.index])
+		}
+	}
+}
+
+// DecodeJSON decodes JSON document keeping numbers as json.Number.
+func DecodeJSON(data []byte) (doc interface{}, err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+
+	return doc, nil
+}
+
+// DiffJSON returns the paths of the values that differ between the documents.
+func DiffJSON(a, b interface{}) (paths []string) {
+	diffJSON("$", a, b, &paths)
+	return
+}
+
+func diffJSON(path string, a, b interface{}, paths *[]string) {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			*paths = append(*paths, path)
+			return
+		}
+		keys := make([]string, 0, len(va)+len(vb))
+		for key := range va {
+			keys = append(keys, key)
+		}
+		for key := range vb {
+			if _, ok := va[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ca, okA := va[key]
+			cb, okB := vb[key]
+			if okA != okB {
+				*paths = append(*paths, path+"."+key)
+				continue
+			}
+			diffJSON(path+"."+key, ca, cb, paths)
+		}
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			*paths = append(*paths, path)
+			return
+		}
+		for i := range va {
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), va[i], vb[i], paths)
+		}
+	case json.Number:
+		vb, ok := b.(json.Number)
+		if !ok {
+			*paths = append(*paths, path)
+			return
+		}
+		if va != vb {
+			fa, errA := va.Float64()
+			fb, errB := vb.Float64()
+			if errA != nil || errB != nil || fa != fb {
+				*paths = append(*paths, path)
+			}
+		}
+	default:
+		if a != b {
+			*paths = append(*paths, path)
+		}
+	}
+}
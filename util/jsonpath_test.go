@@ -0,0 +1,76 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/util"
+)
+
+var _ = Describe("JSONPath", func() {
+	var doc interface{}
+
+	BeforeEach(func() {
+		var err error
+		doc, err = util.DecodeJSON([]byte(`{"id": 7, "meta": {"ts": "now", "host": "a"}, "items": [{"id": 1}, {"id": 2}]}`))
+		Expect(err).Should(BeNil())
+	})
+
+	Describe("ParseJSONPath", func() {
+		It("keys, indexes and wildcards.", func() {
+			defer GinkgoRecover()
+			for path, str := range map[string]string{"$.meta.ts": "$.meta.ts", "items[1].id": "$.items[1].id", "$.items[*].*": "$.items[*].*"} {
+				p, err := util.ParseJSONPath(path)
+				Expect(err).Should(BeNil())
+				Expect(p.String()).To(Equal(str))
+			}
+		})
+		It("invalid paths.", func() {
+			defer GinkgoRecover()
+			for _, path := range []string{"", "$", "$.a..b", "$.items[x]", "$.items[0"} {
+				_, err := util.ParseJSONPath(path)
+				Expect(err).ShouldNot(BeNil(), path)
+			}
+		})
+	})
+
+	Describe("Get, Set and Delete", func() {
+		It("matching values.", func() {
+			defer GinkgoRecover()
+			p, _ := util.ParseJSONPath("$.items[*].id")
+			Expect(p.Get(doc)).To(Equal([]interface{}{json.Number("1"), json.Number("2")}))
+			p.Set(doc, "x")
+			Expect(p.Get(doc)).To(Equal([]interface{}{"x", "x"}))
+			p, _ = util.ParseJSONPath("$.meta.*")
+			p.Delete(doc)
+			Expect(doc.(map[string]interface{})["meta"]).To(BeEmpty())
+			p, _ = util.ParseJSONPath("$.missing[0]")
+			Expect(p.Get(doc)).To(BeEmpty())
+		})
+	})
+
+	Describe("DiffJSON", func() {
+		It("paths of the differing values.", func() {
+			defer GinkgoRecover()
+			other, _ := util.DecodeJSON([]byte(`{"items": [{"id": 1}, {"id": 3}], "meta": {"host": "a", "ts": "later"}, "id": 7.0, "new": true}`))
+			Expect(util.DiffJSON(doc, other)).To(Equal([]string{"$.items[1].id", "$.meta.ts", "$.new"}))
+			Expect(util.DiffJSON(doc, doc)).To(BeEmpty())
+		})
+	})
+})
@@ -14,5 +14,32 @@
 
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // MaxUint maximum value of uint.
 const MaxUint = ^uint(0)
+
+// ParseFraction parses a fraction such as 0.05 or a percentage such as 5%.
+func ParseFraction(s string) (frac float64, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	pct := strings.HasSuffix(s, "%")
+	if frac, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64); err != nil {
+		return 0, fmt.Errorf("invalid fraction %q", s)
+	}
+	if pct {
+		frac /= 100
+	}
+	if frac < 0 || frac > 1 {
+		return 0, fmt.Errorf("fraction %q out of range", s)
+	}
+
+	return frac, nil
+}
@@ -0,0 +1,38 @@
+// Copyright © 2019 Alexey Stolpovskikh <stolpovskikh@hotmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/alexstov/sling/util"
+)
+
+var _ = Describe("ParseFraction", func() {
+	It("fractions and percentages.", func() {
+		defer GinkgoRecover()
+		Expect(util.ParseFraction("0.05")).To(Equal(0.05))
+		Expect(util.ParseFraction("5%")).To(Equal(0.05))
+		Expect(util.ParseFraction("")).To(Equal(0.0))
+	})
+	It("invalid fractions.", func() {
+		defer GinkgoRecover()
+		for _, s := range []string{"x", "150%", "-0.1"} {
+			_, err := util.ParseFraction(s)
+			Expect(err).ShouldNot(BeNil())
+		}
+	})
+})